@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -12,14 +14,24 @@ import (
 	"github.com/kidpech/api_free_demo/internal/app"
 	"github.com/kidpech/api_free_demo/internal/app/diagnostics"
 	"github.com/kidpech/api_free_demo/internal/config"
+	"github.com/kidpech/api_free_demo/internal/domain/customfield"
+	"github.com/kidpech/api_free_demo/internal/domain/media"
 	"github.com/kidpech/api_free_demo/internal/domain/profile"
+	"github.com/kidpech/api_free_demo/internal/domain/settings"
+	"github.com/kidpech/api_free_demo/internal/domain/social"
+	"github.com/kidpech/api_free_demo/internal/domain/tenant"
 	"github.com/kidpech/api_free_demo/internal/domain/user"
 	"github.com/kidpech/api_free_demo/internal/infrastructure/auth"
 	dbinfra "github.com/kidpech/api_free_demo/internal/infrastructure/db"
+	"github.com/kidpech/api_free_demo/internal/infrastructure/idempotency"
 	"github.com/kidpech/api_free_demo/internal/infrastructure/logging"
+	"github.com/kidpech/api_free_demo/internal/infrastructure/mail"
 	"github.com/kidpech/api_free_demo/internal/infrastructure/monitoring"
 	"github.com/kidpech/api_free_demo/internal/infrastructure/ratelimit"
 	redisintra "github.com/kidpech/api_free_demo/internal/infrastructure/redis"
+	"github.com/kidpech/api_free_demo/internal/infrastructure/storage"
+	"github.com/kidpech/api_free_demo/migrations"
+	"github.com/kidpech/api_free_demo/pkg/geo"
 )
 
 func main() {
@@ -37,6 +49,18 @@ func main() {
 	}
 	defer logging.Sync(logger)
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrate(ctx, cfg.Database, logger, os.Args[2:], os.Stdout)
+		if errors.Is(err, errMigrateUsage) {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			os.Exit(2)
+		}
+		if err != nil {
+			logger.Fatal("migrate failed", zap.Error(err))
+		}
+		return
+	}
+
 	if err := monitoring.InitSentry(cfg.Monitoring, cfg.App); err != nil {
 		logger.Warn("sentry init failed", zap.Error(err))
 	}
@@ -49,6 +73,16 @@ func main() {
 	}
 	defer dbManager.Close()
 
+	if cfg.Database.AutoMigrate {
+		migrator, err := dbinfra.NewMigrator(dbManager.Write, migrations.FS, logger)
+		if err == nil {
+			_, err = migrator.Up(ctx)
+		}
+		if err != nil {
+			logger.Fatal("db migrate failed", zap.Error(err))
+		}
+	}
+
 	var redisClient *redisintra.Client
 	if cfg.Redis.Addr != "" {
 		client, err := redisintra.Connect(cfg.Redis, logger)
@@ -67,14 +101,58 @@ func main() {
 
 	userRepo := dbinfra.NewUserRepository(dbManager.Write)
 	profileRepo := dbinfra.NewProfileRepository(dbManager.Write)
+	tenantRepo := dbinfra.NewTenantRepository(dbManager.Write)
+	settingsRepo := dbinfra.NewSettingsRepository(dbManager.Write)
+	customFieldRepo := dbinfra.NewCustomFieldRepository(dbManager.Write)
+	socialRepo := dbinfra.NewSocialRepository(dbManager.Write)
 
-	userService := user.NewService(userRepo, authManager, logger, cfg.Security.AllowRegistration)
-	profileService := profile.NewService(profileRepo)
+	mailer := mail.New(cfg.Mail, logger)
+
+	blobStore, err := storage.New(cfg.Storage)
+	if err != nil {
+		logger.Fatal("storage init failed", zap.Error(err))
+	}
+	mediaService := media.NewService(blobStore, media.Config{
+		BaseURL:   cfg.App.BaseURL,
+		Secret:    []byte(cfg.Storage.SigningSecret),
+		URLTTL:    cfg.Storage.URLTTL,
+		MaxBytes:  cfg.Storage.MaxBytes,
+		MaxPixels: cfg.Storage.MaxPixels,
+		MaxEdge:   cfg.Storage.MaxEdge,
+	})
+
+	gazetteer := geo.DefaultGazetteer()
+	if cfg.Profiles.Gazetteer != "" {
+		if gazetteer, err = geo.LoadGazetteer(cfg.Profiles.Gazetteer); err != nil {
+			logger.Fatal("gazetteer load failed", zap.Error(err))
+		}
+	}
+
+	// The user service only needs membership lookups and profile summaries,
+	// so it gets bare tenant and profile services; the full ones depend on
+	// users for invitations and owner expansion.
+	userService := user.NewService(userRepo, authManager, logger, cfg.Security.AllowRegistration,
+		user.WithMemberships(tenant.NewService(tenantRepo)), user.WithImages(mediaService),
+		user.WithMailer(mailer, cfg.Security.PasswordSetURL, cfg.Security.ActionTokenTTL),
+		user.WithProfiles(profile.NewService(profileRepo, profile.WithImages(mediaService))))
+	tenantService := tenant.NewService(tenantRepo,
+		tenant.WithInvitations(mailer, userService, cfg.Tenancy.InvitationURL, cfg.Tenancy.InvitationTTL))
+	settingsService := settings.NewService(settingsRepo, settings.DefaultRegistry())
+	customFieldService := customfield.NewService(customFieldRepo)
+	socialService := social.NewService(socialRepo, userService)
+	profileService := profile.NewService(profileRepo, profile.WithImages(mediaService), profile.WithSettings(settingsService),
+		profile.WithCustomFields(customFieldService), profile.WithOwners(userService), profile.WithRelations(socialService),
+		profile.WithGeocoder(gazetteer))
 
 	logBuffer := diagnostics.NewLogBuffer(cfg.Diagnostics.MaxLogLines)
 	diagHandler := diagnostics.NewHandler(logBuffer)
 	userHandler := user.NewHandler(userService)
 	profileHandler := profile.NewHandler(profileService)
+	tenantHandler := tenant.NewHandler(tenantService)
+	settingsHandler := settings.NewHandler(settingsService)
+	mediaHandler := media.NewHandler(mediaService)
+	customFieldHandler := customfield.NewHandler(customFieldService)
+	socialHandler := social.NewHandler(socialService)
 
 	var ipLimiter, userLimiter ratelimit.Limiter
 	if cfg.RateLimit.Enabled {
@@ -87,18 +165,32 @@ func main() {
 		}
 	}
 
+	idempotencyStore := dbinfra.NewIdempotencyRepository(dbManager.Write)
+	if redisClient != nil {
+		idempotencyStore = idempotency.NewRedisStore(redisClient.Native, cfg.Idempotency.RedisPrefix)
+	}
+
 	router := app.NewRouter(app.RouterDeps{
-		Config:         cfg,
-		UserHandler:    userHandler,
-		ProfileHandler: profileHandler,
-		Diagnostics:    diagHandler,
-		AuthManager:    authManager,
-		Logger:         logger,
-		LogBuffer:      logBuffer,
-		IPLimiter:      ipLimiter,
-		UserLimiter:    userLimiter,
+		Config:          cfg,
+		UserHandler:     userHandler,
+		ProfileHandler:  profileHandler,
+		TenantHandler:   tenantHandler,
+		SettingsHandler: settingsHandler,
+		MediaHandler:    mediaHandler,
+		CustomFields:    customFieldHandler,
+		SocialHandler:   socialHandler,
+		TenantResolver:  tenantService,
+		Diagnostics:     diagHandler,
+		AuthManager:     authManager,
+		Logger:          logger,
+		LogBuffer:       logBuffer,
+		IPLimiter:       ipLimiter,
+		UserLimiter:     userLimiter,
+		Idempotency:     idempotencyStore,
 	})
 
+	go profileService.RunPurger(ctx, cfg.Profiles.TrashRetention, cfg.Profiles.PurgeInterval, logger)
+
 	server := &app.Server{Engine: router, Addr: ":" + cfg.App.Port, Logger: logger}
 	if err := server.Run(ctx); err != nil {
 		logger.Fatal("server error", zap.Error(err))
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"go.uber.org/zap"
+
+	"github.com/kidpech/api_free_demo/internal/config"
+	dbinfra "github.com/kidpech/api_free_demo/internal/infrastructure/db"
+	"github.com/kidpech/api_free_demo/migrations"
+)
+
+const migrateUsage = `usage: api migrate [command]
+
+commands:
+  up             apply every pending migration (default)
+  down [n]       revert the last n applied migrations (default 1)
+  to <version>   migrate up or down to version; 0 reverts everything
+  status         list migrations and when they were applied`
+
+var errMigrateUsage = errors.New(migrateUsage)
+
+// runMigrate implements the migrate subcommand.
+func runMigrate(ctx context.Context, cfg config.DatabaseConfig, logger *zap.Logger, args []string, out io.Writer) error {
+	command := "up"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	var n int64
+	switch {
+	case command == "up" || command == "status":
+		if len(args) != 0 {
+			return errMigrateUsage
+		}
+	case command == "down" && len(args) == 0:
+		n = 1
+	case (command == "down" || command == "to") && len(args) == 1:
+		var err error
+		if n, err = strconv.ParseInt(args[0], 10, 64); err != nil || n < 0 || (command == "down" && n == 0) {
+			return errMigrateUsage
+		}
+	default:
+		return errMigrateUsage
+	}
+
+	dbManager, err := dbinfra.Connect(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer dbManager.Close()
+	migrator, err := dbinfra.NewMigrator(dbManager.Write, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	var done int
+	switch command {
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printMigrationStatus(out, statuses)
+	case "up":
+		done, err = migrator.Up(ctx)
+	case "down":
+		done, err = migrator.Down(ctx, int(n))
+	case "to":
+		done, err = migrator.To(ctx, n)
+	}
+	fmt.Fprintf(out, "%d migration(s) run\n", done)
+	return err
+}
+
+func printMigrationStatus(out io.Writer, statuses []dbinfra.MigrationStatus) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		state, appliedAt := "pending", ""
+		if s.AppliedAt != nil {
+			state, appliedAt = "applied", s.AppliedAt.UTC().Format("2006-01-02 15:04:05Z")
+		}
+		switch {
+		case s.Missing:
+			state = "applied, no file"
+		case s.Modified:
+			state = "applied, modified"
+		}
+		fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
@@ -24,8 +24,7 @@ func AuthMiddleware(manager *auth.Manager) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-		c.Set("user_id", claims.UserID)
-		c.Set("user_role", claims.Role)
+		setClaims(c, claims)
 		c.Next()
 	}
 }
@@ -40,8 +39,7 @@ func OptionalAuth(manager *auth.Manager) gin.HandlerFunc {
 		}
 		claims, err := manager.ParseAccessToken(token)
 		if err == nil {
-			c.Set("user_id", claims.UserID)
-			c.Set("user_role", claims.Role)
+			setClaims(c, claims)
 		}
 		c.Next()
 	}
@@ -61,6 +59,14 @@ func AdminOnly() gin.HandlerFunc {
 	}
 }
 
+func setClaims(c *gin.Context, claims *auth.Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("user_role", claims.Role)
+	if claims.TenantID != nil {
+		c.Set("token_tenant_id", *claims.TenantID)
+	}
+}
+
 func extractBearer(header string) string {
 	if header == "" {
 		return ""
@@ -23,6 +23,7 @@ func CORS(cfg config.CORSConfig) gin.HandlerFunc {
 			if _, ok := allowedOrigins[origin]; ok || len(allowedOrigins) == 0 {
 				c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
 				c.Writer.Header().Set("Vary", "Origin")
+				c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, Location, Idempotent-Replayed")
 				if cfg.AllowCredentials {
 					c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 				}
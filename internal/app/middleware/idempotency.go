@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kidpech/api_free_demo/internal/infrastructure/idempotency"
+	"github.com/kidpech/api_free_demo/pkg/response"
+	"github.com/kidpech/api_free_demo/pkg/tenancy"
+)
+
+const (
+	// IdempotencyHeader carries the client-chosen key.
+	IdempotencyHeader = "Idempotency-Key"
+	// ReplayedHeader marks responses served from the idempotency store.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKey  = 255
+	maxIdempotentBytes = 10 << 20
+)
+
+// replayedHeaders are stored alongside the body and replayed verbatim.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// Idempotency makes POST requests carrying an Idempotency-Key safe to retry.
+// The first response per key, user and route is stored for ttl and replayed
+// to retries; reusing a key with a different query string or body is
+// rejected with 422. Multipart uploads are not buffered, so their body is
+// fingerprinted by length only. Server errors are not stored so the request
+// can be retried. Store errors let the request through unprotected.
+func Idempotency(store idempotency.Store, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if store == nil || key == "" || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			response.BadRequest(c, "invalid_idempotency_key", "Idempotency-Key must be at most 255 characters")
+			c.Abort()
+			return
+		}
+		fingerprint := []byte(c.Request.URL.RawQuery + "\n")
+		if c.ContentType() == "multipart/form-data" {
+			fingerprint = strconv.AppendInt(fingerprint, c.Request.ContentLength, 10)
+		} else {
+			body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentBytes+1))
+			if err != nil {
+				response.BadRequest(c, "invalid_body", "could not read request body")
+				c.Abort()
+				return
+			}
+			if len(body) > maxIdempotentBytes {
+				response.PayloadTooLarge(c, "request body too large")
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			fingerprint = append(fingerprint, body...)
+		}
+
+		ctx := c.Request.Context()
+		scope := "anonymous"
+		if userID := response.UserIDFromContext(c); userID != "" {
+			scope = userID
+		}
+		if tenantID, ok := tenancy.FromContext(ctx); ok {
+			scope += "@" + tenantID.String()
+		}
+		storeKey := digest([]byte(scope + "\n" + c.FullPath() + "\n" + key))
+		requestHash := digest(fingerprint)
+
+		rec, claimed, err := store.Begin(ctx, storeKey, requestHash, ttl)
+		if err != nil {
+			c.Next()
+			return
+		}
+		if !claimed {
+			switch {
+			case rec.RequestHash != requestHash:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, response.ErrorResponse{
+					Error:   "idempotency_key_reused",
+					Message: "Idempotency-Key was already used with a different request",
+				})
+			case !rec.Completed:
+				response.Conflict(c, "idempotency_in_progress", "a request with this Idempotency-Key is still in progress")
+				c.Abort()
+			default:
+				for name, val := range rec.Headers {
+					c.Header(name, val)
+				}
+				c.Header(ReplayedHeader, "true")
+				c.Status(rec.Status)
+				_, _ = c.Writer.Write(rec.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		// The outcome is saved even if the client has gone away, so the
+		// key is not left claimed until it expires.
+		saveCtx := context.WithoutCancel(ctx)
+		completed := false
+		defer func() {
+			if !completed {
+				_ = store.Release(saveCtx, storeKey)
+			}
+		}()
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		headers := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if val := recorder.Header().Get(name); val != "" {
+				headers[name] = val
+			}
+		}
+		rec = &idempotency.Record{
+			RequestHash: requestHash,
+			Completed:   true,
+			Status:      status,
+			Headers:     headers,
+			Body:        recorder.body.Bytes(),
+		}
+		completed = store.Complete(saveCtx, storeKey, *rec, ttl) == nil
+	}
+}
+
+// bodyRecorder copies the response body while it is written.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
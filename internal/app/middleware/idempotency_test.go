@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kidpech/api_free_demo/internal/infrastructure/idempotency"
+)
+
+func TestIdempotencyReplaysAndRejectsReuse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := memoryStore{}
+	calls, fail := 0, false
+	r := gin.New()
+	r.Use(Idempotency(store, time.Hour))
+	r.POST("/profiles", func(c *gin.Context) {
+		calls++
+		if fail {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Header("Location", "/profiles/1")
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/profiles", strings.NewReader(body))
+		req.Header.Set(IdempotencyHeader, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post("k1", `{"a":1}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	retry := post("k1", `{"a":1}`)
+	require.Equal(t, http.StatusCreated, retry.Code)
+	require.Equal(t, first.Body.String(), retry.Body.String())
+	require.Equal(t, "/profiles/1", retry.Header().Get("Location"))
+	require.Equal(t, "true", retry.Header().Get(ReplayedHeader))
+	require.Equal(t, 1, calls)
+
+	require.Equal(t, http.StatusUnprocessableEntity, post("k1", `{"a":2}`).Code)
+
+	fail = true
+	require.Equal(t, http.StatusInternalServerError, post("k2", `{}`).Code)
+	fail = false
+	require.Equal(t, http.StatusCreated, post("k2", `{}`).Code)
+	require.Equal(t, 3, calls)
+}
+
+func TestIdempotencyFingerprintsQueryAndSkipsMultipartBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Idempotency(memoryStore{}, time.Hour))
+	var seen string
+	r.POST("/bulk", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"mode": c.Query("mode")})
+	})
+	r.POST("/upload", func(c *gin.Context) {
+		file, err := c.FormFile("file")
+		require.NoError(t, err)
+		seen = file.Filename
+		c.Status(http.StatusCreated)
+	})
+	send := func(target, key, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set(IdempotencyHeader, key)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	require.Equal(t, http.StatusOK, send("/bulk?mode=atomic", "k1", "application/json", `{}`).Code)
+	require.Equal(t, http.StatusUnprocessableEntity, send("/bulk?mode=partial", "k1", "application/json", `{}`).Code)
+
+	form := "--b\r\nContent-Disposition: form-data; name=\"file\"; filename=\"a.png\"\r\n\r\nxyz\r\n--b--\r\n"
+	require.Equal(t, http.StatusCreated, send("/upload", "k2", "multipart/form-data; boundary=b", form).Code)
+	require.Equal(t, "a.png", seen)
+	retry := send("/upload", "k2", "multipart/form-data; boundary=b", form)
+	require.Equal(t, "true", retry.Header().Get(ReplayedHeader))
+	require.Equal(t, http.StatusUnprocessableEntity, send("/upload", "k2", "multipart/form-data; boundary=b", form+"x").Code)
+}
+
+type memoryStore map[string]idempotency.Record
+
+func (m memoryStore) Begin(ctx context.Context, key, hash string, ttl time.Duration) (*idempotency.Record, bool, error) {
+	if rec, ok := m[key]; ok {
+		return &rec, false, nil
+	}
+	m[key] = idempotency.Record{RequestHash: hash}
+	return nil, true, nil
+}
+
+func (m memoryStore) Complete(ctx context.Context, key string, rec idempotency.Record, ttl time.Duration) error {
+	m[key] = rec
+	return nil
+}
+
+func (m memoryStore) Release(ctx context.Context, key string) error {
+	delete(m, key)
+	return nil
+}
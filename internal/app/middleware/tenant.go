@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/internal/config"
+	"github.com/kidpech/api_free_demo/internal/domain/tenant"
+	"github.com/kidpech/api_free_demo/pkg/response"
+	"github.com/kidpech/api_free_demo/pkg/tenancy"
+)
+
+// TenantResolver maps tenant references (id or slug) and checks membership.
+type TenantResolver interface {
+	Resolve(ctx context.Context, ref string) (uuid.UUID, error)
+	IsMember(ctx context.Context, tenantID, userID uuid.UUID) (bool, error)
+}
+
+// Tenant resolves the active tenant from the token claim, the configured
+// header or the request subdomain, in that order, and scopes the request
+// context to it. Authenticated non-admin callers must be members.
+func Tenant(cfg config.TenancyConfig, resolver TenantResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var tenantID uuid.UUID
+		if claim, ok := c.Get("token_tenant_id"); ok {
+			tenantID, _ = claim.(uuid.UUID)
+		}
+		if ref := strings.TrimSpace(c.GetHeader(cfg.Header)); ref != "" {
+			id, err := resolver.Resolve(ctx, ref)
+			if err != nil {
+				abortTenantError(c, err)
+				return
+			}
+			if tenantID != uuid.Nil && tenantID != id {
+				response.Forbidden(c, "tenant header does not match token")
+				c.Abort()
+				return
+			}
+			tenantID = id
+		}
+		if tenantID == uuid.Nil {
+			if slug := subdomain(c.Request.Host, cfg.BaseDomain); slug != "" {
+				// Unknown subdomains (www, api, ...) simply fall through to
+				// the untenanted scope.
+				if id, err := resolver.Resolve(ctx, slug); err == nil {
+					tenantID = id
+				}
+			}
+		}
+		if tenantID == uuid.Nil {
+			c.Next()
+			return
+		}
+		if userID := response.UserIDFromContext(c); userID != "" && c.GetString("user_role") != "admin" {
+			ok, err := resolver.IsMember(ctx, tenantID, uuid.MustParse(userID))
+			if err != nil {
+				response.InternalServerError(c, err)
+				c.Abort()
+				return
+			}
+			if !ok {
+				response.Forbidden(c, "not a member of this tenant")
+				c.Abort()
+				return
+			}
+		}
+		c.Set("tenant_id", tenantID)
+		c.Request = c.Request.WithContext(tenancy.WithTenant(ctx, tenantID))
+		c.Next()
+	}
+}
+
+func abortTenantError(c *gin.Context, err error) {
+	if errors.Is(err, tenant.ErrNotFound) {
+		response.NotFound(c, "tenant")
+	} else {
+		response.InternalServerError(c, err)
+	}
+	c.Abort()
+}
+
+func subdomain(host, base string) string {
+	if base == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	suffix := "." + strings.ToLower(base)
+	if !strings.HasSuffix(host, suffix) {
+		return ""
+	}
+	label := strings.TrimSuffix(host, suffix)
+	if label == "" || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kidpech/api_free_demo/internal/config"
+	"github.com/kidpech/api_free_demo/internal/domain/tenant"
+	"github.com/kidpech/api_free_demo/pkg/tenancy"
+)
+
+func TestTenantResolutionOrder(t *testing.T) {
+	acme, globex := uuid.New(), uuid.New()
+	member, outsider := uuid.New(), uuid.New()
+	resolver := fakeResolver{
+		slugs:   map[string]uuid.UUID{"acme": acme, "globex": globex},
+		members: map[uuid.UUID]uuid.UUID{member: acme},
+	}
+	cases := []struct {
+		name   string
+		claim  uuid.UUID
+		header string
+		host   string
+		user   uuid.UUID
+		role   string
+		status int
+		tenant uuid.UUID
+	}{
+		{name: "untenanted", host: "api.example.com", status: http.StatusOK},
+		{name: "claim", claim: acme, user: member, status: http.StatusOK, tenant: acme},
+		{name: "header by slug", header: "acme", user: member, status: http.StatusOK, tenant: acme},
+		{name: "header by id", header: acme.String(), user: member, status: http.StatusOK, tenant: acme},
+		{name: "header matching claim", claim: acme, header: "acme", user: member, status: http.StatusOK, tenant: acme},
+		{name: "header contradicting claim", claim: acme, header: "globex", user: member, status: http.StatusForbidden},
+		{name: "unknown header", header: "initech", user: member, status: http.StatusNotFound},
+		{name: "subdomain", host: "acme.example.com", user: member, status: http.StatusOK, tenant: acme},
+		{name: "subdomain with port", host: "acme.example.com:8080", status: http.StatusOK, tenant: acme},
+		{name: "unknown subdomain", host: "www.example.com", status: http.StatusOK},
+		{name: "header beats subdomain", header: "globex", host: "acme.example.com", status: http.StatusOK, tenant: globex},
+		{name: "claim beats subdomain", claim: acme, host: "globex.example.com", user: member, status: http.StatusOK, tenant: acme},
+		{name: "non-member header", header: "acme", user: outsider, status: http.StatusForbidden},
+		{name: "non-member subdomain", host: "acme.example.com", user: outsider, status: http.StatusForbidden},
+		{name: "admin header", header: "globex", user: outsider, role: "admin", status: http.StatusOK, tenant: globex},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if tc.claim != uuid.Nil {
+					c.Set("token_tenant_id", tc.claim)
+				}
+				if tc.user != uuid.Nil {
+					c.Set("user_id", tc.user)
+					c.Set("user_role", tc.role)
+				}
+			})
+			r.Use(Tenant(config.TenancyConfig{Header: "X-Tenant-ID", BaseDomain: "example.com"}, resolver))
+			var scoped uuid.UUID
+			r.GET("/", func(c *gin.Context) {
+				scoped, _ = tenancy.FromContext(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.host != "" {
+				req.Host = tc.host
+			}
+			if tc.header != "" {
+				req.Header.Set("X-Tenant-ID", tc.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.status, w.Code)
+			require.Equal(t, tc.tenant, scoped)
+		})
+	}
+}
+
+type fakeResolver struct {
+	slugs   map[string]uuid.UUID
+	members map[uuid.UUID]uuid.UUID
+}
+
+func (f fakeResolver) Resolve(ctx context.Context, ref string) (uuid.UUID, error) {
+	if id, ok := f.slugs[ref]; ok {
+		return id, nil
+	}
+	for _, id := range f.slugs {
+		if id.String() == ref {
+			return id, nil
+		}
+	}
+	return uuid.Nil, tenant.ErrNotFound
+}
+
+func (f fakeResolver) IsMember(ctx context.Context, tenantID, userID uuid.UUID) (bool, error) {
+	return f.members[userID] == tenantID, nil
+}
@@ -8,23 +8,36 @@ import (
 	"github.com/kidpech/api_free_demo/internal/app/diagnostics"
 	"github.com/kidpech/api_free_demo/internal/app/middleware"
 	"github.com/kidpech/api_free_demo/internal/config"
+	"github.com/kidpech/api_free_demo/internal/domain/customfield"
+	"github.com/kidpech/api_free_demo/internal/domain/media"
 	"github.com/kidpech/api_free_demo/internal/domain/profile"
+	"github.com/kidpech/api_free_demo/internal/domain/settings"
+	"github.com/kidpech/api_free_demo/internal/domain/social"
+	"github.com/kidpech/api_free_demo/internal/domain/tenant"
 	"github.com/kidpech/api_free_demo/internal/domain/user"
 	"github.com/kidpech/api_free_demo/internal/infrastructure/auth"
+	"github.com/kidpech/api_free_demo/internal/infrastructure/idempotency"
 	"github.com/kidpech/api_free_demo/internal/infrastructure/ratelimit"
 )
 
 // RouterDeps aggregates HTTP dependencies.
 type RouterDeps struct {
-	Config         *config.Config
-	UserHandler    *user.Handler
-	ProfileHandler *profile.Handler
-	Diagnostics    *diagnostics.Handler
-	AuthManager    *auth.Manager
-	Logger         *zap.Logger
-	LogBuffer      *diagnostics.LogBuffer
-	IPLimiter      ratelimit.Limiter
-	UserLimiter    ratelimit.Limiter
+	Config          *config.Config
+	UserHandler     *user.Handler
+	ProfileHandler  *profile.Handler
+	TenantHandler   *tenant.Handler
+	SettingsHandler *settings.Handler
+	MediaHandler    *media.Handler
+	CustomFields    *customfield.Handler
+	SocialHandler   *social.Handler
+	TenantResolver  middleware.TenantResolver
+	Diagnostics     *diagnostics.Handler
+	AuthManager     *auth.Manager
+	Logger          *zap.Logger
+	LogBuffer       *diagnostics.LogBuffer
+	IPLimiter       ratelimit.Limiter
+	UserLimiter     ratelimit.Limiter
+	Idempotency     idempotency.Store
 }
 
 // NewRouter builds the gin engine.
@@ -41,10 +54,16 @@ func NewRouter(deps RouterDeps) *gin.Engine {
 	if deps.AuthManager != nil {
 		r.Use(middleware.OptionalAuth(deps.AuthManager))
 	}
+	if deps.Config != nil && deps.TenantResolver != nil {
+		r.Use(middleware.Tenant(deps.Config.Tenancy, deps.TenantResolver))
+	}
 	if deps.Config == nil || deps.Config.RateLimit.Enabled {
 		r.Use(middleware.RateLimit(deps.IPLimiter, deps.UserLimiter))
 	}
 	r.Use(middleware.RequestLogger(deps.Logger, deps.LogBuffer))
+	if deps.Config != nil && deps.Idempotency != nil {
+		r.Use(middleware.Idempotency(deps.Idempotency, deps.Config.Idempotency.TTL))
+	}
 
 	var authMW gin.HandlerFunc = func(c *gin.Context) { c.Next() }
 	if deps.AuthManager != nil {
@@ -64,6 +83,21 @@ func NewRouter(deps RouterDeps) *gin.Engine {
 
 	deps.UserHandler.RegisterRoutes(api, authMW, adminMW)
 	deps.ProfileHandler.RegisterRoutes(api, authMW)
+	if deps.TenantHandler != nil {
+		deps.TenantHandler.RegisterRoutes(api, authMW, adminMW)
+	}
+	if deps.SettingsHandler != nil {
+		deps.SettingsHandler.RegisterRoutes(api, authMW, adminMW)
+	}
+	if deps.MediaHandler != nil {
+		deps.MediaHandler.RegisterRoutes(api)
+	}
+	if deps.CustomFields != nil {
+		deps.CustomFields.RegisterRoutes(api, authMW, adminMW)
+	}
+	if deps.SocialHandler != nil {
+		deps.SocialHandler.RegisterRoutes(api, authMW)
+	}
 
 	return r
 }
@@ -21,6 +21,11 @@ type Config struct {
 	Security    SecurityConfig
 	Monitoring  MonitoringConfig
 	Diagnostics DiagnosticsConfig
+	Tenancy     TenancyConfig
+	Mail        MailConfig
+	Storage     StorageConfig
+	Profiles    ProfilesConfig
+	Idempotency IdempotencyConfig
 }
 
 // AppConfig captures application-level settings.
@@ -88,6 +93,8 @@ type CORSConfig struct {
 type SecurityConfig struct {
 	AllowRegistration bool
 	BcryptCost        int
+	PasswordSetURL    string
+	ActionTokenTTL    time.Duration
 }
 
 // MonitoringConfig adds observability tunables.
@@ -103,6 +110,57 @@ type DiagnosticsConfig struct {
 	MaxLogLines     int
 }
 
+// TenancyConfig controls how the active tenant is resolved per request.
+type TenancyConfig struct {
+	Header        string
+	BaseDomain    string
+	InvitationURL string
+	InvitationTTL time.Duration
+}
+
+// ProfilesConfig controls how long soft-deleted profiles stay in the trash
+// and where locations are geocoded. A zero TrashRetention disables the
+// purge; an empty Gazetteer uses the one built into the binary.
+type ProfilesConfig struct {
+	TrashRetention time.Duration
+	PurgeInterval  time.Duration
+	Gazetteer      string
+}
+
+// IdempotencyConfig controls how long Idempotency-Key responses are kept
+// for replay.
+type IdempotencyConfig struct {
+	TTL         time.Duration
+	RedisPrefix string
+}
+
+// MailConfig configures outbound SMTP. An empty Host logs mail instead.
+type MailConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// StorageConfig selects the blob backend and media limits. Driver is
+// "local" or "s3"; the s3 driver also speaks to MinIO via PathStyle.
+type StorageConfig struct {
+	Driver        string
+	LocalDir      string
+	S3Endpoint    string
+	S3Region      string
+	S3Bucket      string
+	S3AccessKey   string
+	S3SecretKey   string
+	S3PathStyle   bool
+	SigningSecret string
+	URLTTL        time.Duration
+	MaxBytes      int64
+	MaxPixels     int
+	MaxEdge       int
+}
+
 // Load reads from environment (optionally .env) and builds Config.
 func Load() (*Config, error) {
 	_ = godotenv.Load()
@@ -154,12 +212,14 @@ func Load() (*Config, error) {
 		Cors: CORSConfig{
 			AllowedOrigins:   splitAndTrim(getenv("CORS_ORIGINS", "http://localhost:3000,http://localhost:5173,http://localhost:8080,https://dev.kidpech.app")),
 			AllowedMethods:   splitAndTrim(getenv("CORS_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS")),
-			AllowedHeaders:   splitAndTrim(getenv("CORS_HEADERS", "Authorization,Content-Type,Accept,X-Requested-With")),
+			AllowedHeaders:   splitAndTrim(getenv("CORS_HEADERS", "Authorization,Content-Type,Accept,X-Requested-With,X-Tenant-ID,If-Match,If-None-Match,Idempotency-Key")),
 			AllowCredentials: getBool("CORS_ALLOW_CREDENTIALS", true),
 		},
 		Security: SecurityConfig{
 			AllowRegistration: getBool("ALLOW_REGISTRATION", true),
 			BcryptCost:        getInt("BCRYPT_COST", 12),
+			PasswordSetURL:    getenv("PASSWORD_SET_URL", strings.TrimRight(getenv("BASE_URL", "http://localhost:8080"), "/")+"/password/set"),
+			ActionTokenTTL:    time.Duration(getInt("ACTION_TOKEN_TTL_HOURS", 72)) * time.Hour,
 		},
 		Monitoring: MonitoringConfig{
 			PrometheusEnabled: getBool("PROMETHEUS_ENABLED", true),
@@ -170,6 +230,43 @@ func Load() (*Config, error) {
 			EnableDebugLogs: getBool("ENABLE_DEBUG_LOGS", false),
 			MaxLogLines:     getInt("DEBUG_LOG_LIMIT", 200),
 		},
+		Tenancy: TenancyConfig{
+			Header:        getenv("TENANT_HEADER", "X-Tenant-ID"),
+			BaseDomain:    getenv("TENANT_BASE_DOMAIN", ""),
+			InvitationURL: getenv("INVITATION_ACCEPT_URL", strings.TrimRight(getenv("BASE_URL", "http://localhost:8080"), "/")+"/invitations/accept"),
+			InvitationTTL: time.Duration(getInt("INVITATION_TTL_HOURS", 168)) * time.Hour,
+		},
+		Mail: MailConfig{
+			Host:     getenv("SMTP_HOST", ""),
+			Port:     getInt("SMTP_PORT", 587),
+			Username: getenv("SMTP_USER", ""),
+			Password: getenv("SMTP_PASSWORD", ""),
+			From:     getenv("MAIL_FROM", "no-reply@kidpech.app"),
+		},
+		Storage: StorageConfig{
+			Driver:        strings.ToLower(getenv("STORAGE_DRIVER", "local")),
+			LocalDir:      getenv("STORAGE_LOCAL_DIR", "./data/uploads"),
+			S3Endpoint:    getenv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			S3Region:      getenv("S3_REGION", "us-east-1"),
+			S3Bucket:      getenv("S3_BUCKET", ""),
+			S3AccessKey:   getenv("S3_ACCESS_KEY", ""),
+			S3SecretKey:   getenv("S3_SECRET_KEY", ""),
+			S3PathStyle:   getBool("S3_PATH_STYLE", false),
+			SigningSecret: getenv("MEDIA_SIGNING_SECRET", getenv("JWT_ACCESS_SECRET", getenv("JWT_SECRET", "change-me"))),
+			URLTTL:        time.Duration(getInt("MEDIA_URL_TTL_MIN", 60)) * time.Minute,
+			MaxBytes:      int64(getInt("MEDIA_MAX_UPLOAD_MB", 5)) << 20,
+			MaxPixels:     getInt("MEDIA_MAX_PIXELS", 40_000_000),
+			MaxEdge:       getInt("MEDIA_MAX_EDGE", 2048),
+		},
+		Profiles: ProfilesConfig{
+			TrashRetention: time.Duration(getInt("PROFILE_TRASH_RETENTION_DAYS", 30)) * 24 * time.Hour,
+			PurgeInterval:  time.Duration(getInt("PROFILE_PURGE_INTERVAL_MIN", 60)) * time.Minute,
+			Gazetteer:      getenv("GEOCODER_GAZETTEER", ""),
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:         time.Duration(getInt("IDEMPOTENCY_TTL_HOURS", 24)) * time.Hour,
+			RedisPrefix: getenv("IDEMPOTENCY_PREFIX", "idempotency"),
+		},
 	}
 
 	if err := cfg.validate(); err != nil {
@@ -187,6 +284,15 @@ func (c *Config) validate() error {
 	default:
 		return fmt.Errorf("unsupported db driver %s", c.Database.Driver)
 	}
+	switch c.Storage.Driver {
+	case "local":
+	case "s3":
+		if c.Storage.S3Bucket == "" {
+			return fmt.Errorf("S3_BUCKET is required for the s3 storage driver")
+		}
+	default:
+		return fmt.Errorf("unsupported storage driver %s", c.Storage.Driver)
+	}
 	return nil
 }
 
@@ -0,0 +1,153 @@
+package customfield
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/pkg/response"
+)
+
+// Handler exposes custom field endpoints.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler returns a custom field Handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes mounts the effective schema for signed-in users and the
+// admin definition registry.
+func (h *Handler) RegisterRoutes(rg *gin.RouterGroup, authMW gin.HandlerFunc, adminMW gin.HandlerFunc) {
+	rg.GET("/profile-fields", authMW, h.effective)
+
+	admin := rg.Group("/admin/profile-fields", authMW, adminMW)
+	{
+		admin.POST("", h.create)
+		admin.GET("", h.list)
+		admin.GET("/:id", h.get)
+		admin.PUT("/:id", h.update)
+		admin.DELETE("/:id", h.delete)
+	}
+}
+
+func (h *Handler) effective(c *gin.Context) {
+	defs, err := h.service.Effective(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	out := make([]Definition, 0, len(defs))
+	for _, def := range defs {
+		out = append(out, def)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	c.JSON(http.StatusOK, gin.H{"data": out})
+}
+
+func (h *Handler) create(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	def, err := h.service.Create(c.Request.Context(), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Header("Location", "/api/v1/admin/profile-fields/"+def.ID.String())
+	c.JSON(http.StatusCreated, def)
+}
+
+func (h *Handler) list(c *gin.Context) {
+	filter := Filter{AllTenants: true}
+	if raw := c.Query("tenant_id"); raw != "" {
+		tenantID, err := uuid.Parse(raw)
+		if err != nil {
+			response.BadRequest(c, "invalid_tenant", "tenant_id must be a UUID")
+			return
+		}
+		filter = Filter{TenantID: &tenantID}
+	}
+	defs, err := h.service.List(c.Request.Context(), filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": defs})
+}
+
+func (h *Handler) get(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "custom field")
+		return
+	}
+	def, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, def)
+}
+
+func (h *Handler) update(c *gin.Context) {
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "custom field")
+		return
+	}
+	def, err := h.service.Update(c.Request.Context(), id, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, def)
+}
+
+func (h *Handler) delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "custom field")
+		return
+	}
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		response.NotFound(c, "custom field")
+	case errors.Is(err, ErrTenantNotFound):
+		response.NotFound(c, "tenant")
+	case errors.Is(err, ErrKeyTaken):
+		response.Conflict(c, "key_taken", err.Error())
+	case errors.Is(err, ErrInvalidKey):
+		response.BadRequest(c, "invalid_key", err.Error())
+	case errors.Is(err, ErrInvalidDefinition):
+		response.BadRequest(c, "invalid_definition", err.Error())
+	default:
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			response.ValidationError(c, err)
+			return
+		}
+		response.InternalServerError(c, err)
+	}
+}
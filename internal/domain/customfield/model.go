@@ -0,0 +1,99 @@
+package customfield
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Value types a custom field may hold.
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeDate    = "date"
+	TypeEnum    = "enum"
+)
+
+// Definition describes one admin-defined profile attribute. Definitions
+// without a tenant apply everywhere; a tenant definition with the same key
+// replaces the global one inside that tenant.
+type Definition struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	TenantID    *uuid.UUID `json:"tenant_id,omitempty" db:"tenant_id"`
+	Key         string     `json:"key" db:"field_key"`
+	Label       string     `json:"label" db:"label"`
+	Type        string     `json:"type" db:"type"`
+	Required    bool       `json:"required" db:"required"`
+	EnumValues  StringList `json:"enum_values,omitempty" db:"enum_values"`
+	Pattern     *string    `json:"pattern,omitempty" db:"pattern"`
+	Description *string    `json:"description,omitempty" db:"description"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// CreateRequest captures definition payloads.
+type CreateRequest struct {
+	TenantID    *uuid.UUID `json:"tenant_id"`
+	Key         string     `json:"key" validate:"required,max=40"`
+	Label       string     `json:"label" validate:"required,max=100"`
+	Type        string     `json:"type" validate:"required,oneof=string integer number boolean date enum"`
+	Required    bool       `json:"required"`
+	EnumValues  []string   `json:"enum_values" validate:"omitempty,unique,dive,required,max=100"`
+	Pattern     *string    `json:"pattern" validate:"omitempty,max=500"`
+	Description *string    `json:"description" validate:"omitempty,max=500"`
+}
+
+// UpdateRequest replaces the mutable parts of a definition; the key and
+// tenant scope are fixed once created.
+type UpdateRequest struct {
+	Label       string   `json:"label" validate:"required,max=100"`
+	Type        string   `json:"type" validate:"required,oneof=string integer number boolean date enum"`
+	Required    bool     `json:"required"`
+	EnumValues  []string `json:"enum_values" validate:"omitempty,unique,dive,required,max=100"`
+	Pattern     *string  `json:"pattern" validate:"omitempty,max=500"`
+	Description *string  `json:"description" validate:"omitempty,max=500"`
+}
+
+// Filter selects definitions: the global ones plus those of TenantID, or
+// every definition when AllTenants is set.
+type Filter struct {
+	TenantID   *uuid.UUID
+	AllTenants bool
+}
+
+// StringList stores a string slice as a JSON array column.
+type StringList []string
+
+// Value implements driver.Valuer.
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan implements sql.Scanner.
+func (l *StringList) Scan(src interface{}) error {
+	var raw []byte
+	switch data := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		raw = data
+	case string:
+		raw = []byte(data)
+	default:
+		return fmt.Errorf("enum_values: unsupported type %T", src)
+	}
+	return json.Unmarshal(raw, (*[]string)(l))
+}
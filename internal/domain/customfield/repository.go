@@ -0,0 +1,17 @@
+package customfield
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Repository persists custom field definitions.
+type Repository interface {
+	Create(ctx context.Context, def *Definition) error
+	Update(ctx context.Context, def *Definition) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Definition, error)
+	// List returns definitions ordered by key, global ones first.
+	List(ctx context.Context, filter Filter) ([]Definition, error)
+}
@@ -0,0 +1,304 @@
+package customfield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/microcosm-cc/bluemonday"
+
+	"github.com/kidpech/api_free_demo/pkg/tenancy"
+)
+
+// Sentinel errors for HTTP mapping.
+var (
+	ErrNotFound          = errors.New("custom field not found")
+	ErrKeyTaken          = errors.New("custom field key already defined in this scope")
+	ErrInvalidKey        = errors.New("key must start with a letter and use lowercase letters, digits and underscores")
+	ErrInvalidDefinition = errors.New("invalid custom field definition")
+	ErrTenantNotFound    = errors.New("tenant not found")
+)
+
+// MaxStringLength bounds string values stored in a custom field.
+const MaxStringLength = 1000
+
+var keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+// Service manages definitions and validates values against them.
+type Service struct {
+	repo      Repository
+	validator *validator.Validate
+	sanitizer *bluemonday.Policy
+}
+
+// NewService wires a custom field Service.
+func NewService(repo Repository) *Service {
+	return &Service{
+		repo:      repo,
+		validator: validator.New(),
+		sanitizer: bluemonday.StrictPolicy(),
+	}
+}
+
+// Create adds a definition to the global or a tenant scope.
+func (s *Service) Create(ctx context.Context, req CreateRequest) (*Definition, error) {
+	req.Key = strings.TrimSpace(req.Key)
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+	if !keyPattern.MatchString(req.Key) {
+		return nil, ErrInvalidKey
+	}
+	existing, err := s.repo.List(ctx, Filter{TenantID: req.TenantID})
+	if err != nil {
+		return nil, err
+	}
+	for _, def := range existing {
+		if def.Key == req.Key && sameScope(def.TenantID, req.TenantID) {
+			return nil, ErrKeyTaken
+		}
+	}
+	now := time.Now().UTC()
+	def := &Definition{ID: uuid.New(), TenantID: req.TenantID, Key: req.Key, CreatedAt: now, UpdatedAt: now}
+	if err := s.apply(def, UpdateRequest{
+		Label:       req.Label,
+		Type:        req.Type,
+		Required:    req.Required,
+		EnumValues:  req.EnumValues,
+		Pattern:     req.Pattern,
+		Description: req.Description,
+	}); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, def); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// Update replaces a definition's label, type and constraints. Stored
+// values are not rewritten; they are checked again the next time a profile
+// changes its custom fields.
+func (s *Service) Update(ctx context.Context, id uuid.UUID, req UpdateRequest) (*Definition, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+	def, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(def, req); err != nil {
+		return nil, err
+	}
+	def.UpdatedAt = time.Now().UTC()
+	if err := s.repo.Update(ctx, def); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// Delete removes a definition. Values already stored under its key are
+// kept but no longer accepted on write.
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// Get returns one definition.
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Definition, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// List returns definitions for administration.
+func (s *Service) List(ctx context.Context, filter Filter) ([]Definition, error) {
+	return s.repo.List(ctx, filter)
+}
+
+// Effective returns the definitions that apply in ctx, keyed by field: the
+// global ones, overridden by those of the active tenant.
+func (s *Service) Effective(ctx context.Context) (map[string]Definition, error) {
+	filter := Filter{}
+	if tenantID, ok := tenancy.FromContext(ctx); ok {
+		filter.TenantID = &tenantID
+	}
+	defs, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]Definition, len(defs))
+	for _, def := range defs {
+		if _, taken := out[def.Key]; taken && def.TenantID == nil {
+			continue
+		}
+		out[def.Key] = def
+	}
+	return out, nil
+}
+
+// Normalize checks values against the definitions that apply in ctx and
+// returns them in canonical form. Problems are reported per field; the
+// error is only set when the definitions could not be loaded.
+func (s *Service) Normalize(ctx context.Context, values map[string]interface{}) (map[string]interface{}, map[string]string, error) {
+	defs, err := s.Effective(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := make(map[string]interface{}, len(values))
+	problems := make(map[string]string)
+	for key, val := range values {
+		def, ok := defs[key]
+		if !ok {
+			problems[key] = "unknown field"
+			continue
+		}
+		if val == nil {
+			continue
+		}
+		norm, err := s.normalizeValue(def, val)
+		if err != nil {
+			problems[key] = err.Error()
+			continue
+		}
+		out[key] = norm
+	}
+	for key, def := range defs {
+		if _, set := out[key]; def.Required && !set && problems[key] == "" {
+			problems[key] = "required"
+		}
+	}
+	return out, problems, nil
+}
+
+// FilterValue checks a list filter on key and returns value in the text
+// form stored values are compared in.
+func (s *Service) FilterValue(ctx context.Context, key, value string) (string, error) {
+	defs, err := s.Effective(ctx)
+	if err != nil {
+		return "", err
+	}
+	def, ok := defs[key]
+	if !ok {
+		return "", fmt.Errorf("unknown field")
+	}
+	switch def.Type {
+	case TypeBoolean:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("must be true or false")
+		}
+		return strconv.FormatBool(b), nil
+	case TypeInteger:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("must be an integer")
+		}
+		return strconv.FormatInt(n, 10), nil
+	case TypeNumber:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", fmt.Errorf("must be a number")
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+	norm, err := s.normalizeValue(def, value)
+	if err != nil {
+		return "", err
+	}
+	return norm.(string), nil
+}
+
+func (s *Service) normalizeValue(def Definition, value interface{}) (interface{}, error) {
+	switch def.Type {
+	case TypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("must be a boolean")
+		}
+		return b, nil
+	case TypeInteger:
+		f, ok := value.(float64)
+		if !ok || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		return f, nil
+	case TypeNumber:
+		f, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return f, nil
+	}
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("must be a string")
+	}
+	switch def.Type {
+	case TypeDate:
+		if _, err := time.Parse("2006-01-02", str); err != nil {
+			return nil, fmt.Errorf("must be a date like 2006-01-02")
+		}
+		return str, nil
+	case TypeEnum:
+		for _, allowed := range def.EnumValues {
+			if str == allowed {
+				return str, nil
+			}
+		}
+		return nil, fmt.Errorf("must be one of %s", strings.Join(def.EnumValues, ", "))
+	}
+	str = strings.TrimSpace(s.sanitizer.Sanitize(str))
+	if utf8.RuneCountInString(str) > MaxStringLength {
+		return nil, fmt.Errorf("must be at most %d characters", MaxStringLength)
+	}
+	if def.Pattern != nil && !regexp.MustCompile(*def.Pattern).MatchString(str) {
+		return nil, fmt.Errorf("must match %s", *def.Pattern)
+	}
+	return str, nil
+}
+
+// apply copies req onto def after checking that the constraints fit the
+// type.
+func (s *Service) apply(def *Definition, req UpdateRequest) error {
+	if req.Type == TypeEnum && len(req.EnumValues) == 0 {
+		return fmt.Errorf("%w: enum fields need enum_values", ErrInvalidDefinition)
+	}
+	if req.Type != TypeEnum && len(req.EnumValues) > 0 {
+		return fmt.Errorf("%w: enum_values only apply to enum fields", ErrInvalidDefinition)
+	}
+	if req.Pattern != nil && *req.Pattern == "" {
+		req.Pattern = nil
+	}
+	if req.Pattern != nil {
+		if req.Type != TypeString {
+			return fmt.Errorf("%w: pattern only applies to string fields", ErrInvalidDefinition)
+		}
+		if _, err := regexp.Compile(*req.Pattern); err != nil {
+			return fmt.Errorf("%w: pattern does not compile", ErrInvalidDefinition)
+		}
+	}
+	def.Label = strings.TrimSpace(s.sanitizer.Sanitize(req.Label))
+	def.Type = req.Type
+	def.Required = req.Required
+	def.EnumValues = req.EnumValues
+	def.Pattern = req.Pattern
+	def.Description = req.Description
+	if def.Description != nil {
+		clean := strings.TrimSpace(s.sanitizer.Sanitize(*def.Description))
+		def.Description = &clean
+	}
+	return nil
+}
+
+func sameScope(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,131 @@
+package customfield
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kidpech/api_free_demo/pkg/tenancy"
+)
+
+func TestNormalizeAppliesTenantOverrides(t *testing.T) {
+	repo := &fakeRepo{}
+	service := NewService(repo)
+	tenantID := uuid.New()
+	ctx := context.Background()
+	_, err := service.Create(ctx, CreateRequest{Key: "department", Label: "Department", Type: TypeString, Required: true})
+	require.NoError(t, err)
+	_, err = service.Create(ctx, CreateRequest{TenantID: &tenantID, Key: "department", Label: "Department", Type: TypeEnum, EnumValues: []string{"eng", "ops"}})
+	require.NoError(t, err)
+
+	_, problems, err := service.Normalize(ctx, map[string]interface{}{"pronouns": "they/them"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"pronouns": "unknown field", "department": "required"}, problems)
+
+	values, problems, err := service.Normalize(tenancy.WithTenant(ctx, tenantID), map[string]interface{}{"department": "eng"})
+	require.NoError(t, err)
+	require.Empty(t, problems)
+	require.Equal(t, "eng", values["department"])
+
+	_, problems, err = service.Normalize(tenancy.WithTenant(ctx, tenantID), map[string]interface{}{"department": "sales"})
+	require.NoError(t, err)
+	require.Contains(t, problems["department"], "must be one of")
+}
+
+func TestCreateRejectsBadDefinitions(t *testing.T) {
+	service := NewService(&fakeRepo{})
+	ctx := context.Background()
+	pattern := "^[0-9]+$"
+
+	_, err := service.Create(ctx, CreateRequest{Key: "Employee-No", Label: "Employee number", Type: TypeString})
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = service.Create(ctx, CreateRequest{Key: "level", Label: "Level", Type: TypeEnum})
+	require.ErrorIs(t, err, ErrInvalidDefinition)
+	_, err = service.Create(ctx, CreateRequest{Key: "level", Label: "Level", Type: TypeInteger, Pattern: &pattern})
+	require.ErrorIs(t, err, ErrInvalidDefinition)
+
+	_, err = service.Create(ctx, CreateRequest{Key: "employee_no", Label: "Employee number", Type: TypeString, Pattern: &pattern})
+	require.NoError(t, err)
+	_, err = service.Create(ctx, CreateRequest{Key: "employee_no", Label: "Employee number", Type: TypeString})
+	require.ErrorIs(t, err, ErrKeyTaken)
+
+	_, problems, err := service.Normalize(ctx, map[string]interface{}{"employee_no": "A12"})
+	require.NoError(t, err)
+	require.Contains(t, problems["employee_no"], "must match")
+}
+
+func TestFilterValueMatchesStoredForm(t *testing.T) {
+	service := NewService(&fakeRepo{})
+	ctx := context.Background()
+	_, err := service.Create(ctx, CreateRequest{Key: "remote", Label: "Remote", Type: TypeBoolean})
+	require.NoError(t, err)
+	_, err = service.Create(ctx, CreateRequest{Key: "floor", Label: "Floor", Type: TypeInteger})
+	require.NoError(t, err)
+
+	value, err := service.FilterValue(ctx, "remote", "1")
+	require.NoError(t, err)
+	require.Equal(t, "true", value)
+	value, err = service.FilterValue(ctx, "floor", "007")
+	require.NoError(t, err)
+	require.Equal(t, "7", value)
+	_, err = service.FilterValue(ctx, "floor", "high")
+	require.Error(t, err)
+	_, err = service.FilterValue(ctx, "missing", "x")
+	require.Error(t, err)
+}
+
+type fakeRepo struct {
+	defs []Definition
+}
+
+func (r *fakeRepo) Create(_ context.Context, def *Definition) error {
+	r.defs = append(r.defs, *def)
+	return nil
+}
+
+func (r *fakeRepo) Update(_ context.Context, def *Definition) error {
+	for i := range r.defs {
+		if r.defs[i].ID == def.ID {
+			r.defs[i] = *def
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *fakeRepo) Delete(_ context.Context, id uuid.UUID) error {
+	for i := range r.defs {
+		if r.defs[i].ID == id {
+			r.defs = append(r.defs[:i], r.defs[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *fakeRepo) GetByID(_ context.Context, id uuid.UUID) (*Definition, error) {
+	for i := range r.defs {
+		if r.defs[i].ID == id {
+			def := r.defs[i]
+			return &def, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *fakeRepo) List(_ context.Context, filter Filter) ([]Definition, error) {
+	var out []Definition
+	for _, def := range r.defs {
+		if def.TenantID == nil {
+			out = append(out, def)
+		}
+	}
+	for _, def := range r.defs {
+		if def.TenantID != nil && (filter.AllTenants || (filter.TenantID != nil && *def.TenantID == *filter.TenantID)) {
+			out = append(out, def)
+		}
+	}
+	return out, nil
+}
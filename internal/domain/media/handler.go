@@ -0,0 +1,90 @@
+package media
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kidpech/api_free_demo/pkg/response"
+)
+
+// Handler serves signed media links.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler returns a media Handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes mounts the public download route; the signature is the
+// only credential, so it sits outside the auth middleware.
+func (h *Handler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.GET("/media/*key", h.serve)
+}
+
+func (h *Handler) serve(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	body, obj, err := h.service.Open(c.Request.Context(), key, c.Query("exp"), c.Query("sig"))
+	if err != nil {
+		if !RespondError(c, err) {
+			response.InternalServerError(c, err)
+		}
+		return
+	}
+	defer body.Close()
+
+	maxAge := int64(0)
+	if exp, err := strconv.ParseInt(c.Query("exp"), 10, 64); err == nil {
+		maxAge = max(exp-time.Now().Unix(), 0)
+	}
+	c.Header("Cache-Control", "private, max-age="+strconv.FormatInt(maxAge, 10))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, obj.Size, obj.ContentType, body, nil)
+}
+
+// ReadUpload returns the "file" part of a multipart request without
+// buffering the form; Service.Save enforces the size limit while reading.
+func ReadUpload(c *gin.Context) (io.Reader, error) {
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, ErrMissingFile
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, ErrMissingFile
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
+
+// RespondError writes the HTTP mapping for media errors and reports
+// whether err was one of them.
+func RespondError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, ErrTooLarge):
+		response.PayloadTooLarge(c, "image exceeds upload limit")
+	case errors.Is(err, ErrUnsupportedType):
+		response.UnsupportedMediaType(c, "image must be jpeg, png, gif or webp")
+	case errors.Is(err, ErrInvalidImage), errors.Is(err, ErrTooManyPixels), errors.Is(err, ErrMissingFile):
+		response.BadRequest(c, "invalid_image", err.Error())
+	case errors.Is(err, ErrInvalidSignature):
+		response.Forbidden(c, "invalid or expired link")
+	case errors.Is(err, ErrNotFound):
+		response.NotFound(c, "media")
+	case errors.Is(err, ErrDisabled):
+		c.JSON(http.StatusNotImplemented, response.ErrorResponse{Error: "uploads_disabled", Message: err.Error()})
+	default:
+		return false
+	}
+	return true
+}
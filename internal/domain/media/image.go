@@ -0,0 +1,143 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif" // register the gif decoder
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the webp decoder
+)
+
+// thumbnailSizes fixes thumbnail geometry per upload kind.
+var thumbnailSizes = map[string]image.Point{
+	KindAvatar: {X: 256, Y: 256},
+	KindCover:  {X: 960, Y: 320},
+}
+
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+type processed struct {
+	data        []byte
+	thumb       []byte
+	contentType string
+	ext         string
+	width       int
+	height      int
+}
+
+// processImage sniffs, decodes and re-encodes raw bytes. Re-encoding drops
+// EXIF and any other metadata the original carried.
+func processImage(raw []byte, kind string, cfg Config) (*processed, error) {
+	if !allowedTypes[http.DetectContentType(raw)] {
+		return nil, ErrUnsupportedType
+	}
+	imgCfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if imgCfg.Width <= 0 || imgCfg.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if cfg.MaxPixels > 0 && imgCfg.Width*imgCfg.Height > cfg.MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	main := fit(src, cfg.MaxEdge)
+	thumbSize, ok := thumbnailSizes[kind]
+	if !ok {
+		thumbSize = thumbnailSizes[KindAvatar]
+	}
+	thumb := cover(src, thumbSize.X, thumbSize.Y)
+
+	out := &processed{width: main.Bounds().Dx(), height: main.Bounds().Dy()}
+	opaque := isOpaque(src)
+	if out.data, err = encode(main, opaque, cfg.JPEGQuality); err != nil {
+		return nil, err
+	}
+	if out.thumb, err = encode(thumb, opaque, cfg.JPEGQuality); err != nil {
+		return nil, err
+	}
+	if opaque {
+		out.contentType, out.ext = "image/jpeg", ".jpg"
+	} else {
+		out.contentType, out.ext = "image/png", ".png"
+	}
+	return out, nil
+}
+
+// fit downscales so the longest edge is at most maxEdge.
+func fit(src image.Image, maxEdge int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if maxEdge <= 0 || (w <= maxEdge && h <= maxEdge) {
+		dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+		return dst
+	}
+	if w >= h {
+		h = h * maxEdge / w
+		w = maxEdge
+	} else {
+		w = w * maxEdge / h
+		h = maxEdge
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, max(w, 1), max(h, 1)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	return dst
+}
+
+// cover scales and center-crops src to exactly w x h.
+func cover(src image.Image, w, h int) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	crop := b
+	if sw*h > sh*w {
+		cw := sh * w / h
+		x0 := b.Min.X + (sw-cw)/2
+		crop = image.Rect(x0, b.Min.Y, x0+cw, b.Max.Y)
+	} else {
+		ch := sw * h / w
+		y0 := b.Min.Y + (sh-ch)/2
+		crop = image.Rect(b.Min.X, y0, b.Max.X, y0+ch)
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+	return dst
+}
+
+func encode(img image.Image, opaque bool, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if opaque {
+		if quality <= 0 {
+			quality = 85
+		}
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
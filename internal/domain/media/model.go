@@ -0,0 +1,62 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// Upload kinds; each has its own thumbnail geometry.
+const (
+	KindAvatar = "avatar"
+	KindCover  = "cover"
+)
+
+// Sentinel errors for HTTP mapping.
+var (
+	ErrNotFound         = errors.New("media not found")
+	ErrTooLarge         = errors.New("upload too large")
+	ErrUnsupportedType  = errors.New("unsupported image type")
+	ErrInvalidImage     = errors.New("invalid image")
+	ErrInvalidSignature = errors.New("invalid or expired media signature")
+	ErrTooManyPixels    = errors.New("image dimensions too large")
+	ErrDisabled         = errors.New("uploads are not configured")
+	ErrMissingFile      = errors.New("multipart field \"file\" is required")
+)
+
+// Object describes a stored blob.
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+}
+
+// BlobStore abstracts where uploaded files live.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Upload reports a processed image and its thumbnail.
+type Upload struct {
+	Key          string    `json:"key"`
+	ContentType  string    `json:"content_type"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Config tunes upload limits and URL signing.
+type Config struct {
+	BaseURL     string
+	Secret      []byte
+	URLTTL      time.Duration
+	MaxBytes    int64
+	MaxPixels   int
+	MaxEdge     int
+	JPEGQuality int
+}
@@ -0,0 +1,105 @@
+package media
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+)
+
+// Service stores processed images and hands out signed links to them.
+type Service struct {
+	store BlobStore
+	cfg   Config
+	now   func() time.Time
+}
+
+// NewService wires a media Service, filling unset limits with defaults.
+func NewService(store BlobStore, cfg Config) *Service {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 5 << 20
+	}
+	if cfg.MaxPixels <= 0 {
+		cfg.MaxPixels = 40_000_000
+	}
+	if cfg.MaxEdge <= 0 {
+		cfg.MaxEdge = 2048
+	}
+	if cfg.JPEGQuality <= 0 {
+		cfg.JPEGQuality = 85
+	}
+	if cfg.URLTTL <= 0 {
+		cfg.URLTTL = time.Hour
+	}
+	return &Service{store: store, cfg: cfg, now: time.Now}
+}
+
+// Save validates and re-encodes an upload, then stores the image and its
+// thumbnail under a fresh key owned by owner.
+func (s *Service) Save(ctx context.Context, kind, owner string, r io.Reader) (*Upload, error) {
+	raw, err := io.ReadAll(io.LimitReader(r, s.cfg.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(raw)) > s.cfg.MaxBytes {
+		return nil, ErrTooLarge
+	}
+	if len(raw) == 0 {
+		return nil, ErrMissingFile
+	}
+	img, err := processImage(raw, kind, s.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	key := kind + "/" + owner + "/" + hex.EncodeToString(id) + img.ext
+	if err := s.store.Put(ctx, key, img.data, img.contentType); err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(ctx, thumbKey(key), img.thumb, img.contentType); err != nil {
+		_ = s.store.Delete(ctx, key)
+		return nil, err
+	}
+
+	up := &Upload{Key: key, ContentType: img.contentType, Width: img.width, Height: img.height}
+	up.URL, up.ExpiresAt = s.signedURL(key)
+	up.ThumbnailURL, _ = s.signedURL(thumbKey(key))
+	return up, nil
+}
+
+// URLs returns signed links for an original and its thumbnail.
+func (s *Service) URLs(key string) (string, string) {
+	original, _ := s.signedURL(key)
+	thumb, _ := s.signedURL(thumbKey(key))
+	return original, thumb
+}
+
+// Delete removes an original and its thumbnail. Missing blobs are ignored.
+func (s *Service) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrNotFound
+	}
+	for _, k := range []string{key, thumbKey(key)} {
+		if err := s.store.Delete(ctx, k); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open verifies a signed request and returns the blob it points at.
+func (s *Service) Open(ctx context.Context, key, exp, sig string) (io.ReadCloser, *Object, error) {
+	if !validKey(key) {
+		return nil, nil, ErrNotFound
+	}
+	if err := s.verify(key, exp, sig, false); err != nil {
+		return nil, nil, err
+	}
+	return s.store.Get(ctx, key)
+}
@@ -0,0 +1,90 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type memStore struct {
+	blobs map[string][]byte
+}
+
+func (m *memStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	m.blobs[key] = data
+	return nil
+}
+
+func (m *memStore) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	data, ok := m.blobs[key]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), &Object{Key: key, Size: int64(len(data))}, nil
+}
+
+func (m *memStore) Delete(ctx context.Context, key string) error {
+	delete(m.blobs, key)
+	return nil
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.NRGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestSaveStoresThumbnailAndSignsLinks(t *testing.T) {
+	store := &memStore{blobs: map[string][]byte{}}
+	svc := NewService(store, Config{BaseURL: "http://api.test", Secret: []byte("s3cret")})
+	owner := "7d444840-9dc0-11d1-b245-5ffdce74fad2"
+
+	up, err := svc.Save(context.Background(), KindCover, owner, bytes.NewReader(testPNG(t, 1200, 600)))
+	require.NoError(t, err)
+	require.Len(t, store.blobs, 2)
+
+	thumb, _, err := image.DecodeConfig(bytes.NewReader(store.blobs[thumbKey(up.Key)]))
+	require.NoError(t, err)
+	require.Equal(t, 960, thumb.Width)
+	require.Equal(t, 320, thumb.Height)
+
+	u, err := url.Parse(up.URL)
+	require.NoError(t, err)
+	key := strings.TrimPrefix(u.Path, RoutePrefix)
+	body, _, err := svc.Open(context.Background(), key, u.Query().Get("exp"), u.Query().Get("sig"))
+	require.NoError(t, err)
+	body.Close()
+
+	_, _, err = svc.Open(context.Background(), key, u.Query().Get("exp"), "forged")
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	got, ok := svc.KeyFromURL(up.ThumbnailURL)
+	require.True(t, ok)
+	require.Equal(t, up.Key, got)
+
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, _, err = svc.Open(context.Background(), key, u.Query().Get("exp"), u.Query().Get("sig"))
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestSaveRejectsNonImagesAndOversizedUploads(t *testing.T) {
+	svc := NewService(&memStore{blobs: map[string][]byte{}}, Config{MaxBytes: 1024})
+	_, err := svc.Save(context.Background(), KindAvatar, "x", strings.NewReader("<html>not an image</html>"))
+	require.ErrorIs(t, err, ErrUnsupportedType)
+
+	_, err = svc.Save(context.Background(), KindAvatar, "x", bytes.NewReader(make([]byte, 2048)))
+	require.ErrorIs(t, err, ErrTooLarge)
+}
@@ -0,0 +1,202 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/pkg/jsonpatch"
+)
+
+// MaxBulkItems bounds the number of items in a single bulk request.
+const MaxBulkItems = 100
+
+// Bulk modes. Atomic requests apply every item or none; partial requests
+// apply what they can and report the rest.
+const (
+	BulkModeAtomic  = "atomic"
+	BulkModePartial = "partial"
+)
+
+// Bulk item statuses.
+const (
+	BulkStatusCreated  = "created"
+	BulkStatusUpdated  = "updated"
+	BulkStatusRestored = "restored"
+	BulkStatusFailed   = "failed"
+	// BulkStatusRolledBack marks items that succeeded before a later item
+	// failed an atomic request.
+	BulkStatusRolledBack = "rolled_back"
+	// BulkStatusSkipped marks items an atomic request never reached.
+	BulkStatusSkipped = "skipped"
+)
+
+// ErrBulkTooLarge is returned for bulk requests over MaxBulkItems.
+var ErrBulkTooLarge = fmt.Errorf("bulk requests are limited to %d items", MaxBulkItems)
+
+// BulkCreate creates each profile in req.
+func (s *Service) BulkCreate(ctx context.Context, userID uuid.UUID, req BulkCreateRequest) (*BulkReport, error) {
+	return s.runBulk(ctx, req.Mode, len(req.Profiles), nil, BulkStatusCreated, func(svc *Service, i int) (*Profile, error) {
+		return svc.Create(ctx, userID, req.Profiles[i])
+	})
+}
+
+// BulkPatch applies a merge patch or JSON Patch to each listed profile.
+func (s *Service) BulkPatch(ctx context.Context, userID uuid.UUID, req BulkPatchRequest) (*BulkReport, error) {
+	ids := make([]uuid.UUID, len(req.Items))
+	for i, item := range req.Items {
+		ids[i] = item.ID
+	}
+	return s.runBulk(ctx, req.Mode, len(req.Items), ids, BulkStatusUpdated, func(svc *Service, i int) (*Profile, error) {
+		item := req.Items[i]
+		doc := PatchDocument{Type: jsonpatch.MergePatchType, Body: item.Patch, Version: item.Version}
+		if bytes.HasPrefix(bytes.TrimSpace(item.Patch), []byte("[")) {
+			doc.Type = jsonpatch.JSONPatchType
+		}
+		return svc.Patch(ctx, item.ID, userID, doc)
+	})
+}
+
+// BulkRestore takes each listed profile out of the trash.
+func (s *Service) BulkRestore(ctx context.Context, userID uuid.UUID, req BulkRestoreRequest) (*BulkReport, error) {
+	return s.runBulk(ctx, req.Mode, len(req.IDs), req.IDs, BulkStatusRestored, func(svc *Service, i int) (*Profile, error) {
+		return svc.Restore(ctx, req.IDs[i], userID, nil)
+	})
+}
+
+// runBulk applies op to items 0..n-1 and reports each outcome. Atomic mode
+// runs every op on a transaction-bound copy of the service and stops at the
+// first failure; image cleanup waits for the commit. The returned error is
+// only set when the request could not be carried out at all.
+func (s *Service) runBulk(ctx context.Context, mode string, n int, ids []uuid.UUID, done string, op func(svc *Service, i int) (*Profile, error)) (*BulkReport, error) {
+	if n > MaxBulkItems {
+		return nil, ErrBulkTooLarge
+	}
+	return s.runItems(ctx, mode, n, ids, done, op)
+}
+
+// runItems is runBulk without the item limit, for callers with their own.
+func (s *Service) runItems(ctx context.Context, mode string, n int, ids []uuid.UUID, done string, op func(svc *Service, i int) (*Profile, error)) (*BulkReport, error) {
+	if mode != BulkModeAtomic {
+		mode = BulkModePartial
+	}
+	report := &BulkReport{Mode: mode, Results: make([]BulkResult, n)}
+	for i := range report.Results {
+		report.Results[i].Index = i
+		if ids != nil {
+			id := ids[i]
+			report.Results[i].ID = &id
+		}
+	}
+	succeed := func(i int, p *Profile) {
+		res := &report.Results[i]
+		res.Status, res.Profile, res.ID = done, p, &p.ID
+	}
+	fail := func(i int, err error) {
+		res := &report.Results[i]
+		res.Status = BulkStatusFailed
+		res.Code, res.Error, res.Details = bulkError(err)
+		report.Failed++
+	}
+
+	if mode == BulkModePartial {
+		for i := 0; i < n; i++ {
+			p, err := op(s, i)
+			if err != nil {
+				fail(i, err)
+				continue
+			}
+			succeed(i, p)
+			report.Succeeded++
+		}
+		return report, nil
+	}
+
+	failed := -1
+	err := s.transact(ctx, func(tx *Service) error {
+		for i := 0; i < n; i++ {
+			p, err := op(tx, i)
+			if err != nil {
+				failed = i
+				return err
+			}
+			succeed(i, p)
+		}
+		return nil
+	})
+	if err != nil && failed < 0 {
+		return nil, err
+	}
+	if failed >= 0 {
+		for i := range report.Results {
+			res := &report.Results[i]
+			switch {
+			case i == failed:
+				fail(i, err)
+			case i < failed:
+				res.Status, res.Profile = BulkStatusRolledBack, nil
+				if ids == nil {
+					res.ID = nil
+				}
+			default:
+				res.Status = BulkStatusSkipped
+			}
+		}
+		return report, nil
+	}
+	report.Succeeded = n
+	return report, nil
+}
+
+// bulkError maps an item failure to the code, message and details the
+// single-item endpoints would respond with.
+func bulkError(err error) (code, message string, details interface{}) {
+	var verr validator.ValidationErrors
+	var unknown *UnknownFieldsError
+	var custom *CustomFieldsError
+	switch {
+	case errors.As(err, &verr):
+		fields := make(map[string]string)
+		for _, field := range verr {
+			fields[strings.ToLower(field.Field())] = field.Tag()
+		}
+		return "validation_error", "invalid request", fields
+	case errors.As(err, &unknown):
+		return "unknown_fields", err.Error(), unknown.Fields
+	case errors.As(err, &custom):
+		return "invalid_custom_fields", err.Error(), custom.Fields
+	case errors.Is(err, ErrNotFound):
+		return "not_found", "profile not found", nil
+	case errors.Is(err, ErrVersionConflict):
+		return "version_conflict", "profile updated elsewhere", nil
+	case errors.Is(err, ErrHandleTaken):
+		return "handle_taken", err.Error(), nil
+	case errors.Is(err, ErrInvalidHandle):
+		return "invalid_handle", err.Error(), nil
+	case errors.Is(err, ErrInvalidPhone):
+		return "invalid_phone", err.Error(), nil
+	case errors.Is(err, ErrInvalidWebsite):
+		return "invalid_website", err.Error(), nil
+	case errors.Is(err, ErrInvalidCoordinates):
+		return "invalid_coordinates", err.Error(), nil
+	case errors.Is(err, ErrUnknownLocation):
+		return "unknown_location", err.Error(), nil
+	case errors.Is(err, ErrTooManyTags):
+		return "too_many_tags", err.Error(), nil
+	case errors.Is(err, ErrInvalidTag):
+		return "invalid_tag", err.Error(), nil
+	case errors.Is(err, ErrInvalidRow):
+		return "invalid_row", err.Error(), nil
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		return "patch_test_failed", err.Error(), nil
+	case errors.Is(err, jsonpatch.ErrInvalid):
+		return "invalid_patch", err.Error(), nil
+	default:
+		return "internal_error", "internal error", nil
+	}
+}
@@ -0,0 +1,81 @@
+package profile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkCreateModes(t *testing.T) {
+	repo := &bulkRepo{profiles: map[uuid.UUID]Profile{}}
+	svc := NewService(repo)
+	userID := uuid.New()
+	items := []CreateRequest{
+		{FirstName: "Ada", LastName: "Lovelace"},
+		{FirstName: "Nameless"},
+		{FirstName: "Alan", LastName: "Turing"},
+	}
+
+	report, err := svc.BulkCreate(context.Background(), userID, BulkCreateRequest{Mode: BulkModeAtomic, Profiles: items})
+	require.NoError(t, err)
+	require.Equal(t, 0, report.Succeeded)
+	require.Equal(t, 1, report.Failed)
+	require.Equal(t, []string{BulkStatusRolledBack, BulkStatusFailed, BulkStatusSkipped}, bulkStatuses(report))
+	require.Nil(t, report.Results[0].ID)
+	require.Equal(t, "validation_error", report.Results[1].Code)
+	require.Empty(t, repo.profiles)
+
+	report, err = svc.BulkCreate(context.Background(), userID, BulkCreateRequest{Profiles: items})
+	require.NoError(t, err)
+	require.Equal(t, BulkModePartial, report.Mode)
+	require.Equal(t, []string{BulkStatusCreated, BulkStatusFailed, BulkStatusCreated}, bulkStatuses(report))
+	require.Equal(t, 2, report.Succeeded)
+	require.Equal(t, 2, report.Results[2].Index)
+	require.Equal(t, report.Results[2].Profile.ID, *report.Results[2].ID)
+	require.Len(t, repo.profiles, 2)
+
+	_, err = svc.BulkCreate(context.Background(), userID, BulkCreateRequest{Profiles: make([]CreateRequest, MaxBulkItems+1)})
+	require.ErrorIs(t, err, ErrBulkTooLarge)
+}
+
+func bulkStatuses(report *BulkReport) []string {
+	statuses := make([]string, len(report.Results))
+	for i, res := range report.Results {
+		statuses[i] = res.Status
+	}
+	return statuses
+}
+
+// bulkRepo is an in-memory Repository whose transactions roll back by
+// restoring a copy of the stored profiles.
+type bulkRepo struct {
+	Repository
+	profiles map[uuid.UUID]Profile
+}
+
+func (r *bulkRepo) Create(ctx context.Context, p *Profile) error {
+	r.profiles[p.ID] = *p
+	return nil
+}
+
+func (r *bulkRepo) CreateRevision(ctx context.Context, rev *Revision) error {
+	return nil
+}
+
+func (r *bulkRepo) ListTags(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]string, error) {
+	return map[uuid.UUID][]string{}, nil
+}
+
+func (r *bulkRepo) Transact(ctx context.Context, fn func(Repository) error) error {
+	saved := make(map[uuid.UUID]Profile, len(r.profiles))
+	for id, p := range r.profiles {
+		saved[id] = p
+	}
+	if err := fn(r); err != nil {
+		r.profiles = saved
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package profile
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/kidpech/api_free_demo/pkg/phone"
+)
+
+// Contact errors. ErrInvalidPhone is wrapped with the reason a number was
+// rejected.
+var (
+	ErrInvalidPhone   = phone.ErrInvalid
+	ErrInvalidWebsite = errors.New("website must be an http or https URL")
+)
+
+// trackingParams are query parameters dropped from websites on top of any
+// utm_* parameter.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "yclid": true,
+	"igshid": true, "mc_cid": true, "mc_eid": true, "_ga": true,
+}
+
+// canonicalWebsite lowercases scheme and host and drops default ports, a
+// bare trailing slash and tracking parameters. An empty value clears the
+// website.
+func canonicalWebsite(raw string) (*string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, ErrInvalidWebsite
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return nil, ErrInvalidWebsite
+	}
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	u.Host = host
+	if port != "" {
+		u.Host += ":" + port
+	}
+	if u.Path == "/" {
+		u.Path, u.RawPath = "", ""
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			if strings.HasPrefix(strings.ToLower(key), "utm_") || trackingParams[strings.ToLower(key)] {
+				query.Del(key)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	u.ForceQuery = false
+	canonical := u.String()
+	return &canonical, nil
+}
+
+// normalizePhone derives PhoneE164 from the phone and country of p. prev is
+// the stored profile when editing: if neither value changed its normalized
+// number is kept, so numbers saved before normalization existed do not
+// block edits to other fields.
+func normalizePhone(p, prev *Profile) error {
+	if prev != nil && reflect.DeepEqual(p.Phone, prev.Phone) && reflect.DeepEqual(p.Country, prev.Country) {
+		p.PhoneE164 = prev.PhoneE164
+		return nil
+	}
+	p.PhoneE164 = nil
+	if p.Phone == nil || *p.Phone == "" {
+		return nil
+	}
+	var region string
+	if p.Country != nil {
+		region = *p.Country
+	}
+	e164, err := phone.Normalize(*p.Phone, region)
+	if err != nil {
+		return err
+	}
+	p.PhoneE164 = &e164
+	return nil
+}
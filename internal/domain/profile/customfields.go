@@ -0,0 +1,94 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidFilter is returned for list filters on unknown custom fields or
+// with values the field cannot hold.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// CustomFieldSchema checks custom field values against the definitions
+// that apply in ctx.
+type CustomFieldSchema interface {
+	// Normalize returns values in canonical form plus a problem per
+	// offending field.
+	Normalize(ctx context.Context, values map[string]interface{}) (map[string]interface{}, map[string]string, error)
+	// FilterValue returns value in the text form stored values compare in.
+	FilterValue(ctx context.Context, key, value string) (string, error)
+}
+
+// WithCustomFields validates custom field values against schema. Without
+// it profiles accept no custom fields.
+func WithCustomFields(schema CustomFieldSchema) Option {
+	return func(s *Service) {
+		s.fields = schema
+	}
+}
+
+// CustomFieldsError lists custom fields whose values were rejected.
+type CustomFieldsError struct {
+	Fields map[string]string
+}
+
+func (e *CustomFieldsError) Error() string {
+	keys := make([]string, 0, len(e.Fields))
+	for key := range e.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key + " " + e.Fields[key]
+	}
+	return "invalid custom fields: " + strings.Join(parts, "; ")
+}
+
+// checkCustomFields validates the full set of custom values a profile is
+// about to store and returns them normalized.
+func (s *Service) checkCustomFields(ctx context.Context, values CustomFields) (CustomFields, error) {
+	if s.fields == nil {
+		if len(values) == 0 {
+			return nil, nil
+		}
+		problems := make(map[string]string, len(values))
+		for key := range values {
+			problems[key] = "unknown field"
+		}
+		return nil, &CustomFieldsError{Fields: problems}
+	}
+	norm, problems, err := s.fields.Normalize(ctx, values)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, &CustomFieldsError{Fields: problems}
+	}
+	if len(norm) == 0 {
+		return nil, nil
+	}
+	return norm, nil
+}
+
+// customFilters resolves list filters to the stored text form.
+func (s *Service) customFilters(ctx context.Context, filters map[string]string) (map[string]string, error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(filters))
+	for key, value := range filters {
+		if s.fields == nil {
+			return nil, fmt.Errorf("%w: unknown custom field %s", ErrInvalidFilter, key)
+		}
+		norm, err := s.fields.FilterValue(ctx, key, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: custom field %s %v", ErrInvalidFilter, key, err)
+		}
+		out[key] = norm
+	}
+	return out, nil
+}
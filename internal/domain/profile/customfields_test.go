@@ -0,0 +1,69 @@
+package profile
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kidpech/api_free_demo/pkg/jsonpatch"
+)
+
+func TestPatchValidatesCustomFields(t *testing.T) {
+	repo, p := newPatchRepo()
+	svc := NewService(repo, WithCustomFields(departmentSchema{}))
+
+	_, err := svc.Patch(context.Background(), p.ID, p.UserID, PatchDocument{
+		Type: jsonpatch.JSONPatchType,
+		Body: []byte(`[{"op":"add","path":"/custom_fields/department","value":"sales"},{"op":"add","path":"/custom_fields/shoe","value":42}]`),
+	})
+	var custom *CustomFieldsError
+	require.ErrorAs(t, err, &custom)
+	require.Equal(t, map[string]string{"department": "must be eng or ops", "shoe": "unknown field"}, custom.Fields)
+
+	updated, err := svc.Patch(context.Background(), p.ID, p.UserID, PatchDocument{
+		Type: jsonpatch.MergePatchType,
+		Body: []byte(`{"custom_fields":{"department":"eng"}}`),
+	})
+	require.NoError(t, err)
+	require.Equal(t, CustomFields{"department": "eng"}, updated.CustomFields)
+	require.Equal(t, []string{"custom_fields"}, repo.patched)
+}
+
+func TestCustomFieldsRejectedWithoutSchema(t *testing.T) {
+	svc := NewService(&bulkRepo{})
+
+	_, err := svc.Create(context.Background(), uuid.New(), CreateRequest{FirstName: "Jane", LastName: "Doe", CustomFields: CustomFields{"department": "eng"}})
+	var custom *CustomFieldsError
+	require.ErrorAs(t, err, &custom)
+
+	_, err = svc.List(context.Background(), Filter{Limit: 10, CustomFields: map[string]string{"department": "eng"}})
+	require.ErrorIs(t, err, ErrInvalidFilter)
+}
+
+// departmentSchema defines a single enum field.
+type departmentSchema struct{}
+
+func (departmentSchema) Normalize(ctx context.Context, values map[string]interface{}) (map[string]interface{}, map[string]string, error) {
+	out, problems := map[string]interface{}{}, map[string]string{}
+	for key, val := range values {
+		switch {
+		case key != "department":
+			problems[key] = "unknown field"
+		case val != "eng" && val != "ops":
+			problems[key] = "must be eng or ops"
+		default:
+			out[key] = val
+		}
+	}
+	return out, problems, nil
+}
+
+func (departmentSchema) FilterValue(ctx context.Context, key, value string) (string, error) {
+	if key != "department" {
+		return "", fmt.Errorf("unknown field")
+	}
+	return value, nil
+}
@@ -1,15 +1,25 @@
 package profile
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 
+	"github.com/kidpech/api_free_demo/internal/domain/media"
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+	"github.com/kidpech/api_free_demo/pkg/jsonpatch"
 	"github.com/kidpech/api_free_demo/pkg/response"
+	"github.com/kidpech/api_free_demo/pkg/vcard"
 )
 
 // Handler wires profile endpoints.
@@ -24,15 +34,38 @@ func NewHandler(service *Service) *Handler {
 
 // RegisterRoutes attaches routes onto router group.
 func (h *Handler) RegisterRoutes(rg *gin.RouterGroup, authMW gin.HandlerFunc) {
+	rg.GET("/public/profiles", h.searchPublic)
+	rg.GET("/public/profiles/:handle", h.getPublic)
+
 	authed := rg.Group("", authMW)
 	authed.POST("/profiles", h.create)
 	authed.POST("/profiles/bulk", h.bulkCreate)
 	authed.GET("/profiles", h.list)
+	authed.GET("/profiles/trash", h.trash)
+	authed.GET("/profiles/shared", h.shared)
+	authed.GET("/profiles/tags", h.tags)
+	authed.GET("/profiles/export", h.export)
+	authed.POST("/profiles/import", h.importProfiles)
 	authed.GET("/profiles/:id", h.get)
 	authed.PUT("/profiles/:id", h.update)
 	authed.PATCH("/profiles/:id", h.patch)
 	authed.DELETE("/profiles/:id", h.delete)
+	authed.POST("/profiles/:id/restore", h.restore)
+	authed.POST("/profiles/:id/avatar", h.uploadImage(media.KindAvatar))
+	authed.POST("/profiles/:id/cover", h.uploadImage(media.KindCover))
+	authed.PATCH("/profiles/bulk", h.bulkPatch)
 	authed.DELETE("/profiles/bulk", h.bulkDelete)
+	authed.POST("/profiles/bulk/restore", h.bulkRestore)
+	authed.POST("/profiles/bulk/tags", h.bulkTag)
+	authed.POST("/profiles/:id/tags", h.addTags)
+	authed.DELETE("/profiles/:id/tags/:tag", h.removeTag)
+	authed.GET("/profiles/:id/revisions", h.listRevisions)
+	authed.GET("/profiles/:id/revisions/:version", h.getRevision)
+	authed.GET("/profiles/:id/revisions/:version/diff", h.diffRevisions)
+	authed.POST("/profiles/:id/revisions/:version/restore", h.restoreRevision)
+	authed.GET("/profiles/:id/grants", h.listGrants)
+	authed.PUT("/profiles/:id/grants/:user_id", h.share)
+	authed.DELETE("/profiles/:id/grants/:user_id", h.revoke)
 }
 
 func (h *Handler) create(c *gin.Context) {
@@ -51,6 +84,7 @@ func (h *Handler) create(c *gin.Context) {
 		return
 	}
 	c.Header("Location", "/api/v1/profiles/"+profile.ID.String())
+	response.SetETag(c, profile.Version)
 	c.JSON(http.StatusCreated, profile)
 }
 
@@ -64,11 +98,167 @@ func (h *Handler) bulkCreate(c *gin.Context) {
 	if userID == uuid.Nil {
 		return
 	}
-	created, errs := h.service.BulkCreate(c.Request.Context(), userID, req)
-	c.JSON(http.StatusMultiStatus, gin.H{
-		"created": created,
-		"errors":  response.SerializeErrors(errs),
+	report, err := h.service.BulkCreate(c.Request.Context(), userID, req)
+	h.respondBulk(c, report, err, http.StatusCreated)
+}
+
+func (h *Handler) bulkPatch(c *gin.Context) {
+	var req BulkPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	report, err := h.service.BulkPatch(c.Request.Context(), userID, req)
+	h.respondBulk(c, report, err, http.StatusOK)
+}
+
+func (h *Handler) bulkRestore(c *gin.Context) {
+	var req BulkRestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	report, err := h.service.BulkRestore(c.Request.Context(), userID, req)
+	h.respondBulk(c, report, err, http.StatusOK)
+}
+
+func (h *Handler) bulkTag(c *gin.Context) {
+	var req BulkTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	report, err := h.service.BulkTag(c.Request.Context(), userID, req)
+	h.respondBulk(c, report, err, http.StatusOK)
+}
+
+// respondBulk writes a bulk report: partial requests answer 207, atomic
+// requests answer ok when every item applied and 422 when none did.
+func (h *Handler) respondBulk(c *gin.Context, report *BulkReport, err error, ok int) {
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	switch {
+	case report.Mode == BulkModePartial:
+		c.JSON(http.StatusMultiStatus, report)
+	case report.Failed > 0:
+		c.JSON(http.StatusUnprocessableEntity, report)
+	default:
+		c.JSON(ok, report)
+	}
+}
+
+// exportFlushEvery is how many exported profiles are written between
+// flushes.
+const exportFlushEvery = 100
+
+func (h *Handler) export(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	var write func(*Profile) error
+	switch c.DefaultQuery("format", FormatCSV) {
+	case FormatCSV:
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="profiles.csv"`)
+		w := csv.NewWriter(c.Writer)
+		defer w.Flush()
+		if err := w.Write(exportColumns); err != nil {
+			return
+		}
+		write = func(p *Profile) error {
+			return w.Write(exportRow(p))
+		}
+	case FormatNDJSON:
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", `attachment; filename="profiles.ndjson"`)
+		enc := json.NewEncoder(c.Writer)
+		write = func(p *Profile) error {
+			return enc.Encode(p)
+		}
+	case FormatVCard:
+		c.Header("Content-Type", vcard.MediaType+"; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="profiles.vcf"`)
+		write = func(p *Profile) error {
+			return vcard.Write(c.Writer, toCard(p))
+		}
+	default:
+		response.BadRequest(c, "invalid_format", "format must be csv, ndjson or vcf")
+		return
+	}
+	c.Status(http.StatusOK)
+	rows := 0
+	err := h.service.Export(c.Request.Context(), userID, func(p *Profile) error {
+		if err := write(p); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
 	})
+	if err != nil {
+		// Headers are already on the wire; surface the failure to the logs only.
+		_ = c.Error(err)
+	}
+}
+
+// maxImportBytes caps the request body of an import.
+const maxImportBytes = 10 << 20
+
+func (h *Handler) importProfiles(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	contentType := c.ContentType()
+	format := c.Query("format")
+	if format == "" {
+		switch contentType {
+		case vcard.MediaType, "text/x-vcard":
+			format = FormatVCard
+		case "application/x-ndjson", "application/ndjson", "application/json":
+			format = FormatNDJSON
+		default:
+			format = FormatCSV
+		}
+	}
+	var body io.Reader = c.Request.Body
+	if contentType == "multipart/form-data" {
+		file, err := media.ReadUpload(c)
+		if err != nil {
+			response.BadRequest(c, "missing_file", err.Error())
+			return
+		}
+		body = file
+	}
+	rows, err := ParseImport(body, format, c.QueryMap("map"))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = ErrImportTooLarge
+		}
+		h.handleError(c, err)
+		return
+	}
+	report, err := h.service.Import(c.Request.Context(), userID, c.Query("mode"), rows)
+	h.respondBulk(c, report, err, http.StatusCreated)
 }
 
 func (h *Handler) get(c *gin.Context) {
@@ -81,12 +271,49 @@ func (h *Handler) get(c *gin.Context) {
 		response.NotFound(c, "profile")
 		return
 	}
+	fields, expand := viewParams(c)
+	if _, err := fieldsetColumns(fields); err != nil {
+		h.handleError(c, err)
+		return
+	}
 	profile, err := h.service.Get(c.Request.Context(), id, userID)
+	if err == nil {
+		err = h.service.Expand(c.Request.Context(), expand, profile)
+	}
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, profile)
+	body, err := project(fields, profile)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	// Embedded owners change without bumping the profile's version, so
+	// expanded responses are not conditional.
+	if len(expand) > 0 {
+		c.JSON(http.StatusOK, body[0])
+		return
+	}
+	if response.NotModified(c, profile.Version) {
+		return
+	}
+	response.SetETag(c, profile.Version)
+	c.JSON(http.StatusOK, body[0])
+}
+
+// viewParams reads the ?fields= sparse fieldset and ?expand= relations.
+// Expanded relations are rendered even when the fieldset leaves them out.
+func viewParams(c *gin.Context) (fields, expand []string) {
+	fields, expand = response.GetList(c, "fields"), response.GetList(c, "expand")
+	if len(fields) > 0 {
+		for _, relation := range expand {
+			if !hasField(fields, relation) {
+				fields = append(fields, relation)
+			}
+		}
+	}
+	return fields, expand
 }
 
 func (h *Handler) list(c *gin.Context) {
@@ -94,34 +321,57 @@ func (h *Handler) list(c *gin.Context) {
 	if userID == uuid.Nil {
 		return
 	}
-	cursor := c.Query("cursor")
 	filter := Filter{
 		Search: c.Query("search"),
+		Query:  strings.TrimSpace(c.Query("q")),
 		Limit:  response.GetLimit(c, 20, 100),
 		Offset: response.GetOffset(c),
-		Cursor: cursor,
 		UserID: userID,
+		// custom[key]=value matches a custom field exactly.
+		CustomFields: c.QueryMap("custom"),
+		TagMatch:     c.Query("match"),
+		Expression:   c.Query("filter"),
+		Country:      strings.ToUpper(strings.TrimSpace(c.Query("country"))),
+		Phone:        c.Query("phone"),
+		Near:         c.Query("near"),
 	}
-	if cursor != "" {
-		if ts, err := time.Parse(time.RFC3339, cursor); err == nil {
-			filter.CursorTime = &ts
+	filter.Fields, filter.Expand = viewParams(c)
+	if raw := c.Query("tags"); raw != "" {
+		filter.Tags = strings.Split(raw, ",")
+	}
+	var err error
+	if raw := c.Query("radius_km"); raw != "" {
+		if filter.RadiusKm, err = strconv.ParseFloat(raw, 64); err != nil {
+			h.handleError(c, fmt.Errorf("%w: radius_km must be a number", ErrInvalidFilter))
+			return
 		}
 	}
-	profiles, total, err := h.service.List(c.Request.Context(), filter)
+	if filter.Cursor, err = cursor.Decode(c.Query("cursor")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	page, err := h.service.List(c.Request.Context(), filter)
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
-	nextCursor := ""
-	if len(profiles) > 0 {
-		nextCursor = profiles[len(profiles)-1].CreatedAt.Format(time.RFC3339)
+	profiles := make([]*Profile, len(page.Profiles))
+	for i := range page.Profiles {
+		profiles[i] = &page.Profiles[i]
+	}
+	data, err := project(filter.Fields, profiles...)
+	if err != nil {
+		h.handleError(c, err)
+		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"data":        profiles,
-		"total":       total,
+		"data":        data,
+		"total":       page.Total,
 		"offset":      filter.Offset,
 		"limit":       filter.Limit,
-		"next_cursor": nextCursor,
+		"next_cursor": page.NextCursor,
+		"prev_cursor": page.PrevCursor,
+		"has_more":    page.HasMore,
 	})
 }
 
@@ -140,20 +390,23 @@ func (h *Handler) update(c *gin.Context) {
 		response.NotFound(c, "profile")
 		return
 	}
+	if req.Version, err = h.expectedVersion(c, id, userID, req.Version); err != nil {
+		h.handleError(c, err)
+		return
+	}
 	profile, err := h.service.Update(c.Request.Context(), id, userID, req)
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
+	response.SetETag(c, profile.Version)
 	c.JSON(http.StatusOK, profile)
 }
 
+// maxPatchBytes bounds PATCH bodies; profiles are small documents.
+const maxPatchBytes = 1 << 20
+
 func (h *Handler) patch(c *gin.Context) {
-	var req PatchRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.ValidationError(c, err)
-		return
-	}
 	userID := response.MustUserID(c)
 	if userID == uuid.Nil {
 		return
@@ -163,11 +416,37 @@ func (h *Handler) patch(c *gin.Context) {
 		response.NotFound(c, "profile")
 		return
 	}
-	profile, err := h.service.Patch(c.Request.Context(), id, userID, req)
+	doc := PatchDocument{Type: c.ContentType()}
+	switch doc.Type {
+	case jsonpatch.MergePatchType, jsonpatch.JSONPatchType:
+		if doc.Body, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPatchBytes)); err != nil {
+			response.PayloadTooLarge(c, "patch body too large")
+			return
+		}
+		if raw := c.Query("version"); raw != "" {
+			version := parseVersion(raw)
+			doc.Version = &version
+		}
+	case binding.MIMEJSON:
+		var req PatchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.ValidationError(c, err)
+			return
+		}
+		doc.Type, doc.Body, doc.Version = jsonpatch.MergePatchType, req.Fields, &req.Version
+	default:
+		response.UnsupportedMediaType(c, ErrUnsupportedPatch.Error())
+		return
+	}
+	if version, ok := response.IfMatch(c); ok {
+		doc.Version = version
+	}
+	profile, err := h.service.Patch(c.Request.Context(), id, userID, doc)
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
+	response.SetETag(c, profile.Version)
 	c.JSON(http.StatusOK, profile)
 }
 
@@ -182,7 +461,11 @@ func (h *Handler) delete(c *gin.Context) {
 		return
 	}
 	hard := c.Query("hard") == "true"
-	version := parseVersion(c.Query("version"))
+	version, err := h.expectedVersion(c, id, userID, parseVersion(c.Query("version")))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
 	if err := h.service.Delete(c.Request.Context(), id, userID, hard, version); err != nil {
 		h.handleError(c, err)
 		return
@@ -208,6 +491,198 @@ func (h *Handler) bulkDelete(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
 }
 
+func (h *Handler) trash(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	limit, offset := response.GetLimit(c, 20, 100), response.GetOffset(c)
+	profiles, total, err := h.service.Trash(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Paginated(c, profiles, total, offset, limit)
+}
+
+func (h *Handler) restore(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "profile")
+		return
+	}
+	expected, _ := response.IfMatch(c)
+	if expected == nil && c.Query("version") != "" {
+		version := parseVersion(c.Query("version"))
+		expected = &version
+	}
+	profile, err := h.service.Restore(c.Request.Context(), id, userID, expected)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.SetETag(c, profile.Version)
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *Handler) tags(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	counts, err := h.service.Tags(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": counts})
+}
+
+func (h *Handler) addTags(c *gin.Context) {
+	var req TagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "profile")
+		return
+	}
+	profile, err := h.service.AddTags(c.Request.Context(), id, userID, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *Handler) removeTag(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "profile")
+		return
+	}
+	profile, err := h.service.RemoveTag(c.Request.Context(), id, userID, c.Param("tag"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *Handler) shared(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	limit, offset := response.GetLimit(c, 20, 100), response.GetOffset(c)
+	profiles, total, err := h.service.Shared(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Paginated(c, profiles, total, offset, limit)
+}
+
+func (h *Handler) listGrants(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "profile")
+		return
+	}
+	grants, err := h.service.Grants(c.Request.Context(), id, userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": grants})
+}
+
+func (h *Handler) share(c *gin.Context) {
+	var req GrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "profile")
+		return
+	}
+	granteeID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		response.NotFound(c, "user")
+		return
+	}
+	grant, err := h.service.Share(c.Request.Context(), id, userID, granteeID, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, grant)
+}
+
+func (h *Handler) revoke(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "profile")
+		return
+	}
+	granteeID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		response.NotFound(c, "grant")
+		return
+	}
+	if err := h.service.Revoke(c.Request.Context(), id, userID, granteeID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// expectedVersion resolves the version a write is conditioned on. If-Match
+// takes precedence over the legacy body or query value, and "*" accepts
+// whatever version is stored.
+func (h *Handler) expectedVersion(c *gin.Context, id, userID uuid.UUID, fallback int) (int, error) {
+	version, ok := response.IfMatch(c)
+	if !ok {
+		return fallback, nil
+	}
+	if version != nil {
+		return *version, nil
+	}
+	current, err := h.service.Get(c.Request.Context(), id, userID)
+	if err != nil {
+		return 0, err
+	}
+	return current.Version, nil
+}
+
 func parseVersion(val string) int {
 	if val == "" {
 		return 0
@@ -219,15 +694,224 @@ func parseVersion(val string) int {
 	return version
 }
 
+func (h *Handler) getPublic(c *gin.Context) {
+	profile, err := h.service.GetPublic(c.Request.Context(), c.Param("handle"), response.OptionalUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *Handler) searchPublic(c *gin.Context) {
+	filter := PublicFilter{
+		Search: c.Query("search"),
+		Limit:  response.GetLimit(c, 20, 50),
+		Offset: response.GetOffset(c),
+		Viewer: response.OptionalUserID(c),
+	}
+	profiles, total, err := h.service.SearchPublic(c.Request.Context(), filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Paginated(c, profiles, total, filter.Offset, filter.Limit)
+}
+
+func (h *Handler) uploadImage(kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := response.MustUserID(c)
+		if userID == uuid.Nil {
+			return
+		}
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			response.NotFound(c, "profile")
+			return
+		}
+		file, err := media.ReadUpload(c)
+		if err != nil {
+			h.handleError(c, err)
+			return
+		}
+		profile, err := h.service.SetImage(c.Request.Context(), id, userID, kind, file)
+		if err != nil {
+			h.handleError(c, err)
+			return
+		}
+		response.SetETag(c, profile.Version)
+		c.JSON(http.StatusOK, profile)
+	}
+}
+
+func (h *Handler) listRevisions(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "profile")
+		return
+	}
+	limit, offset := response.GetLimit(c, 20, 100), response.GetOffset(c)
+	revisions, total, err := h.service.Revisions(c.Request.Context(), id, userID, limit, offset)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Paginated(c, revisions, total, offset, limit)
+}
+
+func (h *Handler) getRevision(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	id, version, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+	rev, err := h.service.Revision(c.Request.Context(), id, userID, version)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rev)
+}
+
+func (h *Handler) diffRevisions(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	id, version, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+	from := 0
+	if raw := c.Query("from"); raw != "" {
+		if from, _ = strconv.Atoi(raw); from <= 0 {
+			response.BadRequest(c, "invalid_version", "from must be a positive version")
+			return
+		}
+	}
+	diff, err := h.service.DiffRevisions(c.Request.Context(), id, userID, from, version)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+func (h *Handler) restoreRevision(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	id, version, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+	expected, _ := response.IfMatch(c)
+	profile, err := h.service.RestoreRevision(c.Request.Context(), id, userID, version, expected)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.SetETag(c, profile.Version)
+	c.JSON(http.StatusOK, profile)
+}
+
+func revisionParams(c *gin.Context) (uuid.UUID, int, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "profile")
+		return uuid.Nil, 0, false
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version <= 0 {
+		response.NotFound(c, "revision")
+		return uuid.Nil, 0, false
+	}
+	return id, version, true
+}
+
 func (h *Handler) handleError(c *gin.Context, err error) {
+	if media.RespondError(c, err) {
+		return
+	}
 	switch {
 	case errors.Is(err, ErrNotFound):
 		response.NotFound(c, "profile")
+	case errors.Is(err, ErrRevisionNotFound):
+		response.NotFound(c, "revision")
+	case errors.Is(err, ErrNoEarlierRevision):
+		response.BadRequest(c, "no_earlier_revision", err.Error())
 	case errors.Is(err, ErrForbidden):
 		response.Forbidden(c, "forbidden")
+	case errors.Is(err, ErrGrantNotFound):
+		response.NotFound(c, "grant")
+	case errors.Is(err, ErrGranteeNotFound):
+		response.NotFound(c, "user")
+	case errors.Is(err, ErrInvalidGrant):
+		response.BadRequest(c, "invalid_grant", err.Error())
 	case errors.Is(err, ErrVersionConflict):
+		if _, conditional := response.IfMatch(c); conditional {
+			response.PreconditionFailed(c, "profile updated elsewhere")
+			return
+		}
 		response.Conflict(c, "version_conflict", "profile updated elsewhere")
+	case errors.Is(err, ErrHandleTaken):
+		response.Conflict(c, "handle_taken", err.Error())
+	case errors.Is(err, ErrInvalidHandle):
+		response.BadRequest(c, "invalid_handle", err.Error())
+	case errors.Is(err, ErrBulkTooLarge), errors.Is(err, ErrImportTooLarge):
+		response.PayloadTooLarge(c, err.Error())
+	case errors.Is(err, ErrInvalidImport):
+		response.BadRequest(c, "invalid_import", err.Error())
+	case errors.Is(err, cursor.ErrInvalid):
+		response.BadRequest(c, "invalid_cursor", err.Error())
+	case errors.Is(err, ErrInvalidFilter):
+		response.InvalidFilter(c, err)
+	case errors.Is(err, ErrInvalidPhone):
+		response.BadRequest(c, "invalid_phone", err.Error())
+	case errors.Is(err, ErrInvalidWebsite):
+		response.BadRequest(c, "invalid_website", err.Error())
+	case errors.Is(err, ErrInvalidCoordinates):
+		response.BadRequest(c, "invalid_coordinates", err.Error())
+	case errors.Is(err, ErrUnknownLocation):
+		response.BadRequest(c, "unknown_location", err.Error())
+	case errors.Is(err, ErrInvalidFields):
+		response.BadRequest(c, "invalid_fields", err.Error())
+	case errors.Is(err, ErrInvalidExpand):
+		response.BadRequest(c, "invalid_expand", err.Error())
+	case errors.Is(err, ErrInvalidTag):
+		response.BadRequest(c, "invalid_tag", err.Error())
+	case errors.Is(err, ErrTooManyTags):
+		response.BadRequest(c, "too_many_tags", err.Error())
+	case errors.Is(err, ErrUnsupportedPatch):
+		response.UnsupportedMediaType(c, err.Error())
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		response.Conflict(c, "patch_test_failed", err.Error())
+	case errors.Is(err, jsonpatch.ErrInvalid):
+		response.BadRequest(c, "invalid_patch", err.Error())
 	default:
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			response.ValidationError(c, err)
+			return
+		}
+		var unknown *UnknownFieldsError
+		if errors.As(err, &unknown) {
+			c.JSON(http.StatusBadRequest, response.ErrorResponse{Error: "unknown_fields", Message: err.Error(), Details: unknown.Fields})
+			return
+		}
+		var custom *CustomFieldsError
+		if errors.As(err, &custom) {
+			c.JSON(http.StatusBadRequest, response.ErrorResponse{Error: "invalid_custom_fields", Message: err.Error(), Details: custom.Fields})
+			return
+		}
 		response.InternalServerError(c, err)
 	}
 }
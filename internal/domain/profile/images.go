@@ -0,0 +1,134 @@
+package profile
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/internal/domain/media"
+)
+
+// ImageStore persists uploaded images and signs links to them.
+type ImageStore interface {
+	Save(ctx context.Context, kind, owner string, r io.Reader) (*media.Upload, error)
+	Delete(ctx context.Context, key string) error
+	URLs(key string) (string, string)
+	KeyFromURL(raw string) (string, bool)
+}
+
+// WithImages enables avatar and cover uploads.
+func WithImages(images ImageStore) Option {
+	return func(s *Service) {
+		s.images = images
+	}
+}
+
+// imageSlot points at the URL, key and thumbnail fields of one image kind.
+type imageSlot struct {
+	column string
+	url    **string
+	key    **string
+	thumb  **string
+}
+
+func imageSlots(p *Profile) []imageSlot {
+	return []imageSlot{
+		{column: "profile_image", url: &p.ProfileImage, key: &p.ProfileKey, thumb: &p.ProfileThumb},
+		{column: "cover_image", url: &p.CoverImage, key: &p.CoverKey, thumb: &p.CoverThumb},
+	}
+}
+
+func imageSlotFor(p *Profile, kind string) imageSlot {
+	if kind == media.KindCover {
+		return imageSlots(p)[1]
+	}
+	return imageSlots(p)[0]
+}
+
+// SetImage stores an uploaded avatar or cover and replaces the previous one.
+func (s *Service) SetImage(ctx context.Context, id, userID uuid.UUID, kind string, r io.Reader) (*Profile, error) {
+	if s.images == nil {
+		return nil, media.ErrDisabled
+	}
+	profile, err := s.Get(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !allows(profile.Access, AccessWrite) {
+		return nil, ErrForbidden
+	}
+	upload, err := s.images.Save(ctx, kind, profile.ID.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	slot := imageSlotFor(profile, kind)
+	previous := *slot.key
+	*slot.key, *slot.url = &upload.Key, nil
+	profile.Version++
+	profile.UpdatedAt = time.Now().UTC()
+	err = s.transact(ctx, func(tx *Service) error {
+		if err := tx.repo.Update(ctx, profile); err != nil {
+			return err
+		}
+		tx.discardImages(ctx, previous)
+		return tx.recordRevision(ctx, profile, userID)
+	})
+	if err != nil {
+		s.discardImages(ctx, &upload.Key)
+		return nil, err
+	}
+	return s.present(profile), nil
+}
+
+// isCurrentUpload reports whether raw is one of our signed links to key, as
+// happens when clients echo a fetched profile back.
+func (s *Service) isCurrentUpload(raw string, key *string) bool {
+	if s.images == nil || key == nil {
+		return false
+	}
+	got, ok := s.images.KeyFromURL(raw)
+	return ok && got == *key
+}
+
+// present swaps stored keys for signed URLs before a profile leaves the
+// service.
+func (s *Service) present(p *Profile) *Profile {
+	if p == nil || s.images == nil {
+		return p
+	}
+	for _, slot := range imageSlots(p) {
+		if *slot.key == nil {
+			continue
+		}
+		url, thumb := s.images.URLs(**slot.key)
+		*slot.url, *slot.thumb = &url, &thumb
+	}
+	return p
+}
+
+// discardImages deletes replaced uploads. Failures only leave orphaned
+// blobs behind, so they are not surfaced.
+func (s *Service) discardImages(ctx context.Context, keys ...*string) {
+	if s.images == nil {
+		return
+	}
+	if s.deferred != nil {
+		*s.deferred = append(*s.deferred, keys...)
+		return
+	}
+	for _, key := range keys {
+		if key != nil {
+			_ = s.images.Delete(ctx, *key)
+		}
+	}
+}
+
+func stringPointers(values []string) []*string {
+	out := make([]*string, len(values))
+	for i := range values {
+		out[i] = &values[i]
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+	"github.com/kidpech/api_free_demo/pkg/geo"
+)
+
+// Location errors.
+var (
+	ErrInvalidCoordinates = errors.New("latitude and longitude must be set together")
+	ErrUnknownLocation    = errors.New("location could not be geocoded")
+)
+
+// Radius search bounds for Filter.RadiusKm.
+const (
+	DefaultRadiusKm = 25
+	MaxRadiusKm     = 1000
+)
+
+// Geocoder resolves a free-text location, optionally within an ISO 3166-1
+// alpha-2 country, to coordinates. It returns geo.ErrNotFound for places it
+// does not know.
+type Geocoder interface {
+	Geocode(ctx context.Context, query, country string) (geo.Point, error)
+}
+
+// WithGeocoder enables geocode requests.
+func WithGeocoder(g Geocoder) Option {
+	return func(s *Service) {
+		s.geocoder = g
+	}
+}
+
+// locate geocodes p when req asks for it without giving coordinates, then
+// checks that p ends up with both coordinates or neither.
+func (s *Service) locate(ctx context.Context, p *Profile, req CreateRequest) error {
+	if req.Geocode && req.Latitude == nil && req.Longitude == nil {
+		if p.Location == nil || *p.Location == "" {
+			return fmt.Errorf("%w: geocode needs a location", ErrUnknownLocation)
+		}
+		if s.geocoder == nil {
+			return fmt.Errorf("%w: no geocoder is configured", ErrUnknownLocation)
+		}
+		var country string
+		if p.Country != nil {
+			country = *p.Country
+		}
+		point, err := s.geocoder.Geocode(ctx, *p.Location, country)
+		if errors.Is(err, geo.ErrNotFound) {
+			return fmt.Errorf("%w: %q", ErrUnknownLocation, *p.Location)
+		}
+		if err != nil {
+			return err
+		}
+		p.Latitude, p.Longitude = &point.Lat, &point.Lng
+	}
+	if (p.Latitude == nil) != (p.Longitude == nil) {
+		return ErrInvalidCoordinates
+	}
+	return nil
+}
+
+// nearFilter parses filter.Near into filter.Origin and settles the radius.
+// Distance results are ranked, so like full-text results they page by
+// offset.
+func nearFilter(filter *Filter) error {
+	if filter.Near == "" {
+		if filter.RadiusKm != 0 {
+			return fmt.Errorf("%w: radius_km needs near", ErrInvalidFilter)
+		}
+		return nil
+	}
+	origin, err := geo.ParsePoint(filter.Near)
+	if err != nil {
+		return fmt.Errorf("%w: near: %w", ErrInvalidFilter, err)
+	}
+	switch {
+	case filter.RadiusKm == 0:
+		filter.RadiusKm = DefaultRadiusKm
+	case filter.RadiusKm < 0 || filter.RadiusKm > MaxRadiusKm:
+		return fmt.Errorf("%w: radius_km must be between 0 and %d", ErrInvalidFilter, MaxRadiusKm)
+	}
+	if filter.Query != "" {
+		return fmt.Errorf("%w: near cannot be combined with q", ErrInvalidFilter)
+	}
+	if filter.Cursor != nil {
+		return fmt.Errorf("%w: distance results page by offset", cursor.ErrInvalid)
+	}
+	filter.Origin = &origin
+	return nil
+}
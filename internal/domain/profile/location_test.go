@@ -0,0 +1,58 @@
+package profile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+	"github.com/kidpech/api_free_demo/pkg/geo"
+)
+
+func TestCreateGeocodesLocation(t *testing.T) {
+	repo := &bulkRepo{profiles: map[uuid.UUID]Profile{}}
+	svc := NewService(repo, WithGeocoder(geo.DefaultGazetteer()))
+	ctx := context.Background()
+	str := func(v string) *string { return &v }
+	num := func(v float64) *float64 { return &v }
+
+	p, err := svc.Create(ctx, uuid.New(), CreateRequest{FirstName: "Ada", LastName: "Lovelace", Location: str("Paris"), Country: str("US"), Geocode: true})
+	require.NoError(t, err)
+	require.InDelta(t, 33.66, *p.Latitude, 0.01)
+	require.InDelta(t, -95.56, *p.Longitude, 0.01)
+
+	// Coordinates in the request win over geocoding.
+	p, err = svc.Create(ctx, uuid.New(), CreateRequest{FirstName: "Ada", LastName: "Lovelace", Location: str("Paris"),
+		Latitude: num(0), Longitude: num(0), Geocode: true})
+	require.NoError(t, err)
+	require.Zero(t, *p.Latitude)
+
+	_, err = svc.Create(ctx, uuid.New(), CreateRequest{FirstName: "Ada", LastName: "Lovelace", Location: str("Atlantis"), Geocode: true})
+	require.ErrorIs(t, err, ErrUnknownLocation)
+	_, err = svc.Create(ctx, uuid.New(), CreateRequest{FirstName: "Ada", LastName: "Lovelace", Latitude: num(51.5)})
+	require.ErrorIs(t, err, ErrInvalidCoordinates)
+	_, err = svc.Create(ctx, uuid.New(), CreateRequest{FirstName: "Ada", LastName: "Lovelace", Latitude: num(91), Longitude: num(0)})
+	require.Error(t, err)
+	_, err = NewService(repo).Create(ctx, uuid.New(), CreateRequest{FirstName: "Ada", LastName: "Lovelace", Location: str("Paris"), Geocode: true})
+	require.ErrorIs(t, err, ErrUnknownLocation)
+}
+
+func TestNearFilter(t *testing.T) {
+	filter := Filter{Near: "48.85,2.35"}
+	require.NoError(t, nearFilter(&filter))
+	require.Equal(t, &geo.Point{Lat: 48.85, Lng: 2.35}, filter.Origin)
+	require.Equal(t, float64(DefaultRadiusKm), filter.RadiusKm)
+
+	for _, bad := range []Filter{
+		{RadiusKm: 10},
+		{Near: "paris"},
+		{Near: "48.85,2.35", RadiusKm: MaxRadiusKm + 1},
+		{Near: "48.85,2.35", Query: "ada"},
+	} {
+		require.ErrorIs(t, nearFilter(&bad), ErrInvalidFilter)
+	}
+	paged := Filter{Near: "48.85,2.35", Cursor: &cursor.Cursor{}}
+	require.ErrorIs(t, nearFilter(&paged), cursor.ErrInvalid)
+}
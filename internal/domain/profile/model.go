@@ -1,28 +1,66 @@
 package profile
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/internal/domain/user"
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+	"github.com/kidpech/api_free_demo/pkg/filterexpr"
+	"github.com/kidpech/api_free_demo/pkg/geo"
 )
 
 // Profile models the user profile entity.
 type Profile struct {
-	ID           uuid.UUID  `json:"id" db:"id"`
-	UserID       uuid.UUID  `json:"user_id" db:"user_id"`
-	FirstName    string     `json:"first_name" db:"first_name"`
-	LastName     string     `json:"last_name" db:"last_name"`
-	Bio          *string    `json:"bio,omitempty" db:"bio"`
-	ProfileImage *string    `json:"profile_image,omitempty" db:"profile_image"`
-	CoverImage   *string    `json:"cover_image,omitempty" db:"cover_image"`
-	DateOfBirth  *time.Time `json:"date_of_birth,omitempty" db:"date_of_birth"`
-	Phone        *string    `json:"phone,omitempty" db:"phone"`
-	Website      *string    `json:"website,omitempty" db:"website"`
-	Location     *string    `json:"location,omitempty" db:"location"`
-	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
-	DeletedAt    *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
-	Version      int        `json:"version" db:"version"`
+	ID           uuid.UUID       `json:"id" db:"id"`
+	UserID       uuid.UUID       `json:"user_id" db:"user_id"`
+	TenantID     *uuid.UUID      `json:"tenant_id,omitempty" db:"tenant_id"`
+	Handle       *string         `json:"handle,omitempty" db:"handle"`
+	Visibility   string          `json:"visibility" db:"visibility"`
+	FieldVis     FieldVisibility `json:"field_visibility" db:"field_visibility"`
+	FirstName    string          `json:"first_name" db:"first_name"`
+	LastName     string          `json:"last_name" db:"last_name"`
+	Bio          *string         `json:"bio,omitempty" db:"bio"`
+	ProfileImage *string         `json:"profile_image,omitempty" db:"profile_image"`
+	CoverImage   *string         `json:"cover_image,omitempty" db:"cover_image"`
+	ProfileKey   *string         `json:"-" db:"profile_image_key"`
+	CoverKey     *string         `json:"-" db:"cover_image_key"`
+	ProfileThumb *string         `json:"profile_image_thumb,omitempty" db:"-"`
+	CoverThumb   *string         `json:"cover_image_thumb,omitempty" db:"-"`
+	DateOfBirth  *time.Time      `json:"date_of_birth,omitempty" db:"date_of_birth"`
+	Phone        *string         `json:"phone,omitempty" db:"phone"`
+	// PhoneE164 is Phone normalized against Country; Phone keeps what the
+	// owner typed.
+	PhoneE164    *string      `json:"phone_e164,omitempty" db:"phone_e164"`
+	Website      *string      `json:"website,omitempty" db:"website"`
+	Location     *string      `json:"location,omitempty" db:"location"`
+	Country      *string      `json:"country,omitempty" db:"country"`
+	Latitude     *float64     `json:"latitude,omitempty" db:"latitude"`
+	Longitude    *float64     `json:"longitude,omitempty" db:"longitude"`
+	CustomFields CustomFields `json:"custom_fields,omitempty" db:"custom_fields"`
+	CreatedAt    time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at" db:"updated_at"`
+	DeletedAt    *time.Time   `json:"deleted_at,omitempty" db:"deleted_at"`
+	Version      int          `json:"version" db:"version"`
+	// Tags live in their own table and are only filled on reads and tag
+	// writes; changing them bumps Version like any other edit.
+	Tags []string `json:"tags,omitempty" db:"-"`
+	// Score and Snippet are only populated by full-text searches.
+	Score   *float64 `json:"score,omitempty" db:"score"`
+	Snippet *string  `json:"snippet,omitempty" db:"snippet"`
+	// DistanceKm is only populated by ?near= searches.
+	DistanceKm *float64 `json:"distance_km,omitempty" db:"distance_km"`
+	// Access is the caller's right on the profile and AccessExpiresAt when
+	// a grant behind it lapses; both are only filled on single reads and
+	// shared listings.
+	Access          string     `json:"access,omitempty" db:"access"`
+	AccessExpiresAt *time.Time `json:"access_expires_at,omitempty" db:"access_expires_at"`
+	// User is only filled by ?expand=user.
+	User *user.Summary `json:"user,omitempty" db:"-"`
 }
 
 // CreateRequest captures POST payloads.
@@ -36,6 +74,21 @@ type CreateRequest struct {
 	Phone        *string `json:"phone"`
 	Website      *string `json:"website" validate:"omitempty,url"`
 	Location     *string `json:"location"`
+	// Country is an ISO 3166-1 alpha-2 code; national phone numbers are
+	// read against it.
+	Country *string `json:"country" validate:"omitempty,iso3166_1_alpha2"`
+	// Latitude and Longitude are set together. Geocode fills both from
+	// Location and Country instead when the request carries neither.
+	Latitude   *float64 `json:"latitude" validate:"omitempty,latitude"`
+	Longitude  *float64 `json:"longitude" validate:"omitempty,longitude"`
+	Geocode    bool     `json:"geocode,omitempty"`
+	Handle     *string  `json:"handle"`
+	Visibility *string  `json:"visibility" validate:"omitempty,oneof=public unlisted private"`
+	// FieldVisibility overrides the exposure of individual fields.
+	FieldVisibility FieldVisibility `json:"field_visibility" validate:"omitempty,dive,keys,oneof=bio phone date_of_birth website location,endkeys,oneof=public private connections"`
+	// CustomFields holds values for admin-defined fields, checked against
+	// their definitions rather than struct tags.
+	CustomFields CustomFields `json:"custom_fields"`
 }
 
 // UpdateRequest handles PUT semantics.
@@ -44,25 +97,163 @@ type UpdateRequest struct {
 	Version int `json:"version" validate:"gte=0"`
 }
 
-// PatchRequest handles PATCH semantics.
+// PatchRequest is the legacy application/json PATCH envelope. Fields is
+// applied as a JSON merge patch.
 type PatchRequest struct {
-	Fields  map[string]interface{} `json:"fields" validate:"required"`
-	Version int                    `json:"version" validate:"gte=0"`
+	Fields  json.RawMessage `json:"fields" validate:"required"`
+	Version int             `json:"version" validate:"gte=0"`
+}
+
+// PatchDocument is a PATCH body in one of the supported patch formats.
+type PatchDocument struct {
+	Type string // jsonpatch.MergePatchType or jsonpatch.JSONPatchType
+	Body []byte
+	// Version, when set, must match the stored profile.
+	Version *int
 }
 
-// Filter for list endpoints.
+// SortCreatedAt is the only keyset order profile listings support.
+const SortCreatedAt = "created_at"
+
+// Filter for list endpoints. Offset only applies to the first keyset page
+// and to full-text results, which are ranked rather than keyset ordered.
 type Filter struct {
-	Search     string
-	Limit      int
-	Offset     int
-	Cursor     string
-	CursorTime *time.Time
-	UserID     uuid.UUID
+	Search string
+	Query  string
+	Limit  int
+	Offset int
+	Cursor *cursor.Cursor
+	UserID uuid.UUID
+	// Country keeps profiles in one country and is the region a national
+	// Phone is read against.
+	Country string
+	// Phone matches profiles by number whatever its formatting; the service
+	// normalizes it into PhoneE164.
+	Phone     string
+	PhoneE164 string
+	// Near is a raw "lat,lng" the service parses into Origin; it keeps
+	// profiles within RadiusKm of it, nearest first, paged by offset.
+	Near     string
+	RadiusKm float64
+	Origin   *geo.Point
+	// CustomFields matches custom field values exactly, keyed by field.
+	CustomFields map[string]string
+	// Tags keeps profiles carrying any of the tags, or all of them when
+	// TagMatch is TagMatchAll.
+	Tags     []string
+	TagMatch string
+	// Fields is a sparse fieldset of response members; the repository is
+	// handed the columns behind it in Columns.
+	Fields  []string
+	Columns []string
+	// Expand names relations to embed.
+	Expand []string
+	// Expression is the raw ?filter= expression; the service parses it
+	// against FilterFields into Where.
+	Expression string
+	Where      filterexpr.Expr
+}
+
+// FilterFields whitelists the profile fields ?filter= expressions may test.
+var FilterFields = filterexpr.Schema{
+	"id":            {Column: "id", Kind: filterexpr.UUID},
+	"handle":        {Column: "handle", Kind: filterexpr.String, Nullable: true},
+	"visibility":    {Column: "visibility", Kind: filterexpr.String},
+	"first_name":    {Column: "first_name", Kind: filterexpr.String},
+	"last_name":     {Column: "last_name", Kind: filterexpr.String},
+	"bio":           {Column: "bio", Kind: filterexpr.String, Nullable: true},
+	"date_of_birth": {Column: "date_of_birth", Kind: filterexpr.Time, Nullable: true},
+	"phone":         {Column: "phone", Kind: filterexpr.String, Nullable: true},
+	"website":       {Column: "website", Kind: filterexpr.String, Nullable: true},
+	"location":      {Column: "location", Kind: filterexpr.String, Nullable: true},
+	"country":       {Column: "country", Kind: filterexpr.String, Nullable: true},
+	"phone_e164":    {Column: "phone_e164", Kind: filterexpr.String, Nullable: true},
+	"latitude":      {Column: "latitude", Kind: filterexpr.Number, Nullable: true},
+	"longitude":     {Column: "longitude", Kind: filterexpr.Number, Nullable: true},
+	"created_at":    {Column: "created_at", Kind: filterexpr.Time},
+	"updated_at":    {Column: "updated_at", Kind: filterexpr.Time},
+	"version":       {Column: "version", Kind: filterexpr.Number},
+}
+
+// Tag match modes for Filter.TagMatch.
+const (
+	TagMatchAny = "any"
+	TagMatchAll = "all"
+)
+
+// TagsRequest adds tags to one profile.
+type TagsRequest struct {
+	Tags []string `json:"tags" validate:"required,min=1,max=50"`
+}
+
+// BulkTagRequest adds and removes the same tags on several profiles.
+type BulkTagRequest struct {
+	Mode   string      `json:"mode" validate:"omitempty,oneof=atomic partial"`
+	IDs    []uuid.UUID `json:"ids" validate:"required,min=1,unique"`
+	Add    []string    `json:"add" validate:"required_without=Remove,max=50"`
+	Remove []string    `json:"remove" validate:"required_without=Add,max=50"`
 }
 
-// BulkCreateRequest handles profile batch creation.
+// TagCount is a tag and the number of profiles carrying it.
+type TagCount struct {
+	Tag   string `json:"tag" db:"tag"`
+	Count int    `json:"count" db:"count"`
+}
+
+// ProfilePage is a keyset-paginated slice of profiles.
+type ProfilePage struct {
+	Profiles []Profile
+	Total    int
+	cursor.Page
+}
+
+// BulkCreateRequest handles profile batch creation. Items are validated one
+// by one so partial mode can report each failure.
 type BulkCreateRequest struct {
-	Profiles []CreateRequest `json:"profiles" validate:"required,dive"`
+	Mode     string          `json:"mode" validate:"omitempty,oneof=atomic partial"`
+	Profiles []CreateRequest `json:"profiles" validate:"required,min=1"`
+}
+
+// BulkPatchItem patches one profile. An object patch is a merge patch and
+// an array is a JSON Patch.
+type BulkPatchItem struct {
+	ID      uuid.UUID       `json:"id" validate:"required"`
+	Patch   json.RawMessage `json:"patch" validate:"required"`
+	Version *int            `json:"version,omitempty"`
+}
+
+// BulkPatchRequest handles batch patches.
+type BulkPatchRequest struct {
+	Mode  string          `json:"mode" validate:"omitempty,oneof=atomic partial"`
+	Items []BulkPatchItem `json:"items" validate:"required,min=1,dive"`
+}
+
+// BulkRestoreRequest takes several profiles out of the trash.
+type BulkRestoreRequest struct {
+	Mode string      `json:"mode" validate:"omitempty,oneof=atomic partial"`
+	IDs  []uuid.UUID `json:"ids" validate:"required,min=1,unique"`
+}
+
+// BulkResult is the outcome of one item of a bulk request. Imports also
+// report the source line of the item.
+type BulkResult struct {
+	Index   int         `json:"index"`
+	Line    int         `json:"line,omitempty"`
+	ID      *uuid.UUID  `json:"id,omitempty"`
+	Status  string      `json:"status"`
+	Code    string      `json:"code,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+	Profile *Profile    `json:"profile,omitempty"`
+}
+
+// BulkReport summarizes a bulk request, one result per item in request
+// order.
+type BulkReport struct {
+	Mode      string       `json:"mode"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []BulkResult `json:"results"`
 }
 
 // BulkDeleteRequest handles batch deletions.
@@ -71,3 +262,140 @@ type BulkDeleteRequest struct {
 	Hard   bool        `json:"hard_delete"`
 	Reason string      `json:"reason"`
 }
+
+// Profile visibility levels. Unlisted profiles resolve by handle but never
+// show up in public search.
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+)
+
+// Field exposure levels for FieldVisibility. Connections-only fields are
+// shown to signed-in readers the owner has an accepted connection with.
+const (
+	FieldPublic      = "public"
+	FieldPrivate     = "private"
+	FieldConnections = "connections"
+)
+
+// defaultFieldVisibility applies to fields the owner has not configured.
+// Contact and identity details stay hidden unless explicitly exposed.
+var defaultFieldVisibility = map[string]string{
+	"bio":           FieldPublic,
+	"website":       FieldPublic,
+	"location":      FieldPublic,
+	"phone":         FieldPrivate,
+	"date_of_birth": FieldPrivate,
+}
+
+// FieldVisibility maps optional profile fields to an exposure level. It is
+// stored as a JSON object.
+type FieldVisibility map[string]string
+
+// Exposed reports whether field is visible to a public reader, who may be
+// one of the owner's connections.
+func (v FieldVisibility) Exposed(field string, connected bool) bool {
+	level, ok := v[field]
+	if !ok {
+		level = defaultFieldVisibility[field]
+	}
+	return level == FieldPublic || (connected && level == FieldConnections)
+}
+
+// Value implements driver.Valuer.
+func (v FieldVisibility) Value() (driver.Value, error) {
+	if v == nil {
+		return "{}", nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan implements sql.Scanner.
+func (v *FieldVisibility) Scan(src interface{}) error {
+	var raw []byte
+	switch data := src.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case []byte:
+		raw = data
+	case string:
+		raw = []byte(data)
+	default:
+		return fmt.Errorf("field_visibility: unsupported type %T", src)
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// CustomFields maps custom field keys to JSON values.
+type CustomFields map[string]interface{}
+
+// Value implements driver.Valuer.
+func (f CustomFields) Value() (driver.Value, error) {
+	if f == nil {
+		return "{}", nil
+	}
+	raw, err := json.Marshal(map[string]interface{}(f))
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan implements sql.Scanner.
+func (f *CustomFields) Scan(src interface{}) error {
+	var raw []byte
+	switch data := src.(type) {
+	case nil:
+		*f = nil
+		return nil
+	case []byte:
+		raw = data
+	case string:
+		raw = []byte(data)
+	default:
+		return fmt.Errorf("custom_fields: unsupported type %T", src)
+	}
+	if err := json.Unmarshal(raw, (*map[string]interface{})(f)); err != nil {
+		return err
+	}
+	if len(*f) == 0 {
+		*f = nil
+	}
+	return nil
+}
+
+// PublicProfile is the anonymous view of a profile: identity fields plus
+// whatever optional fields the owner exposed.
+type PublicProfile struct {
+	ID           uuid.UUID  `json:"id"`
+	Handle       string     `json:"handle"`
+	FirstName    string     `json:"first_name"`
+	LastName     string     `json:"last_name"`
+	ProfileImage *string    `json:"profile_image,omitempty"`
+	ProfileThumb *string    `json:"profile_image_thumb,omitempty"`
+	CoverImage   *string    `json:"cover_image,omitempty"`
+	CoverThumb   *string    `json:"cover_image_thumb,omitempty"`
+	Bio          *string    `json:"bio,omitempty"`
+	Website      *string    `json:"website,omitempty"`
+	Location     *string    `json:"location,omitempty"`
+	Country      *string    `json:"country,omitempty"`
+	Phone        *string    `json:"phone,omitempty"`
+	DateOfBirth  *time.Time `json:"date_of_birth,omitempty"`
+}
+
+// PublicFilter drives public profile search.
+type PublicFilter struct {
+	Search string
+	Limit  int
+	Offset int
+	// Viewer is the signed-in reader, if any; the service fills Exclude
+	// with the owners on the other side of a block from them.
+	Viewer  uuid.UUID
+	Exclude []uuid.UUID
+}
@@ -0,0 +1,255 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/pkg/jsonpatch"
+)
+
+// ErrUnsupportedPatch is returned for PATCH bodies in an unknown format.
+var ErrUnsupportedPatch = errors.New("patch must be application/merge-patch+json or application/json-patch+json")
+
+// UnknownFieldsError lists patched members that are not profile fields.
+type UnknownFieldsError struct {
+	Fields []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return "unknown fields: " + strings.Join(e.Fields, ", ")
+}
+
+// patchableFields are the members of the document patches apply to.
+var patchableFields = func() map[string]bool {
+	fields := map[string]bool{}
+	t := reflect.TypeOf(CreateRequest{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields[name] = true
+	}
+	return fields
+}()
+
+// patchColumns lists the columns a patch may change, in write order.
+var patchColumns = []struct {
+	name  string
+	value func(*Profile) interface{}
+}{
+	{"first_name", func(p *Profile) interface{} { return p.FirstName }},
+	{"last_name", func(p *Profile) interface{} { return p.LastName }},
+	{"bio", func(p *Profile) interface{} { return p.Bio }},
+	{"profile_image", func(p *Profile) interface{} { return p.ProfileImage }},
+	{"profile_image_key", func(p *Profile) interface{} { return p.ProfileKey }},
+	{"cover_image", func(p *Profile) interface{} { return p.CoverImage }},
+	{"cover_image_key", func(p *Profile) interface{} { return p.CoverKey }},
+	{"date_of_birth", func(p *Profile) interface{} { return p.DateOfBirth }},
+	{"phone", func(p *Profile) interface{} { return p.Phone }},
+	{"phone_e164", func(p *Profile) interface{} { return p.PhoneE164 }},
+	{"website", func(p *Profile) interface{} { return p.Website }},
+	{"location", func(p *Profile) interface{} { return p.Location }},
+	{"country", func(p *Profile) interface{} { return p.Country }},
+	{"latitude", func(p *Profile) interface{} { return p.Latitude }},
+	{"longitude", func(p *Profile) interface{} { return p.Longitude }},
+	{"handle", func(p *Profile) interface{} { return p.Handle }},
+	{"visibility", func(p *Profile) interface{} { return p.Visibility }},
+	{"field_visibility", func(p *Profile) interface{} {
+		if len(p.FieldVis) == 0 {
+			return FieldVisibility(nil)
+		}
+		return p.FieldVis
+	}},
+	{"custom_fields", func(p *Profile) interface{} { return p.CustomFields }},
+}
+
+// Patch applies a merge patch or JSON patch to the editable view of a
+// profile, validates the result like a create request and persists the
+// columns that changed. Grantees need write access.
+func (s *Service) Patch(ctx context.Context, id, userID uuid.UUID, patch PatchDocument) (*Profile, error) {
+	stored, err := s.authorize(ctx, id, userID, AccessWrite)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Version != nil && *patch.Version != stored.Version {
+		return nil, ErrVersionConflict
+	}
+	shown := *stored
+	s.present(&shown)
+	doc, err := json.Marshal(editable(&shown))
+	if err != nil {
+		return nil, err
+	}
+	var patched []byte
+	switch patch.Type {
+	case jsonpatch.MergePatchType:
+		patched, err = jsonpatch.MergePatch(doc, patch.Body)
+	case jsonpatch.JSONPatchType:
+		patched, err = jsonpatch.Apply(doc, patch.Body)
+	default:
+		return nil, ErrUnsupportedPatch
+	}
+	if err != nil {
+		return nil, err
+	}
+	req, err := decodePatched(patched)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	next, err := s.applyEditable(stored, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.locate(ctx, next, req); err != nil {
+		return nil, err
+	}
+	if !reflect.DeepEqual(next.CustomFields, stored.CustomFields) {
+		if next.CustomFields, err = s.checkCustomFields(ctx, next.CustomFields); err != nil {
+			return nil, err
+		}
+	}
+	var stale []*string
+	for i, slot := range imageSlots(next) {
+		prev, echoed := imageSlots(stored)[i], imageSlots(&shown)[i]
+		if *prev.key == nil {
+			continue
+		}
+		// Leaving the signed link alone keeps the upload.
+		if reflect.DeepEqual(*slot.url, *echoed.url) || (*slot.url != nil && s.isCurrentUpload(**slot.url, *prev.key)) {
+			*slot.url = *prev.url
+			continue
+		}
+		*slot.key = nil
+		stale = append(stale, *prev.key)
+	}
+
+	update := changedColumns(stored, next)
+	if err := checkOwnerColumns(stored, update); err != nil {
+		return nil, err
+	}
+	if len(update) == 0 {
+		return s.present(stored), nil
+	}
+	var updated *Profile
+	err = s.transact(ctx, func(tx *Service) error {
+		var err error
+		if updated, err = tx.repo.Patch(ctx, id, stored.UserID, update, stored.Version); err != nil {
+			return err
+		}
+		tx.discardImages(ctx, stale...)
+		return tx.recordRevision(ctx, updated, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	updated.Access, updated.AccessExpiresAt = stored.Access, stored.AccessExpiresAt
+	return s.present(updated), nil
+}
+
+// applyEditable returns a copy of stored with every editable field replaced
+// by req; fields req leaves unset are cleared. Image keys are left for the
+// caller to reconcile.
+func (s *Service) applyEditable(stored *Profile, req CreateRequest) (*Profile, error) {
+	next := *stored
+	next.FirstName = sanitizeField(s.sanitizer, req.FirstName)
+	next.LastName = sanitizeField(s.sanitizer, req.LastName)
+	next.Bio, next.ProfileImage, next.CoverImage, next.DateOfBirth = nil, nil, nil, nil
+	next.Phone, next.Website, next.Location, next.Country, next.Handle = nil, nil, nil, nil, nil
+	next.Latitude, next.Longitude = nil, nil
+	next.Visibility, next.FieldVis, next.CustomFields = VisibilityPrivate, nil, nil
+	if err := assignOptionalFields(s.sanitizer, &next, req); err != nil {
+		return nil, err
+	}
+	if err := normalizePhone(&next, stored); err != nil {
+		return nil, err
+	}
+	if len(next.CustomFields) == 0 {
+		next.CustomFields = nil
+	}
+	if next.DateOfBirth != nil && stored.DateOfBirth != nil && next.DateOfBirth.Equal(*stored.DateOfBirth) {
+		next.DateOfBirth = stored.DateOfBirth
+	}
+	return &next, nil
+}
+
+// changedColumns maps the columns that differ between before and after to
+// their new values.
+func changedColumns(before, after *Profile) map[string]interface{} {
+	update := make(map[string]interface{})
+	for _, col := range patchColumns {
+		if val := col.value(after); !reflect.DeepEqual(val, col.value(before)) {
+			update[col.name] = val
+		}
+	}
+	return update
+}
+
+// editable renders the client-writable view of a profile that patches are
+// applied to. Every member is present so JSON Patch paths always resolve.
+func editable(p *Profile) CreateRequest {
+	req := CreateRequest{
+		FirstName:       p.FirstName,
+		LastName:        p.LastName,
+		Bio:             p.Bio,
+		ProfileImage:    p.ProfileImage,
+		CoverImage:      p.CoverImage,
+		Phone:           p.Phone,
+		Website:         p.Website,
+		Location:        p.Location,
+		Country:         p.Country,
+		Latitude:        p.Latitude,
+		Longitude:       p.Longitude,
+		Handle:          p.Handle,
+		Visibility:      &p.Visibility,
+		FieldVisibility: p.FieldVis,
+		CustomFields:    p.CustomFields,
+	}
+	if req.FieldVisibility == nil {
+		req.FieldVisibility = FieldVisibility{}
+	}
+	if req.CustomFields == nil {
+		req.CustomFields = CustomFields{}
+	}
+	if p.DateOfBirth != nil {
+		dob := p.DateOfBirth.Format("2006-01-02")
+		req.DateOfBirth = &dob
+	}
+	return req
+}
+
+// decodePatched turns a patched document back into a create request,
+// rejecting members the profile does not have.
+func decodePatched(raw []byte) (CreateRequest, error) {
+	var req CreateRequest
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil || members == nil {
+		return req, fmt.Errorf("%w: patched document must be an object", jsonpatch.ErrInvalid)
+	}
+	var unknown []string
+	for name := range members {
+		if !patchableFields[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return req, &UnknownFieldsError{Fields: unknown}
+	}
+	if err := json.Unmarshal(raw, &req); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return req, fmt.Errorf("%w: %s must be a %s", jsonpatch.ErrInvalid, typeErr.Field, typeErr.Type)
+		}
+		return req, fmt.Errorf("%w: %v", jsonpatch.ErrInvalid, err)
+	}
+	return req, nil
+}
@@ -0,0 +1,196 @@
+package profile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kidpech/api_free_demo/pkg/jsonpatch"
+)
+
+func TestMergePatchClearsAndRejectsUnknownFields(t *testing.T) {
+	repo, p := newPatchRepo()
+	svc := NewService(repo)
+
+	updated, err := svc.Patch(context.Background(), p.ID, p.UserID, PatchDocument{
+		Type: jsonpatch.MergePatchType,
+		Body: []byte(`{"bio":null,"date_of_birth":"1991-03-04","field_visibility":{"phone":"public"}}`),
+	})
+	require.NoError(t, err)
+	require.Nil(t, updated.Bio)
+	require.Equal(t, "1991-03-04", updated.DateOfBirth.Format("2006-01-02"))
+	require.Equal(t, 2, updated.Version)
+	require.ElementsMatch(t, []string{"bio", "date_of_birth", "field_visibility"}, repo.patched)
+
+	_, err = svc.Patch(context.Background(), p.ID, p.UserID, PatchDocument{
+		Type: jsonpatch.MergePatchType,
+		Body: []byte(`{"nickname":"x","email":"y"}`),
+	})
+	var unknown *UnknownFieldsError
+	require.ErrorAs(t, err, &unknown)
+	require.Equal(t, []string{"email", "nickname"}, unknown.Fields)
+}
+
+func TestJSONPatchRevalidatesResult(t *testing.T) {
+	repo, p := newPatchRepo()
+	svc := NewService(repo)
+	version := 1
+
+	_, err := svc.Patch(context.Background(), p.ID, p.UserID, PatchDocument{
+		Type:    jsonpatch.JSONPatchType,
+		Body:    []byte(`[{"op":"replace","path":"/website","value":"not a url"}]`),
+		Version: &version,
+	})
+	var verr validator.ValidationErrors
+	require.ErrorAs(t, err, &verr)
+
+	_, err = svc.Patch(context.Background(), p.ID, p.UserID, PatchDocument{
+		Type: jsonpatch.JSONPatchType,
+		Body: []byte(`[{"op":"test","path":"/first_name","value":"Someone"}]`),
+	})
+	require.ErrorIs(t, err, jsonpatch.ErrTestFailed)
+
+	updated, err := svc.Patch(context.Background(), p.ID, p.UserID, PatchDocument{
+		Type:    jsonpatch.JSONPatchType,
+		Body:    []byte(`[{"op":"test","path":"/first_name","value":"Jane"},{"op":"replace","path":"/last_name","value":"Roe"}]`),
+		Version: &version,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Roe", updated.LastName)
+	require.Equal(t, []string{"last_name"}, repo.patched)
+
+	_, err = svc.Patch(context.Background(), p.ID, p.UserID, PatchDocument{
+		Type:    jsonpatch.MergePatchType,
+		Body:    []byte(`{"last_name":"Poe"}`),
+		Version: &version,
+	})
+	require.ErrorIs(t, err, ErrVersionConflict)
+}
+
+// patchRepo is an in-memory Repository holding a single profile.
+type patchRepo struct {
+	Repository
+	profile   Profile
+	patched   []string
+	revisions []Revision
+	tags      map[uuid.UUID][]string
+	grants    map[uuid.UUID]string
+	// revisionErr fails CreateRevision.
+	revisionErr error
+
+	purgedBefore time.Time
+	purgedKeys   []string
+}
+
+func newPatchRepo() (*patchRepo, *Profile) {
+	bio, dob := "hello", time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+	repo := &patchRepo{profile: Profile{
+		ID:          uuid.New(),
+		UserID:      uuid.New(),
+		FirstName:   "Jane",
+		LastName:    "Doe",
+		Bio:         &bio,
+		DateOfBirth: &dob,
+		Visibility:  VisibilityPrivate,
+		Version:     1,
+	}}
+	return repo, &repo.profile
+}
+
+func (r *patchRepo) GetByID(ctx context.Context, id, userID uuid.UUID) (*Profile, error) {
+	access := AccessOwner
+	if userID != r.profile.UserID {
+		access = r.grants[userID]
+	}
+	if id != r.profile.ID || access == "" || r.profile.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	p := r.profile
+	p.Access = access
+	return &p, nil
+}
+
+func (r *patchRepo) CreateRevision(ctx context.Context, rev *Revision) error {
+	if r.revisionErr != nil {
+		return r.revisionErr
+	}
+	r.revisions = append(r.revisions, *rev)
+	return nil
+}
+
+// Transact restores the profile, revisions and tags when fn fails.
+func (r *patchRepo) Transact(ctx context.Context, fn func(Repository) error) error {
+	profile, revisions := r.profile, append([]Revision(nil), r.revisions...)
+	tags := make(map[uuid.UUID][]string, len(r.tags))
+	for id, t := range r.tags {
+		tags[id] = append([]string(nil), t...)
+	}
+	if err := fn(r); err != nil {
+		r.profile, r.revisions, r.tags = profile, revisions, tags
+		return err
+	}
+	return nil
+}
+
+func (r *patchRepo) GetRevision(ctx context.Context, id uuid.UUID, version int) (*Revision, error) {
+	for _, rev := range r.revisions {
+		if rev.Version == version {
+			return &rev, nil
+		}
+	}
+	return nil, ErrRevisionNotFound
+}
+
+func (r *patchRepo) PreviousRevision(ctx context.Context, id uuid.UUID, version int) (*Revision, error) {
+	var prev *Revision
+	for i, rev := range r.revisions {
+		if rev.Version < version && (prev == nil || rev.Version > prev.Version) {
+			prev = &r.revisions[i]
+		}
+	}
+	if prev == nil {
+		return nil, ErrRevisionNotFound
+	}
+	out := *prev
+	return &out, nil
+}
+
+func (r *patchRepo) PruneRevisions(ctx context.Context, id uuid.UUID, version int) error {
+	kept := r.revisions[:0]
+	for _, rev := range r.revisions {
+		if rev.Version > version {
+			kept = append(kept, rev)
+		}
+	}
+	r.revisions = kept
+	return nil
+}
+
+func (r *patchRepo) Patch(ctx context.Context, id, userID uuid.UUID, fields map[string]interface{}, version int) (*Profile, error) {
+	if version != r.profile.Version {
+		return nil, ErrVersionConflict
+	}
+	r.patched = r.patched[:0]
+	for name, val := range fields {
+		r.patched = append(r.patched, name)
+		switch name {
+		case "bio":
+			r.profile.Bio = val.(*string)
+		case "last_name":
+			r.profile.LastName = val.(string)
+		case "date_of_birth":
+			r.profile.DateOfBirth = val.(*time.Time)
+		case "field_visibility":
+			r.profile.FieldVis = val.(FieldVisibility)
+		case "custom_fields":
+			r.profile.CustomFields = val.(CustomFields)
+		}
+	}
+	r.profile.Version++
+	p := r.profile
+	return &p, nil
+}
@@ -0,0 +1,177 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Handle errors.
+var (
+	ErrInvalidHandle = errors.New("handle must be 3-30 lowercase letters, digits or underscores")
+	ErrHandleTaken   = errors.New("handle already taken")
+)
+
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+
+// reservedHandles cannot be claimed because they collide with routes,
+// staff roles or common impersonation targets.
+var reservedHandles = map[string]bool{
+	"admin": true, "administrator": true, "api": true, "help": true, "login": true,
+	"logout": true, "me": true, "null": true, "profiles": true, "public": true,
+	"root": true, "settings": true, "signup": true, "support": true, "system": true,
+	"undefined": true, "users": true, "www": true,
+}
+
+// normalizeHandle lowercases and validates a handle. An empty value clears it.
+func normalizeHandle(raw string) (*string, error) {
+	handle := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(raw), "@"))
+	if handle == "" {
+		return nil, nil
+	}
+	if !handlePattern.MatchString(handle) || reservedHandles[handle] {
+		return nil, ErrInvalidHandle
+	}
+	return &handle, nil
+}
+
+// Relations tells how a signed-in reader relates to profile owners.
+type Relations interface {
+	// BlockedIDs returns the users userID blocks or is blocked by.
+	BlockedIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	// Connected reports which candidates userID is connected with.
+	Connected(ctx context.Context, userID uuid.UUID, candidates []uuid.UUID) (map[uuid.UUID]bool, error)
+}
+
+// WithRelations hides public profiles across blocks and shows
+// connections-only fields to connections. Without it every reader is a
+// stranger.
+func WithRelations(relations Relations) Option {
+	return func(s *Service) {
+		s.relations = relations
+	}
+}
+
+// GetPublic resolves a public or unlisted profile by handle for viewer,
+// which is uuid.Nil for anonymous readers. Profiles whose owner blocks or
+// is blocked by viewer are not found.
+func (s *Service) GetPublic(ctx context.Context, handle string, viewer uuid.UUID) (*PublicProfile, error) {
+	normalized, err := normalizeHandle(handle)
+	if err != nil || normalized == nil {
+		return nil, ErrNotFound
+	}
+	p, err := s.repo.GetByHandle(ctx, *normalized)
+	if err != nil {
+		return nil, err
+	}
+	if p.Visibility != VisibilityPublic && p.Visibility != VisibilityUnlisted {
+		return nil, ErrNotFound
+	}
+	blocked, err := s.blockedOwners(ctx, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(blocked, p.UserID) {
+		return nil, ErrNotFound
+	}
+	connected, err := s.connectedOwners(ctx, viewer, p)
+	if err != nil {
+		return nil, err
+	}
+	return s.toPublic(p, connected[p.UserID]), nil
+}
+
+// SearchPublic lists public profiles only; unlisted ones are reachable by
+// handle but never enumerated.
+func (s *Service) SearchPublic(ctx context.Context, filter PublicFilter) ([]PublicProfile, int, error) {
+	var err error
+	if filter.Exclude, err = s.blockedOwners(ctx, filter.Viewer); err != nil {
+		return nil, 0, err
+	}
+	profiles, total, err := s.repo.SearchPublic(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	owners := make([]*Profile, len(profiles))
+	for i := range profiles {
+		owners[i] = &profiles[i]
+	}
+	connected, err := s.connectedOwners(ctx, filter.Viewer, owners...)
+	if err != nil {
+		return nil, 0, err
+	}
+	out := make([]PublicProfile, 0, len(profiles))
+	for i := range profiles {
+		out = append(out, *s.toPublic(&profiles[i], connected[profiles[i].UserID]))
+	}
+	return out, total, nil
+}
+
+// blockedOwners returns the users on the other side of a block from viewer.
+func (s *Service) blockedOwners(ctx context.Context, viewer uuid.UUID) ([]uuid.UUID, error) {
+	if s.relations == nil || viewer == uuid.Nil {
+		return nil, nil
+	}
+	return s.relations.BlockedIDs(ctx, viewer)
+}
+
+// connectedOwners reports which owners of profiles viewer may see
+// connections-only fields of: their connections and themselves.
+func (s *Service) connectedOwners(ctx context.Context, viewer uuid.UUID, profiles ...*Profile) (map[uuid.UUID]bool, error) {
+	out := map[uuid.UUID]bool{}
+	if s.relations == nil || viewer == uuid.Nil {
+		return out, nil
+	}
+	var owners []uuid.UUID
+	for _, p := range profiles {
+		if p.UserID == viewer {
+			out[viewer] = true
+		} else if !slices.Contains(owners, p.UserID) {
+			owners = append(owners, p.UserID)
+		}
+	}
+	connected, err := s.relations.Connected(ctx, viewer, owners)
+	if err != nil {
+		return nil, err
+	}
+	for id, ok := range connected {
+		out[id] = out[id] || ok
+	}
+	return out, nil
+}
+
+func (s *Service) toPublic(p *Profile, connected bool) *PublicProfile {
+	s.present(p)
+	pub := &PublicProfile{
+		ID:           p.ID,
+		FirstName:    p.FirstName,
+		LastName:     p.LastName,
+		ProfileImage: p.ProfileImage,
+		ProfileThumb: p.ProfileThumb,
+		CoverImage:   p.CoverImage,
+		CoverThumb:   p.CoverThumb,
+	}
+	if p.Handle != nil {
+		pub.Handle = *p.Handle
+	}
+	if p.FieldVis.Exposed("bio", connected) {
+		pub.Bio = p.Bio
+	}
+	if p.FieldVis.Exposed("website", connected) {
+		pub.Website = p.Website
+	}
+	if p.FieldVis.Exposed("location", connected) {
+		pub.Location, pub.Country = p.Location, p.Country
+	}
+	if p.FieldVis.Exposed("phone", connected) {
+		pub.Phone = p.Phone
+	}
+	if p.FieldVis.Exposed("date_of_birth", connected) {
+		pub.DateOfBirth = p.DateOfBirth
+	}
+	return pub
+}
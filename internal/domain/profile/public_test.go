@@ -0,0 +1,99 @@
+package profile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeHandle(t *testing.T) {
+	handle, err := normalizeHandle(" @Jane_Doe ")
+	require.NoError(t, err)
+	require.Equal(t, "jane_doe", *handle)
+
+	handle, err = normalizeHandle("")
+	require.NoError(t, err)
+	require.Nil(t, handle)
+
+	for _, bad := range []string{"ab", "admin", "has space", "dash-ed"} {
+		_, err := normalizeHandle(bad)
+		require.ErrorIs(t, err, ErrInvalidHandle, bad)
+	}
+}
+
+func TestToPublicHonoursFieldVisibility(t *testing.T) {
+	phone, bio := "+15550100", "hello"
+	dob := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+	p := &Profile{
+		ID:          uuid.New(),
+		FirstName:   "Jane",
+		Phone:       &phone,
+		Bio:         &bio,
+		DateOfBirth: &dob,
+		FieldVis:    FieldVisibility{"phone": FieldPublic, "bio": FieldPrivate},
+	}
+
+	pub := NewService(nil).toPublic(p, false)
+	require.Equal(t, &phone, pub.Phone)
+	require.Nil(t, pub.Bio)
+	require.Nil(t, pub.DateOfBirth)
+}
+
+func TestGetPublicAppliesRelations(t *testing.T) {
+	phone, handle := "+15550100", "jane_doe"
+	owner, friend, stranger, blocked := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	repo := &publicRepo{profile: Profile{
+		ID:         uuid.New(),
+		UserID:     owner,
+		Handle:     &handle,
+		Visibility: VisibilityPublic,
+		Phone:      &phone,
+		FieldVis:   FieldVisibility{"phone": FieldConnections},
+	}}
+	relations := &fakeRelations{owner: owner, connected: map[uuid.UUID]bool{friend: true}, blocked: map[uuid.UUID]bool{blocked: true}}
+	svc := NewService(repo, WithRelations(relations))
+	ctx := context.Background()
+
+	for viewer, sees := range map[uuid.UUID]bool{friend: true, owner: true, stranger: false, uuid.Nil: false} {
+		pub, err := svc.GetPublic(ctx, handle, viewer)
+		require.NoError(t, err)
+		require.Equal(t, sees, pub.Phone != nil, viewer)
+	}
+
+	_, err := svc.GetPublic(ctx, handle, blocked)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+type publicRepo struct {
+	Repository
+	profile Profile
+}
+
+func (r *publicRepo) GetByHandle(ctx context.Context, handle string) (*Profile, error) {
+	p := r.profile
+	return &p, nil
+}
+
+// fakeRelations relates viewers to a single profile owner.
+type fakeRelations struct {
+	owner              uuid.UUID
+	connected, blocked map[uuid.UUID]bool
+}
+
+func (f *fakeRelations) BlockedIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	if f.blocked[userID] {
+		return []uuid.UUID{f.owner}, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeRelations) Connected(ctx context.Context, userID uuid.UUID, candidates []uuid.UUID) (map[uuid.UUID]bool, error) {
+	out := map[uuid.UUID]bool{}
+	for _, id := range candidates {
+		out[id] = f.connected[userID]
+	}
+	return out, nil
+}
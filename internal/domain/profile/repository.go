@@ -2,6 +2,7 @@ package profile
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -9,11 +10,58 @@ import (
 // Repository defines persistence needs for profiles.
 type Repository interface {
 	Create(ctx context.Context, profile *Profile) error
-	BulkCreate(ctx context.Context, profiles []*Profile) error
+	// Update writes profile when the stored version is profile.Version-1
+	// and returns ErrVersionConflict otherwise.
 	Update(ctx context.Context, profile *Profile) error
 	Patch(ctx context.Context, profileID uuid.UUID, userID uuid.UUID, fields map[string]interface{}, version int) (*Profile, error)
 	Delete(ctx context.Context, profileID uuid.UUID, userID uuid.UUID, hard bool, version int) error
-	BulkDelete(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, hard bool) (int, error)
+	// BulkDelete returns how many profiles it removed and, when hard, the
+	// image keys of the removed rows.
+	BulkDelete(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, hard bool) (int, []string, error)
+	// GetByID returns a live profile userID owns or holds an unexpired
+	// grant on, with Access set accordingly.
 	GetByID(ctx context.Context, profileID uuid.UUID, userID uuid.UUID) (*Profile, error)
+	// GetDeleted returns a profile only while it is in the trash.
+	GetDeleted(ctx context.Context, profileID uuid.UUID, userID uuid.UUID) (*Profile, error)
+	// ListTrash returns soft-deleted profiles, most recently deleted first.
+	ListTrash(ctx context.Context, userID uuid.UUID, limit, offset int) ([]Profile, int, error)
+	Restore(ctx context.Context, profileID uuid.UUID, userID uuid.UUID, version int) (*Profile, error)
+	// PurgeDeleted hard-deletes profiles soft-deleted before the cutoff
+	// across all users and tenants, returning how many it removed and their
+	// image keys.
+	PurgeDeleted(ctx context.Context, before time.Time) (int, []string, error)
 	List(ctx context.Context, filter Filter) ([]Profile, int, error)
+	// Stream calls fn for every live profile of the user, oldest first.
+	Stream(ctx context.Context, userID uuid.UUID, fn func(*Profile) error) error
+	GetByHandle(ctx context.Context, handle string) (*Profile, error)
+	SearchPublic(ctx context.Context, filter PublicFilter) ([]Profile, int, error)
+	CreateRevision(ctx context.Context, rev *Revision) error
+	// ListRevisions returns revisions newest first.
+	ListRevisions(ctx context.Context, profileID uuid.UUID, limit, offset int) ([]Revision, int, error)
+	GetRevision(ctx context.Context, profileID uuid.UUID, version int) (*Revision, error)
+	// PreviousRevision returns the newest retained revision below version.
+	PreviousRevision(ctx context.Context, profileID uuid.UUID, version int) (*Revision, error)
+	// PruneRevisions deletes revisions at or below version.
+	PruneRevisions(ctx context.Context, profileID uuid.UUID, version int) error
+	// AddTags attaches tags to a profile, ignoring ones it already has.
+	AddTags(ctx context.Context, profileID uuid.UUID, tags []string) error
+	RemoveTags(ctx context.Context, profileID uuid.UUID, tags []string) error
+	// ListTags returns the tags of each profile, sorted.
+	ListTags(ctx context.Context, profileIDs []uuid.UUID) (map[uuid.UUID][]string, error)
+	// TagCounts returns the user's tags with how many live profiles carry
+	// each, most used first.
+	TagCounts(ctx context.Context, userID uuid.UUID) ([]TagCount, error)
+	// SaveGrant creates the grant or replaces the access and expiry of the
+	// existing one for the same profile and user.
+	SaveGrant(ctx context.Context, grant *Grant) error
+	// ListGrants returns every grant on a profile, oldest first.
+	ListGrants(ctx context.Context, profileID uuid.UUID) ([]Grant, error)
+	// DeleteGrant reports whether a grant was removed.
+	DeleteGrant(ctx context.Context, profileID, userID uuid.UUID) (bool, error)
+	// ListShared returns live profiles userID holds unexpired grants on,
+	// most recently granted first, with Access and AccessExpiresAt set.
+	ListShared(ctx context.Context, userID uuid.UUID, limit, offset int) ([]Profile, int, error)
+	// Transact runs fn with a repository bound to one transaction; fn's
+	// writes commit together when it returns nil and roll back otherwise.
+	Transact(ctx context.Context, fn func(Repository) error) error
 }
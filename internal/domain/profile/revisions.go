@@ -0,0 +1,293 @@
+package profile
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RetentionSettingKey is the per-user setting bounding how many revisions
+// are kept for each profile.
+const RetentionSettingKey = "profiles.revision_retention"
+
+// DefaultRevisionRetention applies when no settings source is wired or the
+// user has no usable value.
+const DefaultRevisionRetention = 50
+
+// Revision errors.
+var (
+	// ErrRevisionNotFound is returned for versions that were never stored
+	// or have been pruned.
+	ErrRevisionNotFound = errors.New("revision not found")
+	// ErrNoEarlierRevision is returned when a diff has nothing to compare
+	// against: the revision is the first one or its predecessors are pruned.
+	ErrNoEarlierRevision = errors.New("no earlier revision to compare with")
+)
+
+// SettingsReader resolves effective per-user settings.
+type SettingsReader interface {
+	Value(ctx context.Context, userID uuid.UUID, key string) (interface{}, error)
+}
+
+// WithSettings reads per-user revision retention from settings.
+func WithSettings(settings SettingsReader) Option {
+	return func(s *Service) {
+		s.settings = settings
+	}
+}
+
+// Snapshot is the stored state of one profile version: the editable fields,
+// the upload keys behind the image links and the tags. Tags is nil in
+// revisions recorded before tags were versioned.
+type Snapshot struct {
+	CreateRequest
+	ProfileKey *string  `json:"profile_image_key,omitempty"`
+	CoverKey   *string  `json:"cover_image_key,omitempty"`
+	Tags       []string `json:"tags"`
+}
+
+// Value implements driver.Valuer.
+func (s Snapshot) Value() (driver.Value, error) {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan implements sql.Scanner.
+func (s *Snapshot) Scan(src interface{}) error {
+	switch data := src.(type) {
+	case []byte:
+		return json.Unmarshal(data, s)
+	case string:
+		return json.Unmarshal([]byte(data), s)
+	default:
+		return fmt.Errorf("snapshot: unsupported type %T", src)
+	}
+}
+
+// Revision is a snapshot of one profile version and who produced it.
+type Revision struct {
+	ProfileID uuid.UUID `json:"profile_id" db:"profile_id"`
+	Version   int       `json:"version" db:"version"`
+	Snapshot  Snapshot  `json:"snapshot" db:"snapshot"`
+	ActorID   uuid.UUID `json:"actor_id" db:"actor_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Change is one field that differs between two revisions.
+type Change struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// RevisionDiff lists the changes from one revision to another.
+type RevisionDiff struct {
+	From    int      `json:"from"`
+	To      int      `json:"to"`
+	Changes []Change `json:"changes"`
+}
+
+// Revisions lists the retained revisions of a profile, newest first.
+func (s *Service) Revisions(ctx context.Context, id, userID uuid.UUID, limit, offset int) ([]Revision, int, error) {
+	if _, err := s.Get(ctx, id, userID); err != nil {
+		return nil, 0, err
+	}
+	revisions, total, err := s.repo.ListRevisions(ctx, id, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range revisions {
+		s.presentSnapshot(&revisions[i].Snapshot)
+	}
+	return revisions, total, nil
+}
+
+// Revision returns a single retained revision.
+func (s *Service) Revision(ctx context.Context, id, userID uuid.UUID, version int) (*Revision, error) {
+	if _, err := s.Get(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	rev, err := s.repo.GetRevision(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+	s.presentSnapshot(&rev.Snapshot)
+	return rev, nil
+}
+
+// DiffRevisions compares two retained revisions field by field. A zero from
+// compares against the nearest earlier retained revision.
+func (s *Service) DiffRevisions(ctx context.Context, id, userID uuid.UUID, from, to int) (*RevisionDiff, error) {
+	b, err := s.Revision(ctx, id, userID, to)
+	if err != nil {
+		return nil, err
+	}
+	var a *Revision
+	if from == 0 {
+		a, err = s.repo.PreviousRevision(ctx, id, to)
+		if errors.Is(err, ErrRevisionNotFound) {
+			return nil, ErrNoEarlierRevision
+		}
+	} else {
+		a, err = s.repo.GetRevision(ctx, id, from)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.presentSnapshot(&a.Snapshot)
+	return &RevisionDiff{From: a.Version, To: to, Changes: diffSnapshots(a.Snapshot, b.Snapshot)}, nil
+}
+
+// RestoreRevision writes the fields and tags of an earlier revision as a
+// new version. Uploaded images are not versioned, so image slots backed by
+// an upload keep their current value.
+func (s *Service) RestoreRevision(ctx context.Context, id, userID uuid.UUID, version int, expected *int) (*Profile, error) {
+	current, err := s.authorize(ctx, id, userID, AccessWrite)
+	if err != nil {
+		return nil, err
+	}
+	if expected != nil && *expected != current.Version {
+		return nil, ErrVersionConflict
+	}
+	rev, err := s.repo.GetRevision(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+	next, err := s.applyEditable(current, rev.Snapshot.CreateRequest)
+	if err != nil {
+		return nil, err
+	}
+	restored := &Profile{ProfileKey: rev.Snapshot.ProfileKey, CoverKey: rev.Snapshot.CoverKey}
+	for i, slot := range imageSlots(next) {
+		prev, old := imageSlots(current)[i], imageSlots(restored)[i]
+		if *prev.key != nil || *old.key != nil {
+			*slot.url, *slot.key = *prev.url, *prev.key
+		}
+	}
+	update := changedColumns(current, next)
+	if err := checkOwnerColumns(current, update); err != nil {
+		return nil, err
+	}
+	tags, err := s.repo.ListTags(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	current.Tags = tags[id]
+	var add, remove []string
+	if rev.Snapshot.Tags != nil {
+		add, remove = tagChanges(current.Tags, rev.Snapshot.Tags)
+	}
+	if len(update) == 0 && len(add) == 0 && len(remove) == 0 {
+		return s.present(current), nil
+	}
+	var updated *Profile
+	err = s.transact(ctx, func(tx *Service) error {
+		var err error
+		if updated, err = tx.repo.Patch(ctx, id, current.UserID, update, current.Version); err != nil {
+			return err
+		}
+		if len(remove) > 0 {
+			if err := tx.repo.RemoveTags(ctx, id, remove); err != nil {
+				return err
+			}
+		}
+		if len(add) > 0 {
+			if err := tx.repo.AddTags(ctx, id, add); err != nil {
+				return err
+			}
+		}
+		return tx.recordRevision(ctx, updated, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	updated.Tags = current.Tags
+	if rev.Snapshot.Tags != nil {
+		updated.Tags = rev.Snapshot.Tags
+	}
+	updated.Access, updated.AccessExpiresAt = current.Access, current.AccessExpiresAt
+	return s.present(updated), nil
+}
+
+// recordRevision snapshots p and its stored tags at its current version and
+// prunes revisions beyond the owner's retention.
+func (s *Service) recordRevision(ctx context.Context, p *Profile, actor uuid.UUID) error {
+	tags, err := s.repo.ListTags(ctx, []uuid.UUID{p.ID})
+	if err != nil {
+		return fmt.Errorf("record revision: %w", err)
+	}
+	rev := &Revision{
+		ProfileID: p.ID,
+		Version:   p.Version,
+		Snapshot: Snapshot{
+			CreateRequest: editable(p),
+			ProfileKey:    p.ProfileKey,
+			CoverKey:      p.CoverKey,
+			Tags:          append([]string{}, tags[p.ID]...),
+		},
+		ActorID:   actor,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.repo.CreateRevision(ctx, rev); err != nil {
+		return fmt.Errorf("record revision: %w", err)
+	}
+	if keep := s.retention(ctx, p.UserID); p.Version > keep {
+		return s.repo.PruneRevisions(ctx, p.ID, p.Version-keep)
+	}
+	return nil
+}
+
+func (s *Service) retention(ctx context.Context, userID uuid.UUID) int {
+	if s.settings == nil {
+		return DefaultRevisionRetention
+	}
+	if v, err := s.settings.Value(ctx, userID, RetentionSettingKey); err == nil {
+		if n, ok := v.(int); ok && n > 0 {
+			return n
+		}
+	}
+	return DefaultRevisionRetention
+}
+
+// presentSnapshot swaps upload keys for signed links, like present does for
+// profiles.
+func (s *Service) presentSnapshot(snap *Snapshot) {
+	p := &Profile{ProfileKey: snap.ProfileKey, CoverKey: snap.CoverKey}
+	s.present(p)
+	if p.ProfileImage != nil {
+		snap.ProfileImage = p.ProfileImage
+	}
+	if p.CoverImage != nil {
+		snap.CoverImage = p.CoverImage
+	}
+	snap.ProfileKey, snap.CoverKey = nil, nil
+}
+
+func diffSnapshots(a, b Snapshot) []Change {
+	var before, after map[string]interface{}
+	rawA, _ := json.Marshal(a.CreateRequest)
+	rawB, _ := json.Marshal(b.CreateRequest)
+	_ = json.Unmarshal(rawA, &before)
+	_ = json.Unmarshal(rawB, &after)
+	changes := []Change{}
+	for field, from := range before {
+		if to := after[field]; !reflect.DeepEqual(from, to) {
+			changes = append(changes, Change{Field: field, From: from, To: to})
+		}
+	}
+	if a.Tags != nil && b.Tags != nil && !sameTags(a.Tags, b.Tags) {
+		changes = append(changes, Change{Field: "tags", From: a.Tags, To: b.Tags})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
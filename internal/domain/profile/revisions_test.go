@@ -0,0 +1,86 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kidpech/api_free_demo/pkg/jsonpatch"
+)
+
+func TestRestoreRevisionCreatesNewVersion(t *testing.T) {
+	repo, p := newPatchRepo()
+	svc := NewService(repo, WithSettings(fixedSettings{RetentionSettingKey: 2}))
+	ctx := context.Background()
+	require.NoError(t, svc.recordRevision(ctx, p, p.UserID))
+
+	for _, name := range []string{"Roe", "Poe"} {
+		_, err := svc.Patch(ctx, p.ID, p.UserID, PatchDocument{
+			Type: jsonpatch.MergePatchType,
+			Body: []byte(`{"last_name":"` + name + `"}`),
+		})
+		require.NoError(t, err)
+	}
+	// Retention of two drops version 1.
+	require.Len(t, repo.revisions, 2)
+	_, err := svc.RestoreRevision(ctx, p.ID, p.UserID, 1, nil)
+	require.ErrorIs(t, err, ErrRevisionNotFound)
+
+	diff, err := svc.DiffRevisions(ctx, p.ID, p.UserID, 2, 3)
+	require.NoError(t, err)
+	require.Equal(t, []Change{{Field: "last_name", From: "Roe", To: "Poe"}}, diff.Changes)
+	diff, err = svc.DiffRevisions(ctx, p.ID, p.UserID, 0, 3)
+	require.NoError(t, err)
+	require.Equal(t, 2, diff.From)
+	// Version 1 is pruned, so version 2 has nothing left to compare with.
+	_, err = svc.DiffRevisions(ctx, p.ID, p.UserID, 0, 2)
+	require.ErrorIs(t, err, ErrNoEarlierRevision)
+
+	restored, err := svc.RestoreRevision(ctx, p.ID, p.UserID, 2, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Roe", restored.LastName)
+	require.Equal(t, 4, restored.Version)
+	require.Equal(t, 4, repo.revisions[len(repo.revisions)-1].Version)
+}
+
+type fixedSettings map[string]interface{}
+
+func (f fixedSettings) Value(ctx context.Context, userID uuid.UUID, key string) (interface{}, error) {
+	return f[key], nil
+}
+
+func TestPatchRollsBackWhenRevisionFails(t *testing.T) {
+	repo, p := newPatchRepo()
+	repo.revisionErr = errors.New("disk full")
+	svc := NewService(repo)
+
+	_, err := svc.Patch(context.Background(), p.ID, p.UserID, PatchDocument{
+		Type: jsonpatch.MergePatchType,
+		Body: []byte(`{"last_name":"Roe"}`),
+	})
+	require.ErrorIs(t, err, repo.revisionErr)
+	require.Equal(t, "Doe", repo.profile.LastName)
+	require.Equal(t, 1, repo.profile.Version)
+}
+
+func TestRevisionsTrackTags(t *testing.T) {
+	repo, p := newPatchRepo()
+	svc := NewService(repo)
+	ctx := context.Background()
+	require.NoError(t, svc.recordRevision(ctx, p, p.UserID))
+
+	_, err := svc.AddTags(ctx, p.ID, p.UserID, TagsRequest{Tags: []string{"vip"}})
+	require.NoError(t, err)
+	diff, err := svc.DiffRevisions(ctx, p.ID, p.UserID, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, []Change{{Field: "tags", From: []string{}, To: []string{"vip"}}}, diff.Changes)
+
+	restored, err := svc.RestoreRevision(ctx, p.ID, p.UserID, 1, nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, restored.Version)
+	require.Empty(t, restored.Tags)
+	require.Empty(t, repo.tags[p.ID])
+}
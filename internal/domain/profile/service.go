@@ -10,6 +10,11 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/microcosm-cc/bluemonday"
+
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+	"github.com/kidpech/api_free_demo/pkg/filterexpr"
+	"github.com/kidpech/api_free_demo/pkg/phone"
+	"github.com/kidpech/api_free_demo/pkg/search"
 )
 
 // Service orchestrates profile logic.
@@ -17,8 +22,20 @@ type Service struct {
 	repo      Repository
 	validator *validator.Validate
 	sanitizer *bluemonday.Policy
+	images    ImageStore
+	settings  SettingsReader
+	fields    CustomFieldSchema
+	owners    OwnerDirectory
+	relations Relations
+	geocoder  Geocoder
+	// deferred collects image keys to discard once an enclosing
+	// transaction commits; nil discards immediately.
+	deferred *[]*string
 }
 
+// Option customizes optional Service collaborators.
+type Option func(*Service)
+
 // Sentinel errors for HTTP mapping.
 var (
 	ErrNotFound        = errors.New("profile not found")
@@ -27,12 +44,35 @@ var (
 )
 
 // NewService provides a profile service.
-func NewService(repo Repository) *Service {
-	return &Service{
+func NewService(repo Repository, opts ...Option) *Service {
+	s := &Service{
 		repo:      repo,
 		validator: validator.New(),
 		sanitizer: bluemonday.UGCPolicy(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// transact runs fn with a copy of s bound to one transaction, so a write
+// and its revision commit together. Image discards wait for the commit of
+// the outermost transaction.
+func (s *Service) transact(ctx context.Context, fn func(tx *Service) error) error {
+	var discards []*string
+	err := s.repo.Transact(ctx, func(repo Repository) error {
+		tx := *s
+		tx.repo = repo
+		if tx.deferred == nil {
+			tx.deferred = &discards
+		}
+		return fn(&tx)
+	})
+	if err == nil {
+		s.discardImages(ctx, discards...)
+	}
+	return err
 }
 
 // Create persists a profile.
@@ -42,55 +82,42 @@ func (s *Service) Create(ctx context.Context, userID uuid.UUID, req CreateReques
 	}
 	now := time.Now().UTC()
 	profile := &Profile{
-		ID:        uuid.New(),
-		UserID:    userID,
-		FirstName: sanitizeField(s.sanitizer, req.FirstName),
-		LastName:  sanitizeField(s.sanitizer, req.LastName),
-		CreatedAt: now,
-		UpdatedAt: now,
-		Version:   1,
+		ID:         uuid.New(),
+		UserID:     userID,
+		FirstName:  sanitizeField(s.sanitizer, req.FirstName),
+		LastName:   sanitizeField(s.sanitizer, req.LastName),
+		Visibility: VisibilityPrivate,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Version:    1,
 	}
 	if err := assignOptionalFields(s.sanitizer, profile, req); err != nil {
 		return nil, err
 	}
-	if err := s.repo.Create(ctx, profile); err != nil {
+	if err := normalizePhone(profile, nil); err != nil {
 		return nil, err
 	}
-	return profile, nil
-}
-
-// BulkCreate inserts many profiles and returns successes/failures.
-func (s *Service) BulkCreate(ctx context.Context, userID uuid.UUID, req BulkCreateRequest) ([]*Profile, []error) {
-	profiles := make([]*Profile, 0, len(req.Profiles))
-	errs := make([]error, len(req.Profiles))
-	for i, payload := range req.Profiles {
-		if err := s.validator.Struct(payload); err != nil {
-			errs[i] = err
-			continue
-		}
-		now := time.Now().UTC()
-		p := &Profile{
-			ID:        uuid.New(),
-			UserID:    userID,
-			FirstName: sanitizeField(s.sanitizer, payload.FirstName),
-			LastName:  sanitizeField(s.sanitizer, payload.LastName),
-			CreatedAt: now,
-			UpdatedAt: now,
-			Version:   1,
-		}
-		if err := assignOptionalFields(s.sanitizer, p, payload); err != nil {
-			errs[i] = err
-			continue
-		}
-		profiles = append(profiles, p)
+	if err := s.locate(ctx, profile, req); err != nil {
+		return nil, err
+	}
+	custom, err := s.checkCustomFields(ctx, req.CustomFields)
+	if err != nil {
+		return nil, err
 	}
-	if len(profiles) > 0 {
-		_ = s.repo.BulkCreate(ctx, profiles)
+	profile.CustomFields = custom
+	err = s.transact(ctx, func(tx *Service) error {
+		if err := tx.repo.Create(ctx, profile); err != nil {
+			return err
+		}
+		return tx.recordRevision(ctx, profile, userID)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return profiles, errs
+	return profile, nil
 }
 
-// Get fetches a profile ensuring ownership.
+// Get fetches a profile the user owns or was granted access to.
 func (s *Service) Get(ctx context.Context, id, userID uuid.UUID) (*Profile, error) {
 	p, err := s.repo.GetByID(ctx, id, userID)
 	if err != nil {
@@ -99,92 +126,157 @@ func (s *Service) Get(ctx context.Context, id, userID uuid.UUID) (*Profile, erro
 	if p == nil {
 		return nil, ErrNotFound
 	}
-	return p, nil
+	tags, err := s.repo.ListTags(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	p.Tags = tags[id]
+	return s.present(p), nil
 }
 
-// List returns paginated profiles.
-func (s *Service) List(ctx context.Context, filter Filter) ([]Profile, int, error) {
-	return s.repo.List(ctx, filter)
+// List returns a keyset page of profiles, or a ranked page when filtering by
+// a full-text query.
+func (s *Service) List(ctx context.Context, filter Filter) (*ProfilePage, error) {
+	if err := filter.Cursor.Expect(SortCreatedAt); err != nil {
+		return nil, err
+	}
+	if filter.Query != "" && filter.Cursor != nil {
+		return nil, fmt.Errorf("%w: full-text results page by offset", cursor.ErrInvalid)
+	}
+	custom, err := s.customFilters(ctx, filter.CustomFields)
+	if err != nil {
+		return nil, err
+	}
+	filter.CustomFields = custom
+	if err := s.tagFilter(&filter); err != nil {
+		return nil, err
+	}
+	if filter.Columns, err = fieldsetColumns(filter.Fields); err != nil {
+		return nil, err
+	}
+	if filter.Where, err = filterexpr.Parse(filter.Expression, FilterFields); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+	}
+	if filter.Country != "" {
+		if err := s.validator.Var(filter.Country, "iso3166_1_alpha2"); err != nil {
+			return nil, fmt.Errorf("%w: country must be an ISO 3166-1 alpha-2 code", ErrInvalidFilter)
+		}
+	}
+	if filter.Phone != "" {
+		if filter.PhoneE164, err = phone.Normalize(filter.Phone, filter.Country); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+		}
+	}
+	if err := nearFilter(&filter); err != nil {
+		return nil, err
+	}
+	profiles, total, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	page := &ProfilePage{Total: total}
+	page.Profiles, page.Page = cursor.Paginate(profiles, filter.Limit, filter.Cursor, func(p Profile) cursor.Cursor {
+		return cursor.Cursor{Sort: SortCreatedAt, Value: cursor.FormatTime(p.CreatedAt), ID: p.ID}
+	})
+	if filter.Query != "" || filter.Origin != nil {
+		page.NextCursor = ""
+	}
+	if hasField(filter.Fields, "tags") {
+		if err := s.attachTags(ctx, page.Profiles); err != nil {
+			return nil, err
+		}
+	}
+	expanded := make([]*Profile, len(page.Profiles))
+	for i := range page.Profiles {
+		p := &page.Profiles[i]
+		expanded[i] = p
+		s.present(p)
+		if filter.Query != "" && p.Snippet == nil {
+			snippet := search.Highlight(p.searchText(), filter.Query, 24)
+			p.Snippet = &snippet
+		}
+	}
+	if err := s.Expand(ctx, filter.Expand, expanded...); err != nil {
+		return nil, err
+	}
+	return page, nil
 }
 
-// Update performs PUT semantics.
+// Update performs PUT semantics; grantees need write access.
 func (s *Service) Update(ctx context.Context, id, userID uuid.UUID, req UpdateRequest) (*Profile, error) {
 	if err := s.validator.Struct(req); err != nil {
 		return nil, err
 	}
-	profile, err := s.repo.GetByID(ctx, id, userID)
+	profile, err := s.authorize(ctx, id, userID, AccessWrite)
 	if err != nil {
 		return nil, err
 	}
-	if profile == nil {
-		return nil, ErrNotFound
-	}
 	if profile.Version != req.Version {
 		return nil, ErrVersionConflict
 	}
 	profile.FirstName = sanitizeField(s.sanitizer, req.FirstName)
 	profile.LastName = sanitizeField(s.sanitizer, req.LastName)
+	stored := *profile
 	if err := assignOptionalFields(s.sanitizer, profile, req.CreateRequest); err != nil {
 		return nil, err
 	}
-	profile.Version++
-	profile.UpdatedAt = time.Now().UTC()
-	if err := s.repo.Update(ctx, profile); err != nil {
+	if err := checkOwnerColumns(&stored, changedColumns(&stored, profile)); err != nil {
 		return nil, err
 	}
-	return profile, nil
-}
-
-// Patch performs partial updates via a whitelist.
-func (s *Service) Patch(ctx context.Context, id, userID uuid.UUID, req PatchRequest) (*Profile, error) {
-	if err := s.validator.Struct(req); err != nil {
+	if err := normalizePhone(profile, &stored); err != nil {
+		return nil, err
+	}
+	if err := s.locate(ctx, profile, req.CreateRequest); err != nil {
 		return nil, err
 	}
-	allowed := map[string]bool{
-		"first_name":    true,
-		"last_name":     true,
-		"bio":           true,
-		"profile_image": true,
-		"cover_image":   true,
-		"date_of_birth": true,
-		"phone":         true,
-		"website":       true,
-		"location":      true,
-	}
-	update := make(map[string]interface{})
-	for k, v := range req.Fields {
-		if !allowed[k] {
+	if req.CustomFields != nil {
+		if profile.CustomFields, err = s.checkCustomFields(ctx, req.CustomFields); err != nil {
+			return nil, err
+		}
+	}
+	var stale []*string
+	for i, slot := range imageSlots(profile) {
+		if *slot.key == nil || *slot.url == nil {
 			continue
 		}
-		if str, ok := v.(string); ok {
-			update[k] = sanitizeField(s.sanitizer, str)
-		} else {
-			update[k] = v
+		if s.isCurrentUpload(**slot.url, *slot.key) {
+			*slot.url = *imageSlots(&stored)[i].url
+			continue
 		}
+		stale = append(stale, *slot.key)
+		*slot.key = nil
 	}
-	if len(update) == 0 {
-		return nil, errors.New("no valid fields to update")
-	}
-	updated, err := s.repo.Patch(ctx, id, userID, update, req.Version)
+	profile.Version++
+	profile.UpdatedAt = time.Now().UTC()
+	err = s.transact(ctx, func(tx *Service) error {
+		if err := tx.repo.Update(ctx, profile); err != nil {
+			return err
+		}
+		tx.discardImages(ctx, stale...)
+		return tx.recordRevision(ctx, profile, userID)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return updated, nil
+	return s.present(profile), nil
 }
 
-// Delete removes a profile (soft default).
+// Delete removes a profile (soft default). Only the owner may delete.
 func (s *Service) Delete(ctx context.Context, id, userID uuid.UUID, hard bool, version int) error {
-	profile, err := s.repo.GetByID(ctx, id, userID)
+	profile, err := s.authorize(ctx, id, userID, AccessOwner)
 	if err != nil {
 		return err
 	}
-	if profile == nil {
-		return ErrNotFound
-	}
 	if profile.Version != version {
 		return ErrVersionConflict
 	}
-	return s.repo.Delete(ctx, id, userID, hard, version)
+	if err := s.repo.Delete(ctx, id, userID, hard, version); err != nil {
+		return err
+	}
+	if hard {
+		s.discardImages(ctx, profile.ProfileKey, profile.CoverKey)
+	}
+	return nil
 }
 
 // BulkDelete removes multiple profiles at once.
@@ -192,7 +284,15 @@ func (s *Service) BulkDelete(ctx context.Context, userID uuid.UUID, req BulkDele
 	if len(req.IDs) == 0 {
 		return 0, errors.New("ids required")
 	}
-	return s.repo.BulkDelete(ctx, userID, req.IDs, req.Hard)
+	if len(req.IDs) > MaxBulkItems {
+		return 0, ErrBulkTooLarge
+	}
+	deleted, keys, err := s.repo.BulkDelete(ctx, userID, req.IDs, req.Hard)
+	if err != nil {
+		return 0, err
+	}
+	s.discardImages(ctx, stringPointers(keys)...)
+	return deleted, nil
 }
 
 func assignOptionalFields(policy *bluemonday.Policy, profile *Profile, req CreateRequest) error {
@@ -211,12 +311,28 @@ func assignOptionalFields(policy *bluemonday.Policy, profile *Profile, req Creat
 		profile.Phone = &clean
 	}
 	if req.Website != nil {
-		profile.Website = req.Website
+		website, err := canonicalWebsite(*req.Website)
+		if err != nil {
+			return err
+		}
+		profile.Website = website
 	}
 	if req.Location != nil {
 		clean := sanitizeField(policy, *req.Location)
 		profile.Location = &clean
 	}
+	if req.Latitude != nil {
+		profile.Latitude = req.Latitude
+	}
+	if req.Longitude != nil {
+		profile.Longitude = req.Longitude
+	}
+	if req.Country != nil {
+		profile.Country = nil
+		if *req.Country != "" {
+			profile.Country = req.Country
+		}
+	}
 	if req.DateOfBirth != nil && *req.DateOfBirth != "" {
 		t, err := time.Parse("2006-01-02", *req.DateOfBirth)
 		if err != nil {
@@ -225,9 +341,36 @@ func assignOptionalFields(policy *bluemonday.Policy, profile *Profile, req Creat
 		t = t.UTC()
 		profile.DateOfBirth = &t
 	}
+	if req.Handle != nil {
+		handle, err := normalizeHandle(*req.Handle)
+		if err != nil {
+			return err
+		}
+		profile.Handle = handle
+	}
+	if req.Visibility != nil {
+		profile.Visibility = *req.Visibility
+	}
+	if req.FieldVisibility != nil {
+		profile.FieldVis = req.FieldVisibility
+	}
+	if req.CustomFields != nil {
+		profile.CustomFields = req.CustomFields
+	}
 	return nil
 }
 
+// searchText mirrors the columns the full-text index covers.
+func (p *Profile) searchText() string {
+	parts := []string{p.FirstName, p.LastName}
+	for _, field := range []*string{p.Bio, p.Location} {
+		if field != nil {
+			parts = append(parts, *field)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
 func sanitizeField(policy *bluemonday.Policy, val string) string {
 	return strings.TrimSpace(policy.Sanitize(val))
 }
@@ -0,0 +1,183 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Access levels on a profile, weakest first. Owners hold every right;
+// grants give other users read or write access.
+const (
+	AccessRead  = "read"
+	AccessWrite = "write"
+	AccessOwner = "owner"
+)
+
+var accessRank = map[string]int{AccessRead: 1, AccessWrite: 2, AccessOwner: 3}
+
+// Sharing errors.
+var (
+	ErrInvalidGrant    = errors.New("profiles can only be shared with other users, until a time in the future")
+	ErrGrantNotFound   = errors.New("grant not found")
+	ErrGranteeNotFound = errors.New("user not found")
+)
+
+// Grant gives a user other than the owner access to a profile. Grants
+// past ExpiresAt no longer resolve and are skipped by listings.
+type Grant struct {
+	ProfileID uuid.UUID  `json:"profile_id" db:"profile_id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	Access    string     `json:"access" db:"access"`
+	GrantedBy uuid.UUID  `json:"granted_by" db:"granted_by"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// GrantRequest shares a profile with, or changes the access of, one user.
+type GrantRequest struct {
+	Access    string     `json:"access" validate:"required,oneof=read write"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// ownerColumns decide who else can see a profile, so grantees may not
+// change them.
+var ownerColumns = []string{"handle", "visibility", "field_visibility"}
+
+// checkOwnerColumns rejects changes to ownerColumns unless stored was
+// loaded with owner access.
+func checkOwnerColumns(stored *Profile, changed map[string]interface{}) error {
+	if stored.Access == AccessOwner {
+		return nil
+	}
+	for _, name := range ownerColumns {
+		if _, ok := changed[name]; ok {
+			return fmt.Errorf("%w: only the owner may change %s", ErrForbidden, name)
+		}
+	}
+	return nil
+}
+
+// allows reports whether access covers need.
+func allows(access, need string) bool {
+	return accessRank[access] >= accessRank[need]
+}
+
+// authorize loads a live profile userID can reach and checks that their
+// access covers need. Users without any access get ErrNotFound, the same
+// as for ids that do not exist.
+func (s *Service) authorize(ctx context.Context, id, userID uuid.UUID, need string) (*Profile, error) {
+	p, err := s.repo.GetByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrNotFound
+	}
+	if !allows(p.Access, need) {
+		return nil, ErrForbidden
+	}
+	return p, nil
+}
+
+// Share gives granteeID access to a profile userID owns, replacing the
+// access and expiry of an existing grant. Blocks in either direction make
+// granteeID unknown; placing one later severs the grant.
+func (s *Service) Share(ctx context.Context, id, userID, granteeID uuid.UUID, req GrantRequest) (*Grant, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+	p, err := s.authorize(ctx, id, userID, AccessOwner)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if granteeID == p.UserID || (req.ExpiresAt != nil && !req.ExpiresAt.After(now)) {
+		return nil, ErrInvalidGrant
+	}
+	if s.owners != nil {
+		found, err := s.owners.Summaries(ctx, []uuid.UUID{granteeID})
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := found[granteeID]; !ok {
+			return nil, ErrGranteeNotFound
+		}
+	}
+	// Users on the other side of a block are hidden, as everywhere else.
+	blocked, err := s.blockedOwners(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range blocked {
+		if id == granteeID {
+			return nil, ErrGranteeNotFound
+		}
+	}
+	grant := &Grant{ProfileID: id, UserID: granteeID, Access: req.Access, GrantedBy: userID, CreatedAt: now, UpdatedAt: now}
+	if req.ExpiresAt != nil {
+		expires := req.ExpiresAt.UTC()
+		grant.ExpiresAt = &expires
+	}
+	if err := s.repo.SaveGrant(ctx, grant); err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// Grants lists who a profile userID owns is shared with, expired grants
+// included so the owner can renew or revoke them.
+func (s *Service) Grants(ctx context.Context, id, userID uuid.UUID) ([]Grant, error) {
+	if _, err := s.authorize(ctx, id, userID, AccessOwner); err != nil {
+		return nil, err
+	}
+	grants, err := s.repo.ListGrants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if grants == nil {
+		grants = []Grant{}
+	}
+	return grants, nil
+}
+
+// Revoke removes granteeID's access. The owner may revoke any grant and a
+// grantee may give up their own.
+func (s *Service) Revoke(ctx context.Context, id, userID, granteeID uuid.UUID) error {
+	need := AccessOwner
+	if granteeID == userID {
+		need = AccessRead
+	}
+	if _, err := s.authorize(ctx, id, userID, need); err != nil {
+		// An expired grant no longer resolves but may still be given up.
+		if !errors.Is(err, ErrNotFound) || granteeID != userID {
+			return err
+		}
+	}
+	removed, err := s.repo.DeleteGrant(ctx, id, granteeID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrGrantNotFound
+	}
+	return nil
+}
+
+// Shared lists live profiles other users shared with userID through
+// unexpired grants, most recently shared first. Each carries the caller's
+// access and its expiry.
+func (s *Service) Shared(ctx context.Context, userID uuid.UUID, limit, offset int) ([]Profile, int, error) {
+	profiles, total, err := s.repo.ListShared(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range profiles {
+		s.present(&profiles[i])
+	}
+	return profiles, total, nil
+}
@@ -0,0 +1,65 @@
+package profile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kidpech/api_free_demo/pkg/jsonpatch"
+)
+
+func TestGrantsResolveAccess(t *testing.T) {
+	repo, p := newPatchRepo()
+	reader, writer, stranger := uuid.New(), uuid.New(), uuid.New()
+	repo.grants = map[uuid.UUID]string{reader: AccessRead, writer: AccessWrite}
+	svc := NewService(repo)
+	ctx := context.Background()
+	rename := PatchDocument{Type: jsonpatch.MergePatchType, Body: []byte(`{"last_name":"Roe"}`)}
+
+	got, err := svc.Get(ctx, p.ID, reader)
+	require.NoError(t, err)
+	require.Equal(t, AccessRead, got.Access)
+	_, err = svc.Patch(ctx, p.ID, reader, rename)
+	require.ErrorIs(t, err, ErrForbidden)
+	_, err = svc.Get(ctx, p.ID, stranger)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	updated, err := svc.Patch(ctx, p.ID, writer, rename)
+	require.NoError(t, err)
+	require.Equal(t, "Roe", updated.LastName)
+	require.Equal(t, AccessWrite, updated.Access)
+	require.Equal(t, writer, repo.revisions[len(repo.revisions)-1].ActorID)
+
+	_, err = svc.Patch(ctx, p.ID, writer, PatchDocument{Type: jsonpatch.MergePatchType, Body: []byte(`{"visibility":"public"}`)})
+	require.ErrorIs(t, err, ErrForbidden)
+	public := VisibilityPublic
+	_, err = svc.Update(ctx, p.ID, writer, UpdateRequest{CreateRequest: CreateRequest{FirstName: "Jane", LastName: "Roe", Visibility: &public}, Version: updated.Version})
+	require.ErrorIs(t, err, ErrForbidden)
+	require.Equal(t, VisibilityPrivate, repo.profile.Visibility)
+
+	require.ErrorIs(t, svc.Delete(ctx, p.ID, writer, false, updated.Version), ErrForbidden)
+	_, err = svc.Grants(ctx, p.ID, writer)
+	require.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestShareRejectsInvalidGrantees(t *testing.T) {
+	repo, p := newPatchRepo()
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	_, err := svc.Share(ctx, p.ID, p.UserID, p.UserID, GrantRequest{Access: AccessRead})
+	require.ErrorIs(t, err, ErrInvalidGrant)
+	past := time.Now().Add(-time.Hour)
+	_, err = svc.Share(ctx, p.ID, p.UserID, uuid.New(), GrantRequest{Access: AccessWrite, ExpiresAt: &past})
+	require.ErrorIs(t, err, ErrInvalidGrant)
+	_, err = svc.Share(ctx, p.ID, p.UserID, uuid.New(), GrantRequest{Access: AccessOwner})
+	require.Error(t, err)
+
+	blocked := uuid.New()
+	svc = NewService(repo, WithRelations(&fakeRelations{owner: blocked, blocked: map[uuid.UUID]bool{p.UserID: true}}))
+	_, err = svc.Share(ctx, p.ID, p.UserID, blocked, GrantRequest{Access: AccessRead})
+	require.ErrorIs(t, err, ErrGranteeNotFound)
+}
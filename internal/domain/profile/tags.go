@@ -0,0 +1,219 @@
+package profile
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Tag limits.
+const (
+	MaxTagLength      = 50
+	MaxTagsPerProfile = 50
+)
+
+// Tag errors.
+var (
+	ErrInvalidTag  = fmt.Errorf("tags must be 1-%d characters and may not contain commas", MaxTagLength)
+	ErrTooManyTags = fmt.Errorf("profiles are limited to %d tags", MaxTagsPerProfile)
+)
+
+// AddTags attaches tags to a profile and returns it with its full tag set.
+func (s *Service) AddTags(ctx context.Context, id, userID uuid.UUID, req TagsRequest) (*Profile, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+	add, err := s.normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	return s.retag(ctx, id, userID, add, nil)
+}
+
+// RemoveTag detaches one tag from a profile. Removing a tag the profile
+// does not carry is not an error.
+func (s *Service) RemoveTag(ctx context.Context, id, userID uuid.UUID, tag string) (*Profile, error) {
+	remove, err := s.normalizeTags([]string{tag})
+	if err != nil {
+		return nil, err
+	}
+	return s.retag(ctx, id, userID, nil, remove)
+}
+
+// BulkTag adds and removes the same tags on each listed profile.
+func (s *Service) BulkTag(ctx context.Context, userID uuid.UUID, req BulkTagRequest) (*BulkReport, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+	add, err := s.normalizeTags(req.Add)
+	if err != nil {
+		return nil, err
+	}
+	remove, err := s.normalizeTags(req.Remove)
+	if err != nil {
+		return nil, err
+	}
+	return s.runBulk(ctx, req.Mode, len(req.IDs), req.IDs, BulkStatusUpdated, func(svc *Service, i int) (*Profile, error) {
+		return svc.retag(ctx, req.IDs[i], userID, add, remove)
+	})
+}
+
+// Tags lists the caller's tags with how many profiles carry each.
+func (s *Service) Tags(ctx context.Context, userID uuid.UUID) ([]TagCount, error) {
+	counts, err := s.repo.TagCounts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if counts == nil {
+		counts = []TagCount{}
+	}
+	return counts, nil
+}
+
+// retag applies removals then additions to a profile the user may write.
+// Tags are part of the representation, so a change bumps the version and
+// records a revision. The version check also stops concurrent retags,
+// which read the same tags, from overshooting MaxTagsPerProfile.
+func (s *Service) retag(ctx context.Context, id, userID uuid.UUID, add, remove []string) (*Profile, error) {
+	p, err := s.authorize(ctx, id, userID, AccessWrite)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.repo.ListTags(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	next := make(map[string]bool, len(current[id])+len(add))
+	for _, tag := range current[id] {
+		next[tag] = true
+	}
+	for _, tag := range remove {
+		delete(next, tag)
+	}
+	for _, tag := range add {
+		next[tag] = true
+	}
+	if len(next) > MaxTagsPerProfile {
+		return nil, ErrTooManyTags
+	}
+	tags := make([]string, 0, len(next))
+	for tag := range next {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	if sameTags(tags, current[id]) {
+		p.Tags = tags
+		return s.present(p), nil
+	}
+	var updated *Profile
+	err = s.transact(ctx, func(tx *Service) error {
+		var err error
+		if updated, err = tx.repo.Patch(ctx, id, p.UserID, map[string]interface{}{}, p.Version); err != nil {
+			return err
+		}
+		if len(remove) > 0 {
+			if err := tx.repo.RemoveTags(ctx, id, remove); err != nil {
+				return err
+			}
+		}
+		if len(add) > 0 {
+			if err := tx.repo.AddTags(ctx, id, add); err != nil {
+				return err
+			}
+		}
+		updated.Tags = tags
+		return tx.recordRevision(ctx, updated, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	updated.Access, updated.AccessExpiresAt = p.Access, p.AccessExpiresAt
+	return s.present(updated), nil
+}
+
+// attachTags fills in the tags of each profile with a single lookup.
+func (s *Service) attachTags(ctx context.Context, profiles []Profile) error {
+	if len(profiles) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(profiles))
+	for i := range profiles {
+		ids[i] = profiles[i].ID
+	}
+	tags, err := s.repo.ListTags(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range profiles {
+		profiles[i].Tags = tags[profiles[i].ID]
+	}
+	return nil
+}
+
+// normalizeTags sanitizes, lowercases and dedupes tags, collapsing inner
+// whitespace. Commas are rejected because list filters are comma
+// separated.
+func (s *Service) normalizeTags(raw []string) ([]string, error) {
+	seen := make(map[string]bool, len(raw))
+	out := make([]string, 0, len(raw))
+	for _, tag := range raw {
+		tag = strings.ToLower(strings.Join(strings.Fields(sanitizeField(s.sanitizer, tag)), " "))
+		if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength || strings.Contains(tag, ",") {
+			return nil, ErrInvalidTag
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	return out, nil
+}
+
+// tagFilter normalizes the tag part of a list filter.
+func (s *Service) tagFilter(filter *Filter) error {
+	switch filter.TagMatch {
+	case "":
+		filter.TagMatch = TagMatchAny
+	case TagMatchAny, TagMatchAll:
+	default:
+		return fmt.Errorf("%w: match must be any or all", ErrInvalidFilter)
+	}
+	tags, err := s.normalizeTags(filter.Tags)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	filter.Tags = tags
+	return nil
+}
+
+// sameTags reports whether a and b hold the same tags in any order.
+func sameTags(a, b []string) bool {
+	add, remove := tagChanges(a, b)
+	return len(add) == 0 && len(remove) == 0
+}
+
+// tagChanges returns the tags to add to and remove from current to reach
+// target.
+func tagChanges(current, target []string) (add, remove []string) {
+	have := make(map[string]bool, len(current))
+	for _, tag := range current {
+		have[tag] = true
+	}
+	want := make(map[string]bool, len(target))
+	for _, tag := range target {
+		want[tag] = true
+		if !have[tag] {
+			add = append(add, tag)
+		}
+	}
+	for _, tag := range current {
+		if !want[tag] {
+			remove = append(remove, tag)
+		}
+	}
+	return add, remove
+}
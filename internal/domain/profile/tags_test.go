@@ -0,0 +1,107 @@
+package profile
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddAndRemoveTags(t *testing.T) {
+	repo, p := newPatchRepo()
+	svc := NewService(repo)
+
+	updated, err := svc.AddTags(context.Background(), p.ID, p.UserID, TagsRequest{Tags: []string{" VIP ", "vip", "New   York"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"new york", "vip"}, updated.Tags)
+	require.Equal(t, 2, updated.Version)
+	require.Len(t, repo.revisions, 1)
+
+	// Tags already carried change nothing.
+	updated, err = svc.AddTags(context.Background(), p.ID, p.UserID, TagsRequest{Tags: []string{"vip"}})
+	require.NoError(t, err)
+	require.Equal(t, 2, updated.Version)
+	// Nor does a database collation listing them in another order.
+	repo.tags[p.ID] = []string{"vip", "new york"}
+	updated, err = svc.AddTags(context.Background(), p.ID, p.UserID, TagsRequest{Tags: []string{"new york"}})
+	require.NoError(t, err)
+	require.Equal(t, 2, updated.Version)
+	require.Len(t, repo.revisions, 1)
+
+	_, err = svc.AddTags(context.Background(), p.ID, p.UserID, TagsRequest{Tags: []string{"a,b"}})
+	require.ErrorIs(t, err, ErrInvalidTag)
+
+	updated, err = svc.RemoveTag(context.Background(), p.ID, p.UserID, "VIP")
+	require.NoError(t, err)
+	require.Equal(t, []string{"new york"}, updated.Tags)
+	require.Equal(t, 3, updated.Version)
+
+	got, err := svc.Get(context.Background(), p.ID, p.UserID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"new york"}, got.Tags)
+}
+
+func TestBulkTagReportsMissingProfiles(t *testing.T) {
+	repo, p := newPatchRepo()
+	svc := NewService(repo)
+	missing := uuid.New()
+
+	report, err := svc.BulkTag(context.Background(), p.UserID, BulkTagRequest{IDs: []uuid.UUID{p.ID, missing}, Add: []string{"team"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{BulkStatusUpdated, BulkStatusFailed}, bulkStatuses(report))
+	require.Equal(t, "not_found", report.Results[1].Code)
+	require.Equal(t, []string{"team"}, repo.tags[p.ID])
+}
+
+func TestListRejectsUnknownTagMatch(t *testing.T) {
+	repo, p := newPatchRepo()
+	svc := NewService(repo)
+
+	_, err := svc.List(context.Background(), Filter{UserID: p.UserID, Limit: 10, Tags: []string{"vip"}, TagMatch: "some"})
+	require.ErrorIs(t, err, ErrInvalidFilter)
+}
+
+func (r *patchRepo) AddTags(ctx context.Context, id uuid.UUID, tags []string) error {
+	if r.tags == nil {
+		r.tags = map[uuid.UUID][]string{}
+	}
+	for _, tag := range tags {
+		if !containsTag(r.tags[id], tag) {
+			r.tags[id] = append(r.tags[id], tag)
+		}
+	}
+	sort.Strings(r.tags[id])
+	return nil
+}
+
+func (r *patchRepo) RemoveTags(ctx context.Context, id uuid.UUID, tags []string) error {
+	kept := r.tags[id][:0]
+	for _, tag := range r.tags[id] {
+		if !containsTag(tags, tag) {
+			kept = append(kept, tag)
+		}
+	}
+	r.tags[id] = kept
+	return nil
+}
+
+func (r *patchRepo) ListTags(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]string, error) {
+	out := map[uuid.UUID][]string{}
+	for _, id := range ids {
+		if tags := r.tags[id]; len(tags) > 0 {
+			out[id] = append([]string(nil), tags...)
+		}
+	}
+	return out, nil
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
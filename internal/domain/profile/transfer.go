@@ -0,0 +1,447 @@
+package profile
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/pkg/csvcell"
+	"github.com/kidpech/api_free_demo/pkg/vcard"
+)
+
+// Import and export formats.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatVCard  = "vcf"
+)
+
+// MaxImportRows bounds a single import request.
+const MaxImportRows = 1000
+
+// exportBatch is how many streamed profiles share one tag lookup.
+const exportBatch = 100
+
+// Import errors. ErrInvalidImport rejects the whole file; ErrInvalidRow
+// marks a single record that could not be decoded.
+var (
+	ErrImportTooLarge = fmt.Errorf("imports are limited to %d rows", MaxImportRows)
+	ErrInvalidImport  = errors.New("invalid import")
+	ErrInvalidRow     = errors.New("invalid row")
+)
+
+// ImportRow is one decoded record of an import file.
+type ImportRow struct {
+	Line    int
+	Profile CreateRequest
+	Tags    []string
+	// Err is set when the record could not be decoded.
+	Err error
+}
+
+// exportColumns is the CSV header exports write. Imports accept the same
+// names and ignore the read-only id and timestamps.
+var exportColumns = []string{
+	"id", "handle", "first_name", "last_name", "bio", "date_of_birth", "phone", "website", "location",
+	"country", "latitude", "longitude", "profile_image", "cover_image", "visibility", "field_visibility", "custom_fields", "tags", "created_at", "updated_at",
+}
+
+var readOnlyColumns = map[string]bool{"id": true, "created_at": true, "updated_at": true}
+
+// Export calls fn for every profile the caller owns, oldest first, with
+// tags and signed image links filled in.
+func (s *Service) Export(ctx context.Context, userID uuid.UUID, fn func(*Profile) error) error {
+	batch := make([]Profile, 0, exportBatch)
+	flush := func() error {
+		if err := s.attachTags(ctx, batch); err != nil {
+			return err
+		}
+		for i := range batch {
+			if err := fn(s.present(&batch[i])); err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+	err := s.repo.Stream(ctx, userID, func(p *Profile) error {
+		batch = append(batch, *p)
+		if len(batch) < exportBatch {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// Import creates a profile per row through the same path as BulkCreate
+// and reports each row with its source line. Rows that failed to decode
+// are reported without being attempted.
+func (s *Service) Import(ctx context.Context, userID uuid.UUID, mode string, rows []ImportRow) (*BulkReport, error) {
+	if len(rows) > MaxImportRows {
+		return nil, ErrImportTooLarge
+	}
+	report, err := s.runItems(ctx, mode, len(rows), nil, BulkStatusCreated, func(svc *Service, i int) (*Profile, error) {
+		return svc.importRow(ctx, userID, rows[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range report.Results {
+		report.Results[i].Line = rows[i].Line
+	}
+	return report, nil
+}
+
+func (s *Service) importRow(ctx context.Context, userID uuid.UUID, row ImportRow) (*Profile, error) {
+	if row.Err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRow, row.Err)
+	}
+	tags, err := s.normalizeTags(row.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > MaxTagsPerProfile {
+		return nil, ErrTooManyTags
+	}
+	p, err := s.Create(ctx, userID, row.Profile)
+	if err != nil || len(tags) == 0 {
+		return p, err
+	}
+	return s.retag(ctx, p.ID, userID, tags, nil)
+}
+
+// ParseImport decodes a CSV (with a header row), NDJSON or vCard file.
+// mapping renames CSV header columns to profile fields; unmapped columns
+// are matched by name and unknown ones ignored. Records that cannot be
+// decoded come back with Err set so the report can point at them.
+func ParseImport(r io.Reader, format string, mapping map[string]string) ([]ImportRow, error) {
+	switch format {
+	case FormatCSV:
+		return parseImportCSV(r, mapping)
+	case FormatNDJSON:
+		return parseImportNDJSON(r)
+	case FormatVCard:
+		return parseImportVCard(r)
+	default:
+		return nil, fmt.Errorf("%w: format must be csv, ndjson or vcf", ErrInvalidImport)
+	}
+}
+
+func parseImportCSV(r io.Reader, mapping map[string]string) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing csv header", ErrInvalidImport)
+	}
+	known := make(map[string]bool, len(exportColumns))
+	for _, name := range exportColumns {
+		known[name] = true
+	}
+	columns := make([]string, len(header))
+	mapped := make(map[string]bool, len(mapping))
+	fields := 0
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		target, ok := mapping[name]
+		if ok {
+			mapped[name] = true
+			if !known[target] {
+				return nil, fmt.Errorf("%w: unknown target column %q", ErrInvalidImport, target)
+			}
+		} else {
+			target = strings.ToLower(name)
+		}
+		if known[target] && !readOnlyColumns[target] {
+			columns[i] = target
+			fields++
+		}
+	}
+	for name := range mapping {
+		if !mapped[name] {
+			return nil, fmt.Errorf("%w: mapped column %q is not in the header", ErrInvalidImport, name)
+		}
+	}
+	if fields == 0 {
+		return nil, fmt.Errorf("%w: csv header has no profile columns", ErrInvalidImport)
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if len(rows) >= MaxImportRows {
+			return nil, ErrImportTooLarge
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			if !errors.As(err, &perr) {
+				return nil, err
+			}
+			rows = append(rows, ImportRow{Line: perr.StartLine, Err: perr.Err})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		row := ImportRow{Line: line}
+		for i, value := range record {
+			if i >= len(columns) || columns[i] == "" {
+				continue
+			}
+			if err := row.set(columns[i], csvcell.Unescape(value)); err != nil {
+				row.Err = err
+				break
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// set assigns one CSV cell. Empty cells leave optional fields unset.
+func (row *ImportRow) set(column, value string) error {
+	value = strings.TrimSpace(value)
+	req := &row.Profile
+	optional := func(dst **string) {
+		if value != "" {
+			v := value
+			*dst = &v
+		}
+	}
+	switch column {
+	case "first_name":
+		req.FirstName = value
+	case "last_name":
+		req.LastName = value
+	case "handle":
+		optional(&req.Handle)
+	case "bio":
+		optional(&req.Bio)
+	case "date_of_birth":
+		optional(&req.DateOfBirth)
+	case "phone":
+		optional(&req.Phone)
+	case "website":
+		optional(&req.Website)
+	case "location":
+		optional(&req.Location)
+	case "country":
+		optional(&req.Country)
+	case "latitude", "longitude":
+		if value == "" {
+			return nil
+		}
+		coord, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number", column)
+		}
+		if column == "latitude" {
+			req.Latitude = &coord
+		} else {
+			req.Longitude = &coord
+		}
+	case "profile_image":
+		optional(&req.ProfileImage)
+	case "cover_image":
+		optional(&req.CoverImage)
+	case "visibility":
+		optional(&req.Visibility)
+	case "field_visibility":
+		if value != "" && json.Unmarshal([]byte(value), &req.FieldVisibility) != nil {
+			return fmt.Errorf("field_visibility must be a JSON object")
+		}
+	case "custom_fields":
+		if value != "" && json.Unmarshal([]byte(value), &req.CustomFields) != nil {
+			return fmt.Errorf("custom_fields must be a JSON object")
+		}
+	case "tags":
+		if value != "" {
+			row.Tags = strings.Split(value, ",")
+		}
+	}
+	return nil
+}
+
+// importRecord is the NDJSON shape: the create payload plus tags. Exported
+// profiles decode into it, so their extra members are ignored.
+type importRecord struct {
+	CreateRequest
+	Tags []string `json:"tags"`
+}
+
+func parseImportNDJSON(r io.Reader) ([]ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var rows []ImportRow
+	for line := 1; scanner.Scan(); line++ {
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+		if len(rows) >= MaxImportRows {
+			return nil, ErrImportTooLarge
+		}
+		var rec importRecord
+		row := ImportRow{Line: line}
+		if err := json.Unmarshal([]byte(raw), &rec); err != nil {
+			row.Err = err
+		}
+		row.Profile, row.Tags = rec.CreateRequest, rec.Tags
+		row.Profile.DateOfBirth = dateOnly(row.Profile.DateOfBirth)
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+func parseImportVCard(r io.Reader) ([]ImportRow, error) {
+	reader := vcard.NewReader(r)
+	var rows []ImportRow
+	for {
+		card, line, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if len(rows) >= MaxImportRows {
+			return nil, ErrImportTooLarge
+		}
+		if err != nil && !errors.Is(err, vcard.ErrInvalid) {
+			return nil, err
+		}
+		row := ImportRow{Line: line, Err: err}
+		if err == nil {
+			row.Profile, row.Tags = fromCard(card), card.Categories
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// fromCard maps contact properties onto a create payload, splitting the
+// formatted name when the card has no structured one.
+func fromCard(card vcard.Card) CreateRequest {
+	req := CreateRequest{FirstName: card.GivenName, LastName: card.FamilyName}
+	if req.FirstName == "" && req.LastName == "" {
+		name := strings.TrimSpace(card.FormattedName)
+		if i := strings.LastIndex(name, " "); i > 0 {
+			req.FirstName, req.LastName = name[:i], name[i+1:]
+		} else {
+			req.FirstName = name
+		}
+	}
+	optional := func(v string) *string {
+		if v = strings.TrimSpace(v); v == "" {
+			return nil
+		}
+		return &v
+	}
+	req.Bio = optional(card.Note)
+	req.DateOfBirth = optional(card.Birthday)
+	req.Phone = optional(card.Tel)
+	req.Website = optional(card.URL)
+	req.Location = optional(card.Locality)
+	req.ProfileImage = optional(card.Photo)
+	return req
+}
+
+// toCard renders the contact part of a profile as a vCard.
+func toCard(p *Profile) vcard.Card {
+	card := vcard.Card{
+		UID:        p.ID.String(),
+		GivenName:  p.FirstName,
+		FamilyName: p.LastName,
+		Categories: p.Tags,
+	}
+	deref := func(v *string) string {
+		if v == nil {
+			return ""
+		}
+		return *v
+	}
+	card.Note, card.Tel, card.URL = deref(p.Bio), deref(p.Phone), deref(p.Website)
+	card.Locality, card.Photo = deref(p.Location), deref(p.ProfileImage)
+	if p.DateOfBirth != nil {
+		card.Birthday = p.DateOfBirth.Format("2006-01-02")
+	}
+	return card
+}
+
+// exportRow renders a profile in exportColumns order, escaping cells a
+// spreadsheet would evaluate as formulas.
+func exportRow(p *Profile) []string {
+	deref := func(v *string) string {
+		if v == nil {
+			return ""
+		}
+		return *v
+	}
+	var dob string
+	if p.DateOfBirth != nil {
+		dob = p.DateOfBirth.Format("2006-01-02")
+	}
+	var fieldVis, custom string
+	if len(p.FieldVis) > 0 {
+		raw, _ := json.Marshal(p.FieldVis)
+		fieldVis = string(raw)
+	}
+	if len(p.CustomFields) > 0 {
+		raw, _ := json.Marshal(p.CustomFields)
+		custom = string(raw)
+	}
+	return csvcell.EscapeRow([]string{
+		p.ID.String(),
+		deref(p.Handle),
+		p.FirstName,
+		p.LastName,
+		deref(p.Bio),
+		dob,
+		deref(p.Phone),
+		deref(p.Website),
+		deref(p.Location),
+		deref(p.Country),
+		formatCoordinate(p.Latitude),
+		formatCoordinate(p.Longitude),
+		deref(p.ProfileImage),
+		deref(p.CoverImage),
+		p.Visibility,
+		fieldVis,
+		custom,
+		strings.Join(p.Tags, ","),
+		p.CreatedAt.UTC().Format(time.RFC3339),
+		p.UpdatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+func formatCoordinate(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+// dateOnly trims exported timestamps such as 1990-01-02T00:00:00Z to the
+// date create requests expect.
+func dateOnly(v *string) *string {
+	if v == nil {
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, *v); err == nil {
+		date := t.Format("2006-01-02")
+		return &date
+	}
+	return v
+}
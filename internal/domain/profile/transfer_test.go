@@ -0,0 +1,94 @@
+package profile
+
+import (
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImportCSVMapping(t *testing.T) {
+	input := "Given,Surname,Notes,id,Team\n" +
+		"Ada,Lovelace,Analyst,ignored,x\n" +
+		"\"Alan,Turing,\"unterminated\n"
+	rows, err := ParseImport(strings.NewReader(input), FormatCSV, map[string]string{"Given": "first_name", "Surname": "last_name", "Notes": "bio"})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, 2, rows[0].Line)
+	require.NoError(t, rows[0].Err)
+	require.Equal(t, "Ada", rows[0].Profile.FirstName)
+	require.Equal(t, "Lovelace", rows[0].Profile.LastName)
+	require.Equal(t, "Analyst", *rows[0].Profile.Bio)
+	require.Equal(t, 3, rows[1].Line)
+	require.Error(t, rows[1].Err)
+
+	_, err = ParseImport(strings.NewReader(input), FormatCSV, map[string]string{"Given": "nickname"})
+	require.ErrorIs(t, err, ErrInvalidImport)
+	_, err = ParseImport(strings.NewReader("a,b\n1,2\n"), FormatCSV, nil)
+	require.ErrorIs(t, err, ErrInvalidImport)
+}
+
+func TestExportEscapesFormulasForImport(t *testing.T) {
+	bio, lat := "@SUM(A1:A9)", -33.86
+	p := &Profile{ID: uuid.New(), FirstName: "=cmd|' /C calc'!A0", LastName: "-Lovelace", Bio: &bio, Latitude: &lat}
+	var out strings.Builder
+	w := csv.NewWriter(&out)
+	require.NoError(t, w.Write(exportColumns))
+	require.NoError(t, w.Write(exportRow(p)))
+	w.Flush()
+	require.Contains(t, out.String(), `,'=cmd|' /C calc'!A0,'-Lovelace,'@SUM(A1:A9),`)
+	require.Contains(t, out.String(), ",-33.86,")
+
+	rows, err := ParseImport(strings.NewReader(out.String()), FormatCSV, nil)
+	require.NoError(t, err)
+	require.NoError(t, rows[0].Err)
+	require.Equal(t, p.FirstName, rows[0].Profile.FirstName)
+	require.Equal(t, p.LastName, rows[0].Profile.LastName)
+	require.Equal(t, bio, *rows[0].Profile.Bio)
+}
+
+func TestParseImportNDJSONAndVCard(t *testing.T) {
+	input := `{"first_name":"Ada","last_name":"Lovelace","date_of_birth":"1815-12-10T00:00:00Z","tags":["math"]}
+
+{"first_name":
+`
+	rows, err := ParseImport(strings.NewReader(input), FormatNDJSON, nil)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, "1815-12-10", *rows[0].Profile.DateOfBirth)
+	require.Equal(t, []string{"math"}, rows[0].Tags)
+	require.Equal(t, 3, rows[1].Line)
+	require.Error(t, rows[1].Err)
+
+	card := "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Grace Hopper\r\nBDAY:19061209\r\nCATEGORIES:navy,cobol\r\nEND:VCARD\r\n"
+	rows, err = ParseImport(strings.NewReader(card), FormatVCard, nil)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, "Grace", rows[0].Profile.FirstName)
+	require.Equal(t, "Hopper", rows[0].Profile.LastName)
+	require.Equal(t, "1906-12-09", *rows[0].Profile.DateOfBirth)
+	require.Equal(t, []string{"navy", "cobol"}, rows[0].Tags)
+}
+
+func TestImportReportsRowsByLine(t *testing.T) {
+	repo := &bulkRepo{profiles: map[uuid.UUID]Profile{}}
+	svc := NewService(repo)
+	input := "first_name,last_name,website\nAda,Lovelace,\nAlan,,\nGrace,Hopper,not a url\n"
+	rows, err := ParseImport(strings.NewReader(input), FormatCSV, nil)
+	require.NoError(t, err)
+	rows = append(rows, ImportRow{Line: 5, Err: ErrInvalidImport})
+
+	report, err := svc.Import(context.Background(), uuid.New(), "", rows)
+	require.NoError(t, err)
+	require.Equal(t, []string{BulkStatusCreated, BulkStatusFailed, BulkStatusFailed, BulkStatusFailed}, bulkStatuses(report))
+	require.Equal(t, []int{2, 3, 4, 5}, []int{report.Results[0].Line, report.Results[1].Line, report.Results[2].Line, report.Results[3].Line})
+	require.Equal(t, "validation_error", report.Results[1].Code)
+	require.Equal(t, "invalid_row", report.Results[3].Code)
+	require.Len(t, repo.profiles, 1)
+
+	_, err = svc.Import(context.Background(), uuid.New(), "", make([]ImportRow, MaxImportRows+1))
+	require.ErrorIs(t, err, ErrImportTooLarge)
+}
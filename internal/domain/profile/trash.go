@@ -0,0 +1,84 @@
+package profile
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Trash lists the caller's soft-deleted profiles, most recently deleted
+// first.
+func (s *Service) Trash(ctx context.Context, userID uuid.UUID, limit, offset int) ([]Profile, int, error) {
+	profiles, total, err := s.repo.ListTrash(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range profiles {
+		s.present(&profiles[i])
+	}
+	return profiles, total, nil
+}
+
+// Restore takes a profile out of the trash as a new version. A nil expected
+// version restores whatever is stored.
+func (s *Service) Restore(ctx context.Context, id, userID uuid.UUID, expected *int) (*Profile, error) {
+	deleted, err := s.repo.GetDeleted(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if deleted == nil {
+		return nil, ErrNotFound
+	}
+	if expected != nil && *expected != deleted.Version {
+		return nil, ErrVersionConflict
+	}
+	var restored *Profile
+	err = s.transact(ctx, func(tx *Service) error {
+		var err error
+		if restored, err = tx.repo.Restore(ctx, id, userID, deleted.Version); err != nil {
+			return err
+		}
+		return tx.recordRevision(ctx, restored, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.present(restored), nil
+}
+
+// PurgeTrash hard-deletes profiles that have been in the trash longer than
+// retention and discards their images.
+func (s *Service) PurgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	purged, keys, err := s.repo.PurgeDeleted(ctx, time.Now().UTC().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	s.discardImages(ctx, stringPointers(keys)...)
+	return purged, nil
+}
+
+// RunPurger calls PurgeTrash every interval until ctx is done. It is a no-op
+// when retention or interval is not positive.
+func (s *Service) RunPurger(ctx context.Context, retention, interval time.Duration, logger *zap.Logger) {
+	if retention <= 0 || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := s.PurgeTrash(ctx, retention)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			logger.Warn("profile trash purge failed", zap.Error(err))
+		case purged > 0:
+			logger.Info("purged profile trash", zap.Int("profiles", purged))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
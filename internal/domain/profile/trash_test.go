@@ -0,0 +1,78 @@
+package profile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreFromTrash(t *testing.T) {
+	repo, p := newPatchRepo()
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	_, err := svc.Restore(ctx, p.ID, p.UserID, nil)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	deletedAt := time.Now().UTC()
+	p.DeletedAt, p.Version = &deletedAt, 2
+	stale := 1
+	_, err = svc.Restore(ctx, p.ID, p.UserID, &stale)
+	require.ErrorIs(t, err, ErrVersionConflict)
+
+	restored, err := svc.Restore(ctx, p.ID, p.UserID, nil)
+	require.NoError(t, err)
+	require.Nil(t, restored.DeletedAt)
+	require.Equal(t, 3, restored.Version)
+	require.Len(t, repo.revisions, 1)
+	require.Equal(t, 3, repo.revisions[0].Version)
+}
+
+func TestPurgeTrashUsesRetentionCutoff(t *testing.T) {
+	repo, _ := newPatchRepo()
+	repo.purgedKeys = []string{"profile/a.png", "cover/b.png"}
+	images := &discardStore{}
+	svc := NewService(repo, WithImages(images))
+
+	_, err := svc.PurgeTrash(context.Background(), 48*time.Hour)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(-48*time.Hour), repo.purgedBefore, time.Minute)
+	require.Equal(t, repo.purgedKeys, images.deleted)
+}
+
+// discardStore records the keys it is asked to delete.
+type discardStore struct {
+	ImageStore
+	deleted []string
+}
+
+func (d *discardStore) Delete(ctx context.Context, key string) error {
+	d.deleted = append(d.deleted, key)
+	return nil
+}
+
+func (r *patchRepo) GetDeleted(ctx context.Context, id, userID uuid.UUID) (*Profile, error) {
+	if id != r.profile.ID || userID != r.profile.UserID || r.profile.DeletedAt == nil {
+		return nil, ErrNotFound
+	}
+	p := r.profile
+	return &p, nil
+}
+
+func (r *patchRepo) Restore(ctx context.Context, id, userID uuid.UUID, version int) (*Profile, error) {
+	if version != r.profile.Version {
+		return nil, ErrVersionConflict
+	}
+	r.profile.DeletedAt = nil
+	r.profile.Version++
+	p := r.profile
+	return &p, nil
+}
+
+func (r *patchRepo) PurgeDeleted(ctx context.Context, before time.Time) (int, []string, error) {
+	r.purgedBefore = before
+	return 0, r.purgedKeys, nil
+}
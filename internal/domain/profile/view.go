@@ -0,0 +1,199 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/internal/domain/user"
+)
+
+// ExpandUser embeds the owning user via ?expand=user.
+const ExpandUser = "user"
+
+// View errors.
+var (
+	ErrInvalidFields = errors.New("invalid fields")
+	ErrInvalidExpand = errors.New("invalid expand")
+)
+
+// OwnerDirectory batch-loads the users embedded by ?expand=user.
+type OwnerDirectory interface {
+	Summaries(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]user.Summary, error)
+}
+
+// WithOwners enables ?expand=user.
+func WithOwners(owners OwnerDirectory) Option {
+	return func(s *Service) {
+		s.owners = owners
+	}
+}
+
+// fieldColumns maps each response member ?fields= may name onto the columns
+// it is rendered from. Image links are signed from their storage keys,
+// snippets are highlighted from the searched text, and tags, scores,
+// distances and the user expansion need no profile columns.
+var fieldColumns = map[string][]string{
+	"id":                  {"id"},
+	"user_id":             {"user_id"},
+	"tenant_id":           {"tenant_id"},
+	"handle":              {"handle"},
+	"visibility":          {"visibility"},
+	"field_visibility":    {"field_visibility"},
+	"first_name":          {"first_name"},
+	"last_name":           {"last_name"},
+	"bio":                 {"bio"},
+	"profile_image":       {"profile_image", "profile_image_key"},
+	"profile_image_thumb": {"profile_image", "profile_image_key"},
+	"cover_image":         {"cover_image", "cover_image_key"},
+	"cover_image_thumb":   {"cover_image", "cover_image_key"},
+	"date_of_birth":       {"date_of_birth"},
+	"phone":               {"phone"},
+	"phone_e164":          {"phone_e164"},
+	"website":             {"website"},
+	"location":            {"location"},
+	"country":             {"country"},
+	"latitude":            {"latitude"},
+	"longitude":           {"longitude"},
+	"custom_fields":       {"custom_fields"},
+	"created_at":          {"created_at"},
+	"updated_at":          {"updated_at"},
+	"deleted_at":          {"deleted_at"},
+	"version":             {"version"},
+	"snippet":             {"first_name", "last_name", "bio", "location"},
+	"tags":                nil,
+	"score":               nil,
+	"distance_km":         nil,
+	ExpandUser:            nil,
+}
+
+// keyColumns are selected whatever the fieldset: id and created_at drive
+// the keyset cursor and tag lookups, user_id the owner expansion.
+var keyColumns = []string{"id", "user_id", "created_at"}
+
+// fieldsetColumns validates a fieldset and returns the sorted columns the
+// repository needs to render it, or nil for every column.
+func fieldsetColumns(fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	set := make(map[string]bool, len(fields)+len(keyColumns))
+	for _, column := range keyColumns {
+		set[column] = true
+	}
+	for _, field := range fields {
+		columns, ok := fieldColumns[field]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidFields, field)
+		}
+		for _, column := range columns {
+			set[column] = true
+		}
+	}
+	out := make([]string, 0, len(set))
+	for column := range set {
+		out = append(out, column)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// hasField reports whether a fieldset renders field; empty fieldsets
+// render everything.
+func hasField(fields []string, field string) bool {
+	if len(fields) == 0 {
+		return true
+	}
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand embeds the named relations into profiles, loading each relation
+// once for the whole set.
+func (s *Service) Expand(ctx context.Context, relations []string, profiles ...*Profile) error {
+	for _, relation := range relations {
+		if relation != ExpandUser || s.owners == nil {
+			return fmt.Errorf("%w: profiles can expand %s", ErrInvalidExpand, ExpandUser)
+		}
+	}
+	if len(relations) == 0 || len(profiles) == 0 {
+		return nil
+	}
+	seen := make(map[uuid.UUID]bool, 1)
+	ids := make([]uuid.UUID, 0, 1)
+	for _, p := range profiles {
+		if !seen[p.UserID] {
+			seen[p.UserID] = true
+			ids = append(ids, p.UserID)
+		}
+	}
+	owners, err := s.owners.Summaries(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, p := range profiles {
+		if owner, ok := owners[p.UserID]; ok {
+			p.User = &owner
+		}
+	}
+	return nil
+}
+
+// ProfileSummaries lists a user's live profiles for embedding in the user,
+// oldest first.
+func (s *Service) ProfileSummaries(ctx context.Context, userID uuid.UUID) ([]user.ProfileSummary, error) {
+	var out []user.ProfileSummary
+	err := s.repo.Stream(ctx, userID, func(p *Profile) error {
+		s.present(p)
+		out = append(out, user.ProfileSummary{
+			ID:           p.ID,
+			Handle:       p.Handle,
+			FirstName:    p.FirstName,
+			LastName:     p.LastName,
+			Visibility:   p.Visibility,
+			ProfileImage: p.ProfileImage,
+			ProfileThumb: p.ProfileThumb,
+		})
+		return nil
+	})
+	return out, err
+}
+
+// project renders profiles keeping only the named members; members a
+// profile omits come back as null so clients always see what they asked
+// for. An empty fieldset renders profiles unchanged.
+func project(fields []string, profiles ...*Profile) ([]interface{}, error) {
+	out := make([]interface{}, len(profiles))
+	for i, p := range profiles {
+		if len(fields) == 0 {
+			out[i] = p
+			continue
+		}
+		raw, err := json.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		var members map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &members); err != nil {
+			return nil, err
+		}
+		projected := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := members[field]; ok {
+				projected[field] = value
+			} else {
+				projected[field] = json.RawMessage("null")
+			}
+		}
+		out[i] = projected
+	}
+	return out, nil
+}
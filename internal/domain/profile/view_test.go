@@ -0,0 +1,66 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kidpech/api_free_demo/internal/domain/user"
+)
+
+func TestFieldsetColumns(t *testing.T) {
+	columns, err := fieldsetColumns([]string{"first_name", "profile_image", "tags"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"created_at", "first_name", "id", "profile_image", "profile_image_key", "user_id"}, columns)
+
+	columns, err = fieldsetColumns(nil)
+	require.NoError(t, err)
+	require.Nil(t, columns)
+
+	_, err = fieldsetColumns([]string{"id", "password_hash"})
+	require.ErrorIs(t, err, ErrInvalidFields)
+}
+
+func TestProjectKeepsRequestedMembers(t *testing.T) {
+	p := &Profile{ID: uuid.New(), FirstName: "Ada", LastName: "Lovelace", Version: 3}
+	out, err := project([]string{"id", "first_name", "bio"}, p)
+	require.NoError(t, err)
+	raw, err := json.Marshal(out[0])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":"`+p.ID.String()+`","first_name":"Ada","bio":null}`, string(raw))
+
+	out, err = project(nil, p)
+	require.NoError(t, err)
+	require.Same(t, p, out[0])
+}
+
+func TestExpandLoadsOwnersOnce(t *testing.T) {
+	owners := &fakeOwners{}
+	svc := NewService(&bulkRepo{profiles: map[uuid.UUID]Profile{}}, WithOwners(owners))
+	ada, alan := uuid.New(), uuid.New()
+	owners.summaries = map[uuid.UUID]user.Summary{ada: {ID: ada, Name: "Ada"}}
+	profiles := []*Profile{{UserID: ada}, {UserID: ada}, {UserID: alan}}
+
+	require.NoError(t, svc.Expand(context.Background(), []string{ExpandUser}, profiles...))
+	require.Len(t, owners.calls, 1)
+	require.ElementsMatch(t, []uuid.UUID{ada, alan}, owners.calls[0])
+	require.Equal(t, "Ada", profiles[0].User.Name)
+	require.Equal(t, "Ada", profiles[1].User.Name)
+	require.Nil(t, profiles[2].User)
+
+	require.ErrorIs(t, svc.Expand(context.Background(), []string{"tenant"}, profiles...), ErrInvalidExpand)
+	require.ErrorIs(t, NewService(&bulkRepo{}).Expand(context.Background(), []string{ExpandUser}), ErrInvalidExpand)
+}
+
+type fakeOwners struct {
+	summaries map[uuid.UUID]user.Summary
+	calls     [][]uuid.UUID
+}
+
+func (f *fakeOwners) Summaries(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]user.Summary, error) {
+	f.calls = append(f.calls, ids)
+	return f.summaries, nil
+}
@@ -0,0 +1,121 @@
+package settings
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/pkg/response"
+)
+
+// Handler exposes settings endpoints.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler returns a settings Handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes mounts user and tenant-admin settings routes.
+func (h *Handler) RegisterRoutes(rg *gin.RouterGroup, authMW gin.HandlerFunc, adminMW gin.HandlerFunc) {
+	me := rg.Group("/users/me/settings", authMW)
+	{
+		me.GET("", h.get)
+		me.PATCH("", h.patch)
+		me.GET("/schema", h.schema)
+	}
+
+	admin := rg.Group("/admin/tenants/:id/settings", authMW, adminMW)
+	{
+		admin.GET("", h.getTenantDefaults)
+		admin.PUT("", h.putTenantDefaults)
+	}
+}
+
+func (h *Handler) get(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	values, err := h.service.Get(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, values)
+}
+
+func (h *Handler) patch(c *gin.Context) {
+	var changes map[string]interface{}
+	if err := c.ShouldBindJSON(&changes); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	values, err := h.service.Patch(c.Request.Context(), userID, changes)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, values)
+}
+
+func (h *Handler) schema(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.service.Registry().Definitions()})
+}
+
+func (h *Handler) getTenantDefaults(c *gin.Context) {
+	tenantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "tenant")
+		return
+	}
+	values, err := h.service.TenantDefaults(c.Request.Context(), tenantID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, values)
+}
+
+func (h *Handler) putTenantDefaults(c *gin.Context) {
+	var defaults map[string]interface{}
+	if err := c.ShouldBindJSON(&defaults); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	tenantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "tenant")
+		return
+	}
+	values, err := h.service.SetTenantDefaults(c.Request.Context(), tenantID, defaults)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, values)
+}
+
+func (h *Handler) handleError(c *gin.Context, err error) {
+	var verr *ValidationError
+	switch {
+	case errors.As(err, &verr):
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "invalid_settings",
+			Message: "one or more settings are invalid",
+			Details: verr.Fields,
+		})
+	case errors.Is(err, ErrTenantNotFound):
+		response.NotFound(c, "tenant")
+	default:
+		response.InternalServerError(c, err)
+	}
+}
@@ -0,0 +1,178 @@
+package settings
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Value types supported by the registry.
+const (
+	TypeString = "string"
+	TypeBool   = "bool"
+	TypeInt    = "int"
+	TypeEnum   = "enum"
+)
+
+// Definition describes a known settings key.
+type Definition struct {
+	Key         string      `json:"key"`
+	Type        string      `json:"type"`
+	Default     interface{} `json:"default"`
+	Enum        []string    `json:"enum,omitempty"`
+	Min         *int        `json:"min,omitempty"`
+	Max         *int        `json:"max,omitempty"`
+	Description string      `json:"description,omitempty"`
+	// Check adds key-specific validation on top of the type check.
+	Check func(interface{}) error `json:"-"`
+}
+
+// Registry holds the server-side catalogue of settings keys.
+type Registry struct {
+	defs map[string]Definition
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{defs: make(map[string]Definition)}
+}
+
+// DefaultRegistry returns the registry with the built-in preference keys.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(Definition{
+		Key:         "locale",
+		Type:        TypeString,
+		Default:     "en",
+		Description: "BCP 47 language tag, e.g. en or th-TH",
+		Check:       matchPattern(regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`), "a language tag like en or th-TH"),
+	})
+	r.Register(Definition{
+		Key:         "timezone",
+		Type:        TypeString,
+		Default:     "UTC",
+		Description: "IANA time zone name",
+		Check: func(v interface{}) error {
+			if _, err := time.LoadLocation(v.(string)); err != nil {
+				return fmt.Errorf("unknown time zone")
+			}
+			return nil
+		},
+	})
+	r.Register(Definition{Key: "notifications.email", Type: TypeBool, Default: true, Description: "Transactional email opt-in"})
+	r.Register(Definition{Key: "notifications.push", Type: TypeBool, Default: false, Description: "Push notification opt-in"})
+	r.Register(Definition{Key: "notifications.marketing", Type: TypeBool, Default: false, Description: "Marketing email opt-in"})
+	r.Register(Definition{Key: "ui.theme", Type: TypeEnum, Default: "system", Enum: []string{"light", "dark", "system"}})
+	r.Register(Definition{
+		Key:         "profiles.revision_retention",
+		Type:        TypeInt,
+		Default:     50,
+		Min:         IntPtr(1),
+		Max:         IntPtr(500),
+		Description: "Number of revisions kept per profile",
+	})
+	return r
+}
+
+// Register adds or replaces a definition.
+func (r *Registry) Register(def Definition) {
+	r.defs[def.Key] = def
+}
+
+// Lookup returns the definition for key.
+func (r *Registry) Lookup(key string) (Definition, bool) {
+	def, ok := r.defs[key]
+	return def, ok
+}
+
+// Definitions returns every definition sorted by key.
+func (r *Registry) Definitions() []Definition {
+	out := make([]Definition, 0, len(r.defs))
+	for _, def := range r.defs {
+		out = append(out, def)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// Defaults returns the registry defaults keyed by setting.
+func (r *Registry) Defaults() map[string]interface{} {
+	out := make(map[string]interface{}, len(r.defs))
+	for key, def := range r.defs {
+		out[key] = def.Default
+	}
+	return out
+}
+
+// Normalize validates a JSON-decoded value against its definition and
+// returns it in canonical Go form (ints come back as int).
+func (d Definition) Normalize(value interface{}) (interface{}, error) {
+	switch d.Type {
+	case TypeBool:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("must be a boolean")
+		}
+		return b, d.check(b)
+	case TypeInt:
+		var n int
+		switch v := value.(type) {
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, fmt.Errorf("must be an integer")
+			}
+			n = int(v)
+		case int:
+			n = v
+		default:
+			return nil, fmt.Errorf("must be an integer")
+		}
+		if d.Min != nil && n < *d.Min {
+			return nil, fmt.Errorf("must be at least %d", *d.Min)
+		}
+		if d.Max != nil && n > *d.Max {
+			return nil, fmt.Errorf("must be at most %d", *d.Max)
+		}
+		return n, d.check(n)
+	case TypeEnum:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		for _, allowed := range d.Enum {
+			if s == allowed {
+				return s, d.check(s)
+			}
+		}
+		return nil, fmt.Errorf("must be one of %v", d.Enum)
+	default:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		return s, d.check(s)
+	}
+}
+
+func (d Definition) check(v interface{}) error {
+	if d.Check == nil {
+		return nil
+	}
+	return d.Check(v)
+}
+
+func matchPattern(re *regexp.Regexp, hint string) func(interface{}) error {
+	return func(v interface{}) error {
+		if !re.MatchString(v.(string)) {
+			return fmt.Errorf("must be %s", hint)
+		}
+		return nil
+	}
+}
+
+// IntPtr is a helper for Definition.Min/Max literals.
+func IntPtr(v int) *int {
+	return &v
+}
@@ -0,0 +1,15 @@
+package settings
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Repository persists per-user values and per-tenant defaults as JSON documents.
+type Repository interface {
+	GetUser(ctx context.Context, userID uuid.UUID) (map[string]interface{}, error)
+	SaveUser(ctx context.Context, userID uuid.UUID, values map[string]interface{}) error
+	GetTenantDefaults(ctx context.Context, tenantID uuid.UUID) (map[string]interface{}, error)
+	SaveTenantDefaults(ctx context.Context, tenantID uuid.UUID, values map[string]interface{}) error
+}
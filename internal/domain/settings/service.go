@@ -0,0 +1,164 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/pkg/tenancy"
+)
+
+// Sentinel errors for HTTP mapping.
+var (
+	ErrInvalidSettings = errors.New("invalid settings")
+	ErrTenantNotFound  = errors.New("tenant not found")
+)
+
+// ValidationError lists the offending keys and why they were rejected.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return fmt.Sprintf("invalid settings: %s", strings.Join(keys, ", "))
+}
+
+// Unwrap lets errors.Is match ErrInvalidSettings.
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidSettings
+}
+
+// Service resolves settings as registry defaults, then tenant defaults,
+// then the user's own values.
+type Service struct {
+	repo     Repository
+	registry *Registry
+}
+
+// NewService wires a settings Service.
+func NewService(repo Repository, registry *Registry) *Service {
+	return &Service{repo: repo, registry: registry}
+}
+
+// Registry exposes the key catalogue.
+func (s *Service) Registry() *Registry {
+	return s.registry
+}
+
+// Get returns the effective settings for a user in the active tenant.
+func (s *Service) Get(ctx context.Context, userID uuid.UUID) (map[string]interface{}, error) {
+	resolved := s.registry.Defaults()
+	if tenantID, ok := tenancy.FromContext(ctx); ok {
+		defaults, err := s.repo.GetTenantDefaults(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		s.overlay(resolved, defaults)
+	}
+	values, err := s.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.overlay(resolved, values)
+	return resolved, nil
+}
+
+// Value returns one effective setting.
+func (s *Service) Value(ctx context.Context, userID uuid.UUID, key string) (interface{}, error) {
+	resolved, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return resolved[key], nil
+}
+
+// Patch merges changes into the user's stored values. A null value resets
+// the key to its default.
+func (s *Service) Patch(ctx context.Context, userID uuid.UUID, changes map[string]interface{}) (map[string]interface{}, error) {
+	values, err := s.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	if err := s.apply(values, changes); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveUser(ctx, userID, values); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, userID)
+}
+
+// TenantDefaults returns the stored defaults of a tenant.
+func (s *Service) TenantDefaults(ctx context.Context, tenantID uuid.UUID) (map[string]interface{}, error) {
+	values, err := s.repo.GetTenantDefaults(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	return values, nil
+}
+
+// SetTenantDefaults replaces the defaults of a tenant.
+func (s *Service) SetTenantDefaults(ctx context.Context, tenantID uuid.UUID, defaults map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(defaults))
+	if err := s.apply(values, defaults); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveTenantDefaults(ctx, tenantID, values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (s *Service) apply(values, changes map[string]interface{}) error {
+	problems := make(map[string]string)
+	for key, raw := range changes {
+		def, ok := s.registry.Lookup(key)
+		if !ok {
+			problems[key] = "unknown setting"
+			continue
+		}
+		if raw == nil {
+			delete(values, key)
+			continue
+		}
+		val, err := def.Normalize(raw)
+		if err != nil {
+			problems[key] = err.Error()
+			continue
+		}
+		values[key] = val
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Fields: problems}
+	}
+	return nil
+}
+
+// overlay copies stored values that still pass validation; keys removed
+// from the registry or no longer valid fall back silently.
+func (s *Service) overlay(dst, src map[string]interface{}) {
+	for key, raw := range src {
+		def, ok := s.registry.Lookup(key)
+		if !ok {
+			continue
+		}
+		if val, err := def.Normalize(raw); err == nil {
+			dst[key] = val
+		}
+	}
+}
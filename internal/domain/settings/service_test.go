@@ -0,0 +1,88 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kidpech/api_free_demo/pkg/tenancy"
+)
+
+func TestGetLayersTenantAndUserValues(t *testing.T) {
+	repo := newFakeRepo()
+	service := NewService(repo, DefaultRegistry())
+	tenantID, userID := uuid.New(), uuid.New()
+	repo.tenants[tenantID] = map[string]interface{}{"ui.theme": "dark", "locale": "th-TH"}
+	repo.users[userID] = map[string]interface{}{"locale": "en"}
+
+	values, err := service.Get(tenancy.WithTenant(context.Background(), tenantID), userID)
+
+	require.NoError(t, err)
+	require.Equal(t, "dark", values["ui.theme"])
+	require.Equal(t, "en", values["locale"])
+	require.Equal(t, "UTC", values["timezone"])
+}
+
+func TestPatchValidatesAndResets(t *testing.T) {
+	repo := newFakeRepo()
+	service := NewService(repo, DefaultRegistry())
+	userID := uuid.New()
+
+	_, err := service.Patch(context.Background(), userID, map[string]interface{}{
+		"ui.theme":     "neon",
+		"timezone":     "Mars/Olympus",
+		"unknown.flag": true,
+	})
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr))
+	require.Len(t, verr.Fields, 3)
+
+	values, err := service.Patch(context.Background(), userID, map[string]interface{}{"timezone": "Asia/Bangkok"})
+	require.NoError(t, err)
+	require.Equal(t, "Asia/Bangkok", values["timezone"])
+
+	values, err = service.Patch(context.Background(), userID, map[string]interface{}{"timezone": nil})
+	require.NoError(t, err)
+	require.Equal(t, "UTC", values["timezone"])
+}
+
+type fakeRepo struct {
+	users   map[uuid.UUID]map[string]interface{}
+	tenants map[uuid.UUID]map[string]interface{}
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{
+		users:   make(map[uuid.UUID]map[string]interface{}),
+		tenants: make(map[uuid.UUID]map[string]interface{}),
+	}
+}
+
+func (f *fakeRepo) GetUser(ctx context.Context, userID uuid.UUID) (map[string]interface{}, error) {
+	return clone(f.users[userID]), nil
+}
+
+func (f *fakeRepo) SaveUser(ctx context.Context, userID uuid.UUID, values map[string]interface{}) error {
+	f.users[userID] = clone(values)
+	return nil
+}
+
+func (f *fakeRepo) GetTenantDefaults(ctx context.Context, tenantID uuid.UUID) (map[string]interface{}, error) {
+	return clone(f.tenants[tenantID]), nil
+}
+
+func (f *fakeRepo) SaveTenantDefaults(ctx context.Context, tenantID uuid.UUID, values map[string]interface{}) error {
+	f.tenants[tenantID] = clone(values)
+	return nil
+}
+
+func clone(in map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,242 @@
+package social
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+	"github.com/kidpech/api_free_demo/pkg/response"
+)
+
+// Handler exposes follow, connection and block endpoints.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler returns a social Handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes mounts graph routes. :id accepts "me" for the caller.
+func (h *Handler) RegisterRoutes(rg *gin.RouterGroup, authMW gin.HandlerFunc) {
+	users := rg.Group("/users", authMW)
+	{
+		users.GET("/me/connection-requests", h.listRequests)
+		users.POST("/me/connection-requests/:id/accept", h.accept)
+		users.POST("/me/connection-requests/:id/reject", h.reject)
+		users.GET("/me/blocks", h.listBlocks)
+
+		users.GET("/:id/followers", h.list(KindFollowers))
+		users.GET("/:id/following", h.list(KindFollowing))
+		users.GET("/:id/connections", h.list(KindConnections))
+		users.POST("/:id/follow", h.follow)
+		users.DELETE("/:id/follow", h.unfollow)
+		users.POST("/:id/connection", h.requestConnection)
+		users.DELETE("/:id/connection", h.disconnect)
+		users.POST("/:id/block", h.block)
+		users.DELETE("/:id/block", h.unblock)
+	}
+}
+
+// params returns the caller and the user named by :id.
+func params(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return uuid.Nil, uuid.Nil, false
+	}
+	if c.Param("id") == "me" {
+		return userID, userID, true
+	}
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "user")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, targetID, true
+}
+
+func (h *Handler) follow(c *gin.Context) {
+	userID, targetID, ok := params(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Follow(c.Request.Context(), userID, targetID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) unfollow(c *gin.Context) {
+	userID, targetID, ok := params(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Unfollow(c.Request.Context(), userID, targetID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) requestConnection(c *gin.Context) {
+	userID, targetID, ok := params(c)
+	if !ok {
+		return
+	}
+	conn, err := h.service.RequestConnection(c.Request.Context(), userID, targetID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	status := http.StatusCreated
+	if conn.Status == StatusAccepted {
+		status = http.StatusOK
+	}
+	c.JSON(status, conn)
+}
+
+func (h *Handler) disconnect(c *gin.Context) {
+	userID, otherID, ok := params(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Disconnect(c.Request.Context(), userID, otherID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) accept(c *gin.Context) {
+	userID, requesterID, ok := params(c)
+	if !ok {
+		return
+	}
+	conn, err := h.service.Accept(c.Request.Context(), userID, requesterID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, conn)
+}
+
+func (h *Handler) reject(c *gin.Context) {
+	userID, requesterID, ok := params(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Reject(c.Request.Context(), userID, requesterID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) block(c *gin.Context) {
+	userID, targetID, ok := params(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Block(c.Request.Context(), userID, targetID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) unblock(c *gin.Context) {
+	userID, targetID, ok := params(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Unblock(c.Request.Context(), userID, targetID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) list(kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		viewer, userID, ok := params(c)
+		if !ok {
+			return
+		}
+		h.respondPage(c, Filter{Kind: kind, UserID: userID, Viewer: viewer})
+	}
+}
+
+func (h *Handler) listRequests(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	kind := KindIncoming
+	switch c.DefaultQuery("direction", "incoming") {
+	case "incoming":
+	case "outgoing":
+		kind = KindOutgoing
+	default:
+		response.BadRequest(c, "invalid_direction", "direction must be incoming or outgoing")
+		return
+	}
+	h.respondPage(c, Filter{Kind: kind, UserID: userID, Viewer: userID})
+}
+
+func (h *Handler) listBlocks(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	// Blocked users are exactly what this listing shows, so no viewer.
+	h.respondPage(c, Filter{Kind: KindBlocks, UserID: userID})
+}
+
+func (h *Handler) respondPage(c *gin.Context, filter Filter) {
+	var err error
+	filter.Limit = response.GetLimit(c, 20, 100)
+	if filter.Cursor, err = cursor.Decode(c.Query("cursor")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	page, err := h.service.List(c.Request.Context(), filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":        page.Entries,
+		"limit":       filter.Limit,
+		"next_cursor": page.NextCursor,
+		"prev_cursor": page.PrevCursor,
+		"has_more":    page.HasMore,
+	})
+}
+
+func (h *Handler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		response.NotFound(c, "user")
+	case errors.Is(err, ErrRequestNotFound):
+		response.NotFound(c, "connection request")
+	case errors.Is(err, ErrConnectionNotFound):
+		response.NotFound(c, "connection")
+	case errors.Is(err, ErrSelf):
+		response.BadRequest(c, "self_relation", err.Error())
+	case errors.Is(err, ErrBlocked):
+		response.Forbidden(c, err.Error())
+	case errors.Is(err, ErrAlreadyConnected):
+		response.Conflict(c, "already_connected", err.Error())
+	case errors.Is(err, ErrRequestPending):
+		response.Conflict(c, "request_pending", err.Error())
+	case errors.Is(err, cursor.ErrInvalid):
+		response.BadRequest(c, "invalid_cursor", err.Error())
+	default:
+		response.InternalServerError(c, err)
+	}
+}
@@ -0,0 +1,93 @@
+package social
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/internal/domain/user"
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+)
+
+// Connection states. Rejected requests are deleted rather than kept.
+const (
+	StatusPending  = "pending"
+	StatusAccepted = "accepted"
+)
+
+// Listing kinds for Filter.Kind.
+const (
+	KindFollowers   = "followers"
+	KindFollowing   = "following"
+	KindConnections = "connections"
+	// KindIncoming and KindOutgoing list pending connection requests sent
+	// to and by the user.
+	KindIncoming = "incoming"
+	KindOutgoing = "outgoing"
+	KindBlocks   = "blocks"
+)
+
+// SortSince is the only keyset order graph listings support: newest
+// relation first.
+const SortSince = "since"
+
+// Connection is a mutual link between two users. Each pair is stored once
+// with UserA sorting before UserB; UserID is the other party as seen by the
+// caller.
+type Connection struct {
+	UserA       uuid.UUID `json:"-" db:"user_a"`
+	UserB       uuid.UUID `json:"-" db:"user_b"`
+	UserID      uuid.UUID `json:"user_id" db:"-"`
+	RequesterID uuid.UUID `json:"requester_id" db:"requester_id"`
+	Status      string    `json:"status" db:"status"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// pair orders two user ids the way connections are keyed.
+func pair(a, b uuid.UUID) (uuid.UUID, uuid.UUID) {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		return b, a
+	}
+	return a, b
+}
+
+// seenBy fills UserID with the party that is not userID.
+func (c *Connection) seenBy(userID uuid.UUID) *Connection {
+	c.UserID = c.UserA
+	if c.UserA == userID {
+		c.UserID = c.UserB
+	}
+	return c
+}
+
+// Edge is one row of a graph listing: the other user and when the relation
+// started (or, for connections, was accepted).
+type Edge struct {
+	UserID uuid.UUID `db:"user_id"`
+	Since  time.Time `db:"since"`
+}
+
+// Entry is an Edge as returned to clients.
+type Entry struct {
+	User  user.Summary `json:"user"`
+	Since time.Time    `json:"since"`
+}
+
+// Filter drives graph listings.
+type Filter struct {
+	Kind   string
+	UserID uuid.UUID
+	// Viewer hides users that block or are blocked by it; uuid.Nil shows
+	// everyone.
+	Viewer uuid.UUID
+	Limit  int
+	Cursor *cursor.Cursor
+}
+
+// Page is a keyset page of entries.
+type Page struct {
+	Entries []Entry
+	cursor.Page
+}
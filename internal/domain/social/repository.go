@@ -0,0 +1,39 @@
+package social
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository persists follows, connections and blocks.
+type Repository interface {
+	// Transact runs fn against a repository bound to a single transaction.
+	Transact(ctx context.Context, fn func(Repository) error) error
+	// AddFollow is a no-op when the follow exists.
+	AddFollow(ctx context.Context, followerID, followeeID uuid.UUID, at time.Time) error
+	RemoveFollow(ctx context.Context, followerID, followeeID uuid.UUID) error
+	// GetConnection returns nil when the pair has no connection or request.
+	GetConnection(ctx context.Context, userA, userB uuid.UUID) (*Connection, error)
+	CreateConnection(ctx context.Context, c *Connection) error
+	UpdateConnection(ctx context.Context, c *Connection) error
+	// DeleteConnection reports whether a row was removed.
+	DeleteConnection(ctx context.Context, userA, userB uuid.UUID) (bool, error)
+	// AddBlock is a no-op when the block exists.
+	AddBlock(ctx context.Context, blockerID, blockedID uuid.UUID, at time.Time) error
+	RemoveBlock(ctx context.Context, blockerID, blockedID uuid.UUID) error
+	// Blocked reports whether either user blocks the other.
+	Blocked(ctx context.Context, a, b uuid.UUID) (bool, error)
+	// BlockedIDs returns the users userID blocks or is blocked by.
+	BlockedIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	// Sever drops follows in both directions, any connection or request
+	// between a and b, and grants either holds on the other's profiles.
+	Sever(ctx context.Context, a, b uuid.UUID) error
+	// ConnectedIDs returns the candidates userID has an accepted connection
+	// with.
+	ConnectedIDs(ctx context.Context, userID uuid.UUID, candidates []uuid.UUID) ([]uuid.UUID, error)
+	// List returns up to filter.Limit+1 edges of filter.Kind past
+	// filter.Cursor, skipping deleted users.
+	List(ctx context.Context, filter Filter) ([]Edge, error)
+}
@@ -0,0 +1,264 @@
+package social
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/internal/domain/user"
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+)
+
+// Sentinel errors for HTTP mapping.
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrSelf               = errors.New("cannot follow, connect with or block yourself")
+	ErrBlocked            = errors.New("a block exists between these users")
+	ErrRequestNotFound    = errors.New("connection request not found")
+	ErrConnectionNotFound = errors.New("connection not found")
+	ErrAlreadyConnected   = errors.New("already connected")
+	ErrRequestPending     = errors.New("connection request already sent")
+)
+
+// Directory resolves users shown in graph listings.
+type Directory interface {
+	Summaries(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]user.Summary, error)
+}
+
+// Service manages the graph between users: one-way follows, mutual
+// connections that start as requests, and blocks. A block severs every
+// other relation between the two users and hides each from the other.
+type Service struct {
+	repo  Repository
+	users Directory
+}
+
+// NewService wires a social Service.
+func NewService(repo Repository, users Directory) *Service {
+	return &Service{repo: repo, users: users}
+}
+
+// Follow makes userID follow targetID. Following twice is not an error.
+func (s *Service) Follow(ctx context.Context, userID, targetID uuid.UUID) error {
+	if err := s.checkTarget(ctx, userID, targetID); err != nil {
+		return err
+	}
+	return s.repo.AddFollow(ctx, userID, targetID, time.Now().UTC())
+}
+
+// Unfollow removes a follow if there is one.
+func (s *Service) Unfollow(ctx context.Context, userID, targetID uuid.UUID) error {
+	return s.repo.RemoveFollow(ctx, userID, targetID)
+}
+
+// RequestConnection asks targetID to connect. When targetID already asked
+// userID the request is accepted instead.
+func (s *Service) RequestConnection(ctx context.Context, userID, targetID uuid.UUID) (*Connection, error) {
+	if err := s.checkTarget(ctx, userID, targetID); err != nil {
+		return nil, err
+	}
+	a, b := pair(userID, targetID)
+	var conn *Connection
+	err := s.repo.Transact(ctx, func(repo Repository) error {
+		existing, err := repo.GetConnection(ctx, a, b)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		switch {
+		case existing == nil:
+			conn = &Connection{UserA: a, UserB: b, RequesterID: userID, Status: StatusPending, CreatedAt: now, UpdatedAt: now}
+			return repo.CreateConnection(ctx, conn)
+		case existing.Status == StatusAccepted:
+			return ErrAlreadyConnected
+		case existing.RequesterID == userID:
+			return ErrRequestPending
+		default:
+			existing.Status, existing.UpdatedAt = StatusAccepted, now
+			conn = existing
+			return repo.UpdateConnection(ctx, conn)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conn.seenBy(userID), nil
+}
+
+// Accept accepts the pending request requesterID sent to userID.
+func (s *Service) Accept(ctx context.Context, userID, requesterID uuid.UUID) (*Connection, error) {
+	a, b := pair(userID, requesterID)
+	var conn *Connection
+	err := s.repo.Transact(ctx, func(repo Repository) error {
+		existing, err := repo.GetConnection(ctx, a, b)
+		if err != nil {
+			return err
+		}
+		if !incoming(existing, userID, requesterID) {
+			return ErrRequestNotFound
+		}
+		existing.Status, existing.UpdatedAt = StatusAccepted, time.Now().UTC()
+		conn = existing
+		return repo.UpdateConnection(ctx, conn)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conn.seenBy(userID), nil
+}
+
+// Reject discards the pending request requesterID sent to userID; the
+// requester may ask again later.
+func (s *Service) Reject(ctx context.Context, userID, requesterID uuid.UUID) error {
+	a, b := pair(userID, requesterID)
+	return s.repo.Transact(ctx, func(repo Repository) error {
+		existing, err := repo.GetConnection(ctx, a, b)
+		if err != nil {
+			return err
+		}
+		if !incoming(existing, userID, requesterID) {
+			return ErrRequestNotFound
+		}
+		_, err = repo.DeleteConnection(ctx, a, b)
+		return err
+	})
+}
+
+// Disconnect removes the connection between userID and otherID, or
+// withdraws a request either of them sent.
+func (s *Service) Disconnect(ctx context.Context, userID, otherID uuid.UUID) error {
+	a, b := pair(userID, otherID)
+	removed, err := s.repo.DeleteConnection(ctx, a, b)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrConnectionNotFound
+	}
+	return nil
+}
+
+// Block blocks targetID for userID and severs follows, connections and
+// profile grants between them in both directions.
+func (s *Service) Block(ctx context.Context, userID, targetID uuid.UUID) error {
+	if userID == targetID {
+		return ErrSelf
+	}
+	if err := s.checkExists(ctx, targetID); err != nil {
+		return err
+	}
+	return s.repo.Transact(ctx, func(repo Repository) error {
+		if err := repo.AddBlock(ctx, userID, targetID, time.Now().UTC()); err != nil {
+			return err
+		}
+		return repo.Sever(ctx, userID, targetID)
+	})
+}
+
+// Unblock lifts a block userID placed. Severed relations are not restored.
+func (s *Service) Unblock(ctx context.Context, userID, targetID uuid.UUID) error {
+	return s.repo.RemoveBlock(ctx, userID, targetID)
+}
+
+// List returns a keyset page of filter.Kind for filter.UserID. Listing
+// another user's graph fails with ErrUserNotFound when a block stands
+// between them and the viewer.
+func (s *Service) List(ctx context.Context, filter Filter) (*Page, error) {
+	if err := filter.Cursor.Expect(SortSince); err != nil {
+		return nil, err
+	}
+	if filter.Viewer != uuid.Nil && filter.Viewer != filter.UserID {
+		if err := s.checkExists(ctx, filter.UserID); err != nil {
+			return nil, err
+		}
+		blocked, err := s.repo.Blocked(ctx, filter.Viewer, filter.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			return nil, ErrUserNotFound
+		}
+	}
+	edges, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{}
+	edges, page.Page = cursor.Paginate(edges, filter.Limit, filter.Cursor, func(e Edge) cursor.Cursor {
+		return cursor.Cursor{Sort: SortSince, Value: cursor.FormatTime(e.Since), ID: e.UserID}
+	})
+	ids := make([]uuid.UUID, len(edges))
+	for i, e := range edges {
+		ids[i] = e.UserID
+	}
+	summaries, err := s.users.Summaries(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	page.Entries = make([]Entry, 0, len(edges))
+	for _, e := range edges {
+		if summary, ok := summaries[e.UserID]; ok {
+			page.Entries = append(page.Entries, Entry{User: summary, Since: e.Since})
+		}
+	}
+	return page, nil
+}
+
+// BlockedIDs returns the users userID blocks or is blocked by.
+func (s *Service) BlockedIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	return s.repo.BlockedIDs(ctx, userID)
+}
+
+// Connected reports which candidates userID is connected with.
+func (s *Service) Connected(ctx context.Context, userID uuid.UUID, candidates []uuid.UUID) (map[uuid.UUID]bool, error) {
+	out := make(map[uuid.UUID]bool, len(candidates))
+	if len(candidates) == 0 {
+		return out, nil
+	}
+	ids, err := s.repo.ConnectedIDs(ctx, userID, candidates)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		out[id] = true
+	}
+	return out, nil
+}
+
+// checkTarget rejects relating to oneself, to unknown users and across a
+// block.
+func (s *Service) checkTarget(ctx context.Context, userID, targetID uuid.UUID) error {
+	if userID == targetID {
+		return ErrSelf
+	}
+	if err := s.checkExists(ctx, targetID); err != nil {
+		return err
+	}
+	blocked, err := s.repo.Blocked(ctx, userID, targetID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+	return nil
+}
+
+func (s *Service) checkExists(ctx context.Context, id uuid.UUID) error {
+	found, err := s.users.Summaries(ctx, []uuid.UUID{id})
+	if err != nil {
+		return err
+	}
+	if _, ok := found[id]; !ok {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// incoming reports whether c is a pending request from requesterID to
+// userID.
+func incoming(c *Connection, userID, requesterID uuid.UUID) bool {
+	return c != nil && c.Status == StatusPending && c.RequesterID == requesterID && requesterID != userID
+}
@@ -0,0 +1,217 @@
+package social
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kidpech/api_free_demo/internal/domain/user"
+)
+
+func TestConnectionRequestLifecycle(t *testing.T) {
+	repo, users := newFakeRepo(), fakeUsers{}
+	ada, alan, grace := users.add(), users.add(), users.add()
+	svc := NewService(repo, users)
+	ctx := context.Background()
+
+	conn, err := svc.RequestConnection(ctx, ada, alan)
+	require.NoError(t, err)
+	require.Equal(t, StatusPending, conn.Status)
+	require.Equal(t, alan, conn.UserID)
+	_, err = svc.RequestConnection(ctx, ada, alan)
+	require.ErrorIs(t, err, ErrRequestPending)
+
+	// Only the addressee can accept.
+	_, err = svc.Accept(ctx, ada, alan)
+	require.ErrorIs(t, err, ErrRequestNotFound)
+	conn, err = svc.Accept(ctx, alan, ada)
+	require.NoError(t, err)
+	require.Equal(t, StatusAccepted, conn.Status)
+	require.Equal(t, ada, conn.UserID)
+	_, err = svc.RequestConnection(ctx, alan, ada)
+	require.ErrorIs(t, err, ErrAlreadyConnected)
+
+	connected, err := svc.Connected(ctx, ada, []uuid.UUID{alan, grace})
+	require.NoError(t, err)
+	require.Equal(t, map[uuid.UUID]bool{alan: true}, connected)
+
+	// Crossing requests connect straight away.
+	_, err = svc.RequestConnection(ctx, grace, ada)
+	require.NoError(t, err)
+	conn, err = svc.RequestConnection(ctx, ada, grace)
+	require.NoError(t, err)
+	require.Equal(t, StatusAccepted, conn.Status)
+
+	_, err = svc.RequestConnection(ctx, alan, grace)
+	require.NoError(t, err)
+	require.NoError(t, svc.Reject(ctx, grace, alan))
+	require.ErrorIs(t, svc.Reject(ctx, grace, alan), ErrRequestNotFound)
+	require.ErrorIs(t, svc.Disconnect(ctx, alan, grace), ErrConnectionNotFound)
+
+	_, err = svc.RequestConnection(ctx, ada, ada)
+	require.ErrorIs(t, err, ErrSelf)
+	require.ErrorIs(t, svc.Follow(ctx, ada, uuid.New()), ErrUserNotFound)
+}
+
+func TestBlockSeversAndHides(t *testing.T) {
+	repo, users := newFakeRepo(), fakeUsers{}
+	ada, alan := users.add(), users.add()
+	svc := NewService(repo, users)
+	ctx := context.Background()
+
+	require.NoError(t, svc.Follow(ctx, ada, alan))
+	require.NoError(t, svc.Follow(ctx, alan, ada))
+	_, err := svc.RequestConnection(ctx, ada, alan)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Block(ctx, alan, ada))
+	require.Empty(t, repo.follows)
+	require.Empty(t, repo.connections)
+
+	require.ErrorIs(t, svc.Follow(ctx, ada, alan), ErrBlocked)
+	_, err = svc.RequestConnection(ctx, ada, alan)
+	require.ErrorIs(t, err, ErrBlocked)
+	_, err = svc.List(ctx, Filter{Kind: KindFollowers, UserID: alan, Viewer: ada, Limit: 10})
+	require.ErrorIs(t, err, ErrUserNotFound)
+
+	blocked, err := svc.BlockedIDs(ctx, ada)
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{alan}, blocked)
+
+	require.NoError(t, svc.Unblock(ctx, alan, ada))
+	require.NoError(t, svc.Follow(ctx, ada, alan))
+	page, err := svc.List(ctx, Filter{Kind: KindFollowers, UserID: alan, Viewer: ada, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+	require.Equal(t, ada, page.Entries[0].User.ID)
+}
+
+type fakeUsers map[uuid.UUID]user.Summary
+
+func (f fakeUsers) add() uuid.UUID {
+	id := uuid.New()
+	f[id] = user.Summary{ID: id, Name: id.String()[:8]}
+	return id
+}
+
+func (f fakeUsers) Summaries(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]user.Summary, error) {
+	out := map[uuid.UUID]user.Summary{}
+	for _, id := range ids {
+		if s, ok := f[id]; ok {
+			out[id] = s
+		}
+	}
+	return out, nil
+}
+
+type edgeKey struct{ from, to uuid.UUID }
+
+type fakeRepo struct {
+	follows     map[edgeKey]time.Time
+	blocks      map[edgeKey]time.Time
+	connections map[edgeKey]Connection
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{follows: map[edgeKey]time.Time{}, blocks: map[edgeKey]time.Time{}, connections: map[edgeKey]Connection{}}
+}
+
+func (r *fakeRepo) Transact(ctx context.Context, fn func(Repository) error) error {
+	return fn(r)
+}
+
+func (r *fakeRepo) AddFollow(ctx context.Context, followerID, followeeID uuid.UUID, at time.Time) error {
+	r.follows[edgeKey{followerID, followeeID}] = at
+	return nil
+}
+
+func (r *fakeRepo) RemoveFollow(ctx context.Context, followerID, followeeID uuid.UUID) error {
+	delete(r.follows, edgeKey{followerID, followeeID})
+	return nil
+}
+
+func (r *fakeRepo) GetConnection(ctx context.Context, userA, userB uuid.UUID) (*Connection, error) {
+	c, ok := r.connections[edgeKey{userA, userB}]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (r *fakeRepo) CreateConnection(ctx context.Context, c *Connection) error {
+	r.connections[edgeKey{c.UserA, c.UserB}] = *c
+	return nil
+}
+
+func (r *fakeRepo) UpdateConnection(ctx context.Context, c *Connection) error {
+	r.connections[edgeKey{c.UserA, c.UserB}] = *c
+	return nil
+}
+
+func (r *fakeRepo) DeleteConnection(ctx context.Context, userA, userB uuid.UUID) (bool, error) {
+	_, ok := r.connections[edgeKey{userA, userB}]
+	delete(r.connections, edgeKey{userA, userB})
+	return ok, nil
+}
+
+func (r *fakeRepo) AddBlock(ctx context.Context, blockerID, blockedID uuid.UUID, at time.Time) error {
+	r.blocks[edgeKey{blockerID, blockedID}] = at
+	return nil
+}
+
+func (r *fakeRepo) RemoveBlock(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	delete(r.blocks, edgeKey{blockerID, blockedID})
+	return nil
+}
+
+func (r *fakeRepo) Blocked(ctx context.Context, a, b uuid.UUID) (bool, error) {
+	_, ab := r.blocks[edgeKey{a, b}]
+	_, ba := r.blocks[edgeKey{b, a}]
+	return ab || ba, nil
+}
+
+func (r *fakeRepo) BlockedIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for key := range r.blocks {
+		switch userID {
+		case key.from:
+			ids = append(ids, key.to)
+		case key.to:
+			ids = append(ids, key.from)
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeRepo) Sever(ctx context.Context, a, b uuid.UUID) error {
+	delete(r.follows, edgeKey{a, b})
+	delete(r.follows, edgeKey{b, a})
+	userA, userB := pair(a, b)
+	delete(r.connections, edgeKey{userA, userB})
+	return nil
+}
+
+func (r *fakeRepo) ConnectedIDs(ctx context.Context, userID uuid.UUID, candidates []uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, id := range candidates {
+		a, b := pair(userID, id)
+		if c, ok := r.connections[edgeKey{a, b}]; ok && c.Status == StatusAccepted {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// List only serves followers, which is all the tests page through.
+func (r *fakeRepo) List(ctx context.Context, filter Filter) ([]Edge, error) {
+	var edges []Edge
+	for key, at := range r.follows {
+		if key.to == filter.UserID {
+			edges = append(edges, Edge{UserID: key.from, Since: at})
+		}
+	}
+	return edges, nil
+}
@@ -0,0 +1,282 @@
+package tenant
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/internal/domain/user"
+	"github.com/kidpech/api_free_demo/pkg/response"
+)
+
+// Handler exposes tenant administration endpoints.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler returns a tenant Handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes mounts tenant routes.
+func (h *Handler) RegisterRoutes(rg *gin.RouterGroup, authMW gin.HandlerFunc, adminMW gin.HandlerFunc) {
+	rg.GET("/users/me/tenants", authMW, h.myTenants)
+	rg.POST("/invitations/accept", h.acceptInvitation)
+
+	invites := rg.Group("/tenants/:id/invitations", authMW)
+	{
+		invites.POST("", h.invite)
+		invites.GET("", h.listInvitations)
+		invites.DELETE("/:invitation_id", h.revokeInvitation)
+	}
+
+	admin := rg.Group("/admin/tenants", authMW, adminMW)
+	{
+		admin.POST("", h.create)
+		admin.GET("", h.list)
+		admin.GET("/:id", h.get)
+		admin.GET("/:id/members", h.listMembers)
+		admin.PUT("/:id/members/:user_id", h.setMember)
+		admin.DELETE("/:id/members/:user_id", h.removeMember)
+	}
+}
+
+func (h *Handler) create(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	t, err := h.service.Create(c.Request.Context(), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Header("Location", "/api/v1/admin/tenants/"+t.ID.String())
+	c.JSON(http.StatusCreated, t)
+}
+
+func (h *Handler) list(c *gin.Context) {
+	limit := response.GetLimit(c, 50, 200)
+	offset := response.GetOffset(c)
+	tenants, total, err := h.service.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Paginated(c, tenants, total, offset, limit)
+}
+
+func (h *Handler) get(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "tenant")
+		return
+	}
+	t, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+func (h *Handler) listMembers(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "tenant")
+		return
+	}
+	members, err := h.service.ListMembers(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": members})
+}
+
+func (h *Handler) setMember(c *gin.Context) {
+	var req MemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	tenantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "tenant")
+		return
+	}
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		response.NotFound(c, "user")
+		return
+	}
+	member, err := h.service.SetMember(c.Request.Context(), tenantID, userID, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, member)
+}
+
+func (h *Handler) removeMember(c *gin.Context) {
+	tenantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "tenant")
+		return
+	}
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		response.NotFound(c, "membership")
+		return
+	}
+	if err := h.service.RemoveMember(c.Request.Context(), tenantID, userID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) myTenants(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	members, err := h.service.ListForUser(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": members})
+}
+
+func (h *Handler) invite(c *gin.Context) {
+	var req InviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	actor, ok := actorFromContext(c)
+	if !ok {
+		return
+	}
+	tenantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "tenant")
+		return
+	}
+	inv, err := h.service.Invite(c.Request.Context(), actor, tenantID, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, inv)
+}
+
+func (h *Handler) listInvitations(c *gin.Context) {
+	actor, ok := actorFromContext(c)
+	if !ok {
+		return
+	}
+	tenantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "tenant")
+		return
+	}
+	invitations, err := h.service.ListInvitations(c.Request.Context(), actor, tenantID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": invitations})
+}
+
+func (h *Handler) revokeInvitation(c *gin.Context) {
+	actor, ok := actorFromContext(c)
+	if !ok {
+		return
+	}
+	tenantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "tenant")
+		return
+	}
+	invitationID, err := uuid.Parse(c.Param("invitation_id"))
+	if err != nil {
+		response.NotFound(c, "invitation")
+		return
+	}
+	if err := h.service.RevokeInvitation(c.Request.Context(), actor, tenantID, invitationID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) acceptInvitation(c *gin.Context) {
+	var req AcceptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	res, err := h.service.AcceptInvitation(c.Request.Context(), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	status := http.StatusOK
+	if res.Auth != nil {
+		status = http.StatusCreated
+	}
+	c.JSON(status, res)
+}
+
+func actorFromContext(c *gin.Context) (Actor, bool) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return Actor{}, false
+	}
+	return Actor{UserID: userID, Role: c.GetString("user_role")}, true
+}
+
+func (h *Handler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		response.NotFound(c, "tenant")
+	case errors.Is(err, ErrMemberNotFound):
+		response.NotFound(c, "membership")
+	case errors.Is(err, ErrUserNotFound):
+		response.NotFound(c, "user")
+	case errors.Is(err, ErrDuplicateSlug):
+		response.Conflict(c, "duplicate_slug", "tenant slug already taken")
+	case errors.Is(err, ErrInvalidSlug):
+		response.BadRequest(c, "invalid_slug", "slug must be lowercase letters, digits and dashes")
+	case errors.Is(err, ErrLastOwner):
+		response.Conflict(c, "last_owner", "tenant must keep at least one owner")
+	case errors.Is(err, ErrForbidden):
+		response.Forbidden(c, "forbidden")
+	case errors.Is(err, ErrInvitationNotFound):
+		response.NotFound(c, "invitation")
+	case errors.Is(err, ErrInvitationInactive):
+		response.Conflict(c, "invitation_inactive", "invitation was accepted, revoked or has expired")
+	case errors.Is(err, ErrInvitationDuplicate):
+		response.Conflict(c, "invitation_pending", "a pending invitation already exists for this email")
+	case errors.Is(err, ErrAccountDetails):
+		response.BadRequest(c, "account_details_required", "name and password are required to create an account")
+	case errors.Is(err, ErrInvitationsDisabled):
+		response.Forbidden(c, "invitations disabled")
+	case errors.Is(err, user.ErrDuplicateEmail):
+		response.Conflict(c, "duplicate_email", "email already registered")
+	default:
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			response.ValidationError(c, err)
+			return
+		}
+		response.InternalServerError(c, err)
+	}
+}
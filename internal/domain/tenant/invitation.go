@@ -0,0 +1,276 @@
+package tenant
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/internal/domain/user"
+)
+
+// Invitation errors.
+var (
+	ErrInvitationNotFound  = errors.New("invitation not found")
+	ErrInvitationInactive  = errors.New("invitation no longer valid")
+	ErrInvitationDuplicate = errors.New("pending invitation exists")
+	ErrInvitationsDisabled = errors.New("invitations not configured")
+	ErrAccountDetails      = errors.New("name and password required to create an account")
+)
+
+// Mailer delivers invitation emails.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// Accounts bridges invitation acceptance into the user domain.
+type Accounts interface {
+	LookupByEmail(ctx context.Context, email string) (*user.User, error)
+	RegisterInvited(ctx context.Context, req user.RegisterRequest) (*user.AuthResponse, error)
+}
+
+// Actor identifies the caller of a tenant-management operation.
+type Actor struct {
+	UserID uuid.UUID
+	Role   string
+}
+
+// Invite creates an invitation and mails its token. When the mail cannot
+// be sent the invitation is revoked again.
+func (s *Service) Invite(ctx context.Context, actor Actor, tenantID uuid.UUID, req InviteRequest) (*Invitation, error) {
+	if s.mailer == nil || s.accounts == nil {
+		return nil, ErrInvitationsDisabled
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+	t, err := s.repo.GetByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireManager(ctx, actor, tenantID, req.Role); err != nil {
+		return nil, err
+	}
+	existing, err := s.repo.ListInvitations(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for i := range existing {
+		if existing[i].Email == req.Email && invitationStatus(&existing[i], now) == InvitationPending {
+			return nil, ErrInvitationDuplicate
+		}
+	}
+	token, hash, err := newInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+	inv := &Invitation{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		Email:     req.Email,
+		Role:      req.Role,
+		TokenHash: hash,
+		InvitedBy: actor.UserID,
+		ExpiresAt: now.Add(s.inviteTTL),
+		CreatedAt: now,
+	}
+	if err := s.repo.CreateInvitation(ctx, inv); err != nil {
+		return nil, err
+	}
+	inv.Status = InvitationPending
+	body := fmt.Sprintf("You have been invited to join %s as %s.\n\nAccept the invitation before %s:\n%s\n",
+		t.Name, inv.Role, inv.ExpiresAt.Format(time.RFC1123), s.acceptLink(token))
+	if err := s.mailer.Send(ctx, inv.Email, "Invitation to join "+t.Name, body); err != nil {
+		// Revoke the undelivered invitation so a retry is not refused as a
+		// duplicate of it.
+		revokedAt := time.Now().UTC()
+		inv.RevokedAt = &revokedAt
+		if revokeErr := s.repo.UpdateInvitation(ctx, inv); revokeErr != nil {
+			return nil, errors.Join(fmt.Errorf("send invitation: %w", err), revokeErr)
+		}
+		return nil, fmt.Errorf("send invitation: %w", err)
+	}
+	return inv, nil
+}
+
+// ListInvitations returns every invitation of a tenant with derived status.
+func (s *Service) ListInvitations(ctx context.Context, actor Actor, tenantID uuid.UUID) ([]Invitation, error) {
+	if _, err := s.repo.GetByID(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	if err := s.requireManager(ctx, actor, tenantID, RoleMember); err != nil {
+		return nil, err
+	}
+	invitations, err := s.repo.ListInvitations(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for i := range invitations {
+		invitations[i].Status = invitationStatus(&invitations[i], now)
+	}
+	return invitations, nil
+}
+
+// RevokeInvitation invalidates a pending invitation.
+func (s *Service) RevokeInvitation(ctx context.Context, actor Actor, tenantID, id uuid.UUID) error {
+	if err := s.requireManager(ctx, actor, tenantID, RoleMember); err != nil {
+		return err
+	}
+	inv, err := s.repo.GetInvitation(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if invitationStatus(inv, time.Now().UTC()) != InvitationPending {
+		return ErrInvitationInactive
+	}
+	now := time.Now().UTC()
+	inv.RevokedAt = &now
+	return s.repo.UpdateInvitation(ctx, inv)
+}
+
+// AcceptInvitation redeems a token. An existing account for the invited
+// email is linked directly; otherwise a new account is registered even when
+// open registration is disabled.
+func (s *Service) AcceptInvitation(ctx context.Context, req AcceptRequest) (*AcceptResponse, error) {
+	if s.accounts == nil {
+		return nil, ErrInvitationsDisabled
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+	inv, err := s.repo.GetInvitationByTokenHash(ctx, hashInvitationToken(req.Token))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if invitationStatus(inv, now) != InvitationPending {
+		return nil, ErrInvitationInactive
+	}
+
+	resp := &AcceptResponse{}
+	var userID uuid.UUID
+	existing, err := s.accounts.LookupByEmail(ctx, inv.Email)
+	switch {
+	case err == nil:
+		userID = existing.ID
+	case errors.Is(err, user.ErrUserNotFound):
+		if req.Name == "" || req.Password == "" {
+			return nil, ErrAccountDetails
+		}
+		auth, err := s.accounts.RegisterInvited(ctx, user.RegisterRequest{
+			Email:    inv.Email,
+			Password: req.Password,
+			Name:     req.Name,
+		})
+		if err != nil {
+			return nil, err
+		}
+		userID = auth.User.ID
+		resp.Auth = auth
+	default:
+		return nil, err
+	}
+
+	member := &Membership{TenantID: inv.TenantID, UserID: userID, Role: inv.Role, CreatedAt: now, UpdatedAt: now}
+	if current, err := s.repo.GetMember(ctx, inv.TenantID, userID); err == nil {
+		// Never downgrade an existing member through an invitation.
+		member.CreatedAt = current.CreatedAt
+		if roleRank(current.Role) > roleRank(inv.Role) {
+			member.Role = current.Role
+		}
+	}
+	inv.AcceptedAt = &now
+	// Claiming the invitation first makes a concurrent accept of the same
+	// token fail with ErrInvitationInactive instead of succeeding twice.
+	err = s.repo.Transact(ctx, func(repo Repository) error {
+		if err := repo.UpdateInvitation(ctx, inv); err != nil {
+			return err
+		}
+		return repo.UpsertMember(ctx, member)
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Membership = member
+	return resp, nil
+}
+
+// requireManager allows global admins and tenant owners/admins. Only owners
+// (or global admins) may hand out the owner role.
+func (s *Service) requireManager(ctx context.Context, actor Actor, tenantID uuid.UUID, grantedRole string) error {
+	if actor.Role == "admin" {
+		return nil
+	}
+	member, err := s.repo.GetMember(ctx, tenantID, actor.UserID)
+	if errors.Is(err, ErrMemberNotFound) {
+		return ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	switch {
+	case member.Role == RoleOwner:
+		return nil
+	case member.Role == RoleAdmin && grantedRole != RoleOwner:
+		return nil
+	default:
+		return ErrForbidden
+	}
+}
+
+func (s *Service) acceptLink(token string) string {
+	sep := "?"
+	if strings.Contains(s.inviteURL, "?") {
+		sep = "&"
+	}
+	return s.inviteURL + sep + "token=" + url.QueryEscape(token)
+}
+
+func invitationStatus(inv *Invitation, now time.Time) string {
+	switch {
+	case inv.AcceptedAt != nil:
+		return InvitationAccepted
+	case inv.RevokedAt != nil:
+		return InvitationRevoked
+	case !now.Before(inv.ExpiresAt):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
+
+func roleRank(role string) int {
+	switch role {
+	case RoleOwner:
+		return 3
+	case RoleAdmin:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func newInvitationToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashInvitationToken(token), nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
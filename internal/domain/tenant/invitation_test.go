@@ -0,0 +1,186 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kidpech/api_free_demo/internal/domain/user"
+)
+
+func TestInvitationStatus(t *testing.T) {
+	now := time.Now().UTC()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	cases := []struct {
+		name string
+		inv  Invitation
+		want string
+	}{
+		{name: "pending", inv: Invitation{ExpiresAt: future}, want: InvitationPending},
+		{name: "expired", inv: Invitation{ExpiresAt: past}, want: InvitationExpired},
+		{name: "expires now", inv: Invitation{ExpiresAt: now}, want: InvitationExpired},
+		{name: "revoked", inv: Invitation{ExpiresAt: future, RevokedAt: &past}, want: InvitationRevoked},
+		{name: "accepted", inv: Invitation{ExpiresAt: past, AcceptedAt: &past}, want: InvitationAccepted},
+		{name: "accepted wins over revoked", inv: Invitation{ExpiresAt: future, AcceptedAt: &past, RevokedAt: &past}, want: InvitationAccepted},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, invitationStatus(&tc.inv, now))
+		})
+	}
+}
+
+func TestInviteRequiresManager(t *testing.T) {
+	cases := []struct {
+		name  string
+		actor string
+		role  string
+		err   error
+	}{
+		{name: "owner invites owner", actor: RoleOwner, role: RoleOwner},
+		{name: "admin invites admin", actor: RoleAdmin, role: RoleAdmin},
+		{name: "admin invites owner", actor: RoleAdmin, role: RoleOwner, err: ErrForbidden},
+		{name: "member invites member", actor: RoleMember, role: RoleMember, err: ErrForbidden},
+		{name: "outsider invites member", role: RoleMember, err: ErrForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, service, _, _ := newInvitationService()
+			tenantID, actorID := repo.addTenant("acme"), uuid.New()
+			if tc.actor != "" {
+				repo.addMember(tenantID, actorID, tc.actor)
+			}
+
+			_, err := service.Invite(context.Background(), Actor{UserID: actorID, Role: "user"}, tenantID,
+				InviteRequest{Email: "new@example.com", Role: tc.role})
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestInviteRevokesUnsentInvitation(t *testing.T) {
+	repo, service, mailer, _ := newInvitationService()
+	ctx := context.Background()
+	tenantID := repo.addTenant("acme")
+	actor := Actor{UserID: uuid.New(), Role: "admin"}
+	req := InviteRequest{Email: "new@example.com", Role: RoleMember}
+
+	mailer.err = errors.New("smtp down")
+	_, err := service.Invite(ctx, actor, tenantID, req)
+	require.ErrorIs(t, err, mailer.err)
+
+	mailer.err = nil
+	inv, err := service.Invite(ctx, actor, tenantID, req)
+	require.NoError(t, err)
+	require.Equal(t, InvitationPending, inv.Status)
+	_, err = service.Invite(ctx, actor, tenantID, req)
+	require.ErrorIs(t, err, ErrInvitationDuplicate)
+
+	invitations, err := service.ListInvitations(ctx, actor, tenantID)
+	require.NoError(t, err)
+	statuses := map[string]int{}
+	for _, inv := range invitations {
+		statuses[inv.Status]++
+	}
+	require.Equal(t, map[string]int{InvitationPending: 1, InvitationRevoked: 1}, statuses)
+}
+
+func TestAcceptInvitationOnce(t *testing.T) {
+	repo, service, mailer, accounts := newInvitationService()
+	ctx := context.Background()
+	tenantID := repo.addTenant("acme")
+	invitee := &user.User{ID: uuid.New(), Email: "new@example.com"}
+	accounts.users[invitee.Email] = invitee
+	repo.addMember(tenantID, invitee.ID, RoleAdmin)
+
+	_, err := service.Invite(ctx, Actor{UserID: uuid.New(), Role: "admin"}, tenantID,
+		InviteRequest{Email: "New@Example.com ", Role: RoleMember})
+	require.NoError(t, err)
+	require.Equal(t, invitee.Email, mailer.to)
+	token := mailer.token(t)
+
+	resp, err := service.AcceptInvitation(ctx, AcceptRequest{Token: token})
+	require.NoError(t, err)
+	require.Nil(t, resp.Auth)
+	require.Equal(t, RoleAdmin, resp.Membership.Role, "an invitation never downgrades")
+
+	_, err = service.AcceptInvitation(ctx, AcceptRequest{Token: token})
+	require.ErrorIs(t, err, ErrInvitationInactive)
+	_, err = service.AcceptInvitation(ctx, AcceptRequest{Token: "unknown"})
+	require.ErrorIs(t, err, ErrInvitationNotFound)
+}
+
+func TestAcceptInvitationRejectsLateClaim(t *testing.T) {
+	repo, service, mailer, accounts := newInvitationService()
+	ctx := context.Background()
+	tenantID := repo.addTenant("acme")
+	invitee := &user.User{ID: uuid.New(), Email: "new@example.com"}
+	accounts.users[invitee.Email] = invitee
+
+	inv, err := service.Invite(ctx, Actor{UserID: uuid.New(), Role: "admin"}, tenantID,
+		InviteRequest{Email: invitee.Email, Role: RoleMember})
+	require.NoError(t, err)
+	// Another request claims the invitation after this one has read it.
+	accounts.beforeLookup = func() {
+		require.NoError(t, service.RevokeInvitation(ctx, Actor{Role: "admin"}, tenantID, inv.ID))
+	}
+
+	_, err = service.AcceptInvitation(ctx, AcceptRequest{Token: mailer.token(t)})
+	require.ErrorIs(t, err, ErrInvitationInactive)
+	_, err = repo.GetMember(ctx, tenantID, invitee.ID)
+	require.ErrorIs(t, err, ErrMemberNotFound)
+}
+
+func newInvitationService() (*fakeRepo, *Service, *fakeMailer, *fakeAccounts) {
+	repo, mailer := newFakeRepo(), &fakeMailer{}
+	accounts := &fakeAccounts{users: make(map[string]*user.User)}
+	service := NewService(repo, WithInvitations(mailer, accounts, "https://app.example.com/accept", time.Hour))
+	return repo, service, mailer, accounts
+}
+
+type fakeMailer struct {
+	err      error
+	to, body string
+}
+
+func (m *fakeMailer) Send(ctx context.Context, to, subject, body string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.to, m.body = to, body
+	return nil
+}
+
+// token extracts the invitation token from the last mailed link.
+func (m *fakeMailer) token(t *testing.T) string {
+	_, token, ok := strings.Cut(m.body, "token=")
+	require.True(t, ok)
+	return strings.TrimSpace(token)
+}
+
+type fakeAccounts struct {
+	users        map[string]*user.User
+	beforeLookup func()
+}
+
+func (a *fakeAccounts) LookupByEmail(ctx context.Context, email string) (*user.User, error) {
+	if a.beforeLookup != nil {
+		a.beforeLookup()
+	}
+	u, ok := a.users[email]
+	if !ok {
+		return nil, user.ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (a *fakeAccounts) RegisterInvited(ctx context.Context, req user.RegisterRequest) (*user.AuthResponse, error) {
+	u := &user.User{ID: uuid.New(), Email: req.Email, Name: req.Name}
+	a.users[u.Email] = u
+	return &user.AuthResponse{User: u}, nil
+}
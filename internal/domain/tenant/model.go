@@ -0,0 +1,90 @@
+package tenant
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/internal/domain/user"
+)
+
+// Member roles within a tenant.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// Tenant models an organization hosted on the deployment.
+type Tenant struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Slug      string    `json:"slug" db:"slug"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Membership links a user to a tenant with a per-tenant role.
+type Membership struct {
+	TenantID  uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Role      string    `json:"role" db:"role"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CreateRequest captures tenant creation payloads.
+type CreateRequest struct {
+	Slug    string     `json:"slug" validate:"required,min=2,max=63"`
+	Name    string     `json:"name" validate:"required,min=2"`
+	OwnerID *uuid.UUID `json:"owner_id"`
+}
+
+// MemberRequest assigns a role to a member.
+type MemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+// Invitation statuses derived from timestamps.
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// Invitation offers tenant membership to an email address.
+type Invitation struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	TenantID   uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Email      string     `json:"email" db:"email"`
+	Role       string     `json:"role" db:"role"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	InvitedBy  uuid.UUID  `json:"invited_by" db:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	Status     string     `json:"status" db:"-"`
+}
+
+// InviteRequest captures invitation creation payloads.
+type InviteRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+// AcceptRequest redeems an invitation. Name and Password are only needed
+// when no account exists yet for the invited email.
+type AcceptRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Name     string `json:"name" validate:"omitempty,min=2"`
+	Password string `json:"password" validate:"omitempty,min=8"`
+}
+
+// AcceptResponse reports the resulting membership, plus tokens when a new
+// account was registered.
+type AcceptResponse struct {
+	Membership *Membership        `json:"membership"`
+	Auth       *user.AuthResponse `json:"auth,omitempty"`
+}
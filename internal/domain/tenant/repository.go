@@ -0,0 +1,31 @@
+package tenant
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Repository defines persistence for tenants and memberships.
+type Repository interface {
+	Create(ctx context.Context, tenant *Tenant) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Tenant, error)
+	GetBySlug(ctx context.Context, slug string) (*Tenant, error)
+	List(ctx context.Context, limit, offset int) ([]Tenant, int, error)
+	UpsertMember(ctx context.Context, member *Membership) error
+	RemoveMember(ctx context.Context, tenantID, userID uuid.UUID) error
+	GetMember(ctx context.Context, tenantID, userID uuid.UUID) (*Membership, error)
+	ListMembers(ctx context.Context, tenantID uuid.UUID) ([]Membership, error)
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]Membership, error)
+	CountOwners(ctx context.Context, tenantID uuid.UUID) (int, error)
+	CreateInvitation(ctx context.Context, inv *Invitation) error
+	GetInvitation(ctx context.Context, tenantID, id uuid.UUID) (*Invitation, error)
+	GetInvitationByTokenHash(ctx context.Context, hash string) (*Invitation, error)
+	ListInvitations(ctx context.Context, tenantID uuid.UUID) ([]Invitation, error)
+	// UpdateInvitation stores AcceptedAt and RevokedAt of a pending
+	// invitation and returns ErrInvitationInactive once it was already
+	// accepted or revoked.
+	UpdateInvitation(ctx context.Context, inv *Invitation) error
+	// Transact runs fn against a repository bound to a single transaction.
+	Transact(ctx context.Context, fn func(Repository) error) error
+}
@@ -0,0 +1,191 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Sentinel errors for HTTP mapping.
+var (
+	ErrNotFound       = errors.New("tenant not found")
+	ErrMemberNotFound = errors.New("membership not found")
+	ErrDuplicateSlug  = errors.New("tenant slug taken")
+	ErrInvalidSlug    = errors.New("invalid tenant slug")
+	ErrLastOwner      = errors.New("tenant must keep an owner")
+	ErrForbidden      = errors.New("forbidden")
+	ErrUserNotFound   = errors.New("user not found")
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Service orchestrates tenant administration and resolution.
+type Service struct {
+	repo      Repository
+	validator *validator.Validate
+	sanitizer *bluemonday.Policy
+	mailer    Mailer
+	accounts  Accounts
+	inviteURL string
+	inviteTTL time.Duration
+}
+
+// Option customizes optional Service collaborators.
+type Option func(*Service)
+
+// WithInvitations enables the invitation workflow. acceptURL is the link
+// mailed to invitees; the token is appended as a query parameter.
+func WithInvitations(mailer Mailer, accounts Accounts, acceptURL string, ttl time.Duration) Option {
+	return func(s *Service) {
+		s.mailer = mailer
+		s.accounts = accounts
+		s.inviteURL = acceptURL
+		s.inviteTTL = ttl
+	}
+}
+
+// NewService wires a tenant Service.
+func NewService(repo Repository, opts ...Option) *Service {
+	s := &Service{
+		repo:      repo,
+		validator: validator.New(),
+		sanitizer: bluemonday.StrictPolicy(),
+		inviteTTL: 7 * 24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create registers a tenant and optionally seeds its owner.
+func (s *Service) Create(ctx context.Context, req CreateRequest) (*Tenant, error) {
+	req.Slug = strings.ToLower(strings.TrimSpace(req.Slug))
+	req.Name = strings.TrimSpace(s.sanitizer.Sanitize(req.Name))
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+	if !slugPattern.MatchString(req.Slug) {
+		return nil, ErrInvalidSlug
+	}
+	now := time.Now().UTC()
+	t := &Tenant{ID: uuid.New(), Slug: req.Slug, Name: req.Name, CreatedAt: now, UpdatedAt: now}
+	if err := s.repo.Create(ctx, t); err != nil {
+		return nil, err
+	}
+	if req.OwnerID != nil {
+		owner := &Membership{TenantID: t.ID, UserID: *req.OwnerID, Role: RoleOwner, CreatedAt: now, UpdatedAt: now}
+		if err := s.repo.UpsertMember(ctx, owner); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Get returns a tenant by id.
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Tenant, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// List returns paginated tenants.
+func (s *Service) List(ctx context.Context, limit, offset int) ([]Tenant, int, error) {
+	return s.repo.List(ctx, limit, offset)
+}
+
+// SetMember adds a user to a tenant or changes their role.
+func (s *Service) SetMember(ctx context.Context, tenantID, userID uuid.UUID, req MemberRequest) (*Membership, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetByID(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	existing, err := s.repo.GetMember(ctx, tenantID, userID)
+	if err != nil && !errors.Is(err, ErrMemberNotFound) {
+		return nil, err
+	}
+	if existing != nil && existing.Role == RoleOwner && req.Role != RoleOwner {
+		if err := s.ensureAnotherOwner(ctx, tenantID); err != nil {
+			return nil, err
+		}
+	}
+	member := &Membership{TenantID: tenantID, UserID: userID, Role: req.Role, CreatedAt: now, UpdatedAt: now}
+	if existing != nil {
+		member.CreatedAt = existing.CreatedAt
+	}
+	if err := s.repo.UpsertMember(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember drops a user from a tenant, refusing to orphan it.
+func (s *Service) RemoveMember(ctx context.Context, tenantID, userID uuid.UUID) error {
+	existing, err := s.repo.GetMember(ctx, tenantID, userID)
+	if err != nil {
+		return err
+	}
+	if existing.Role == RoleOwner {
+		if err := s.ensureAnotherOwner(ctx, tenantID); err != nil {
+			return err
+		}
+	}
+	return s.repo.RemoveMember(ctx, tenantID, userID)
+}
+
+// ListMembers returns every membership of a tenant.
+func (s *Service) ListMembers(ctx context.Context, tenantID uuid.UUID) ([]Membership, error) {
+	if _, err := s.repo.GetByID(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, tenantID)
+}
+
+// ListForUser returns the tenants a user belongs to.
+func (s *Service) ListForUser(ctx context.Context, userID uuid.UUID) ([]Membership, error) {
+	return s.repo.ListForUser(ctx, userID)
+}
+
+// Resolve looks a tenant up by id or slug.
+func (s *Service) Resolve(ctx context.Context, ref string) (uuid.UUID, error) {
+	ref = strings.TrimSpace(ref)
+	if id, err := uuid.Parse(ref); err == nil {
+		t, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return t.ID, nil
+	}
+	t, err := s.repo.GetBySlug(ctx, strings.ToLower(ref))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return t.ID, nil
+}
+
+// IsMember reports whether userID belongs to tenantID.
+func (s *Service) IsMember(ctx context.Context, tenantID, userID uuid.UUID) (bool, error) {
+	_, err := s.repo.GetMember(ctx, tenantID, userID)
+	if errors.Is(err, ErrMemberNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *Service) ensureAnotherOwner(ctx context.Context, tenantID uuid.UUID) error {
+	owners, err := s.repo.CountOwners(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetMemberKeepsAnOwner(t *testing.T) {
+	cases := []struct {
+		name    string
+		owners  int
+		current string
+		role    string
+		err     error
+	}{
+		{name: "new member", role: RoleMember},
+		{name: "promote member", current: RoleMember, role: RoleAdmin},
+		{name: "demote last owner", owners: 1, current: RoleOwner, role: RoleAdmin, err: ErrLastOwner},
+		{name: "demote one of two owners", owners: 2, current: RoleOwner, role: RoleMember},
+		{name: "owner stays owner", owners: 1, current: RoleOwner, role: RoleOwner},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newFakeRepo()
+			service := NewService(repo)
+			tenantID, userID := repo.addTenant("acme"), uuid.New()
+			for i := 0; i < tc.owners; i++ {
+				if i == 0 && tc.current == RoleOwner {
+					repo.addMember(tenantID, userID, RoleOwner)
+					continue
+				}
+				repo.addMember(tenantID, uuid.New(), RoleOwner)
+			}
+			if tc.current != "" && tc.current != RoleOwner {
+				repo.addMember(tenantID, userID, tc.current)
+			}
+
+			member, err := service.SetMember(context.Background(), tenantID, userID, MemberRequest{Role: tc.role})
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				require.Equal(t, tc.current, repo.members[memberKey{tenantID, userID}].Role)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.role, member.Role)
+			require.Equal(t, tc.role, repo.members[memberKey{tenantID, userID}].Role)
+		})
+	}
+}
+
+func TestRemoveMemberKeepsAnOwner(t *testing.T) {
+	repo := newFakeRepo()
+	service := NewService(repo)
+	ctx := context.Background()
+	tenantID, owner, admin := repo.addTenant("acme"), uuid.New(), uuid.New()
+	repo.addMember(tenantID, owner, RoleOwner)
+	repo.addMember(tenantID, admin, RoleAdmin)
+
+	require.ErrorIs(t, service.RemoveMember(ctx, tenantID, owner), ErrLastOwner)
+	require.NoError(t, service.RemoveMember(ctx, tenantID, admin))
+	require.ErrorIs(t, service.RemoveMember(ctx, tenantID, admin), ErrMemberNotFound)
+
+	_, err := service.SetMember(ctx, tenantID, admin, MemberRequest{Role: RoleOwner})
+	require.NoError(t, err)
+	require.NoError(t, service.RemoveMember(ctx, tenantID, owner))
+}
+
+func TestResolveAcceptsIDOrSlug(t *testing.T) {
+	repo := newFakeRepo()
+	service := NewService(repo)
+	ctx := context.Background()
+	tenantID := repo.addTenant("acme")
+
+	for _, ref := range []string{tenantID.String(), "acme", " ACME "} {
+		id, err := service.Resolve(ctx, ref)
+		require.NoError(t, err, ref)
+		require.Equal(t, tenantID, id)
+	}
+	_, err := service.Resolve(ctx, "globex")
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = service.Resolve(ctx, uuid.NewString())
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+type memberKey struct{ tenantID, userID uuid.UUID }
+
+type fakeRepo struct {
+	tenants     map[uuid.UUID]Tenant
+	members     map[memberKey]Membership
+	invitations map[uuid.UUID]Invitation
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{
+		tenants:     make(map[uuid.UUID]Tenant),
+		members:     make(map[memberKey]Membership),
+		invitations: make(map[uuid.UUID]Invitation),
+	}
+}
+
+func (f *fakeRepo) addTenant(slug string) uuid.UUID {
+	t := Tenant{ID: uuid.New(), Slug: slug, Name: slug}
+	f.tenants[t.ID] = t
+	return t.ID
+}
+
+func (f *fakeRepo) addMember(tenantID, userID uuid.UUID, role string) {
+	f.members[memberKey{tenantID, userID}] = Membership{TenantID: tenantID, UserID: userID, Role: role}
+}
+
+func (f *fakeRepo) Create(ctx context.Context, t *Tenant) error {
+	for _, existing := range f.tenants {
+		if existing.Slug == t.Slug {
+			return ErrDuplicateSlug
+		}
+	}
+	f.tenants[t.ID] = *t
+	return nil
+}
+
+func (f *fakeRepo) GetByID(ctx context.Context, id uuid.UUID) (*Tenant, error) {
+	t, ok := f.tenants[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &t, nil
+}
+
+func (f *fakeRepo) GetBySlug(ctx context.Context, slug string) (*Tenant, error) {
+	for _, t := range f.tenants {
+		if t.Slug == slug {
+			return &t, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (f *fakeRepo) List(ctx context.Context, limit, offset int) ([]Tenant, int, error) {
+	var tenants []Tenant
+	for _, t := range f.tenants {
+		tenants = append(tenants, t)
+	}
+	return tenants, len(tenants), nil
+}
+
+func (f *fakeRepo) UpsertMember(ctx context.Context, m *Membership) error {
+	f.members[memberKey{m.TenantID, m.UserID}] = *m
+	return nil
+}
+
+func (f *fakeRepo) RemoveMember(ctx context.Context, tenantID, userID uuid.UUID) error {
+	if _, ok := f.members[memberKey{tenantID, userID}]; !ok {
+		return ErrMemberNotFound
+	}
+	delete(f.members, memberKey{tenantID, userID})
+	return nil
+}
+
+func (f *fakeRepo) GetMember(ctx context.Context, tenantID, userID uuid.UUID) (*Membership, error) {
+	m, ok := f.members[memberKey{tenantID, userID}]
+	if !ok {
+		return nil, ErrMemberNotFound
+	}
+	return &m, nil
+}
+
+func (f *fakeRepo) ListMembers(ctx context.Context, tenantID uuid.UUID) ([]Membership, error) {
+	var members []Membership
+	for key, m := range f.members {
+		if key.tenantID == tenantID {
+			members = append(members, m)
+		}
+	}
+	return members, nil
+}
+
+func (f *fakeRepo) ListForUser(ctx context.Context, userID uuid.UUID) ([]Membership, error) {
+	var members []Membership
+	for key, m := range f.members {
+		if key.userID == userID {
+			members = append(members, m)
+		}
+	}
+	return members, nil
+}
+
+func (f *fakeRepo) CountOwners(ctx context.Context, tenantID uuid.UUID) (int, error) {
+	owners := 0
+	for key, m := range f.members {
+		if key.tenantID == tenantID && m.Role == RoleOwner {
+			owners++
+		}
+	}
+	return owners, nil
+}
+
+func (f *fakeRepo) CreateInvitation(ctx context.Context, inv *Invitation) error {
+	f.invitations[inv.ID] = *inv
+	return nil
+}
+
+func (f *fakeRepo) GetInvitation(ctx context.Context, tenantID, id uuid.UUID) (*Invitation, error) {
+	inv, ok := f.invitations[id]
+	if !ok || inv.TenantID != tenantID {
+		return nil, ErrInvitationNotFound
+	}
+	return &inv, nil
+}
+
+func (f *fakeRepo) GetInvitationByTokenHash(ctx context.Context, hash string) (*Invitation, error) {
+	for _, inv := range f.invitations {
+		if inv.TokenHash == hash {
+			return &inv, nil
+		}
+	}
+	return nil, ErrInvitationNotFound
+}
+
+func (f *fakeRepo) ListInvitations(ctx context.Context, tenantID uuid.UUID) ([]Invitation, error) {
+	var invitations []Invitation
+	for _, inv := range f.invitations {
+		if inv.TenantID == tenantID {
+			invitations = append(invitations, inv)
+		}
+	}
+	return invitations, nil
+}
+
+func (f *fakeRepo) UpdateInvitation(ctx context.Context, inv *Invitation) error {
+	stored, ok := f.invitations[inv.ID]
+	if !ok || stored.AcceptedAt != nil || stored.RevokedAt != nil {
+		return ErrInvitationInactive
+	}
+	stored.AcceptedAt, stored.RevokedAt = inv.AcceptedAt, inv.RevokedAt
+	f.invitations[inv.ID] = stored
+	return nil
+}
+
+func (f *fakeRepo) Transact(ctx context.Context, fn func(Repository) error) error {
+	return fn(f)
+}
@@ -0,0 +1,84 @@
+package user
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/kidpech/api_free_demo/internal/domain/media"
+)
+
+// ImageStore persists uploaded images and signs links to them.
+type ImageStore interface {
+	Save(ctx context.Context, kind, owner string, r io.Reader) (*media.Upload, error)
+	Delete(ctx context.Context, key string) error
+	URLs(key string) (string, string)
+	KeyFromURL(raw string) (string, bool)
+}
+
+// WithImages enables avatar uploads.
+func WithImages(images ImageStore) Option {
+	return func(s *Service) {
+		s.images = images
+	}
+}
+
+// SetAvatar stores an uploaded avatar and replaces the previous one.
+func (s *Service) SetAvatar(ctx context.Context, userID uuid.UUID, r io.Reader) (*User, error) {
+	if s.images == nil {
+		return nil, media.ErrDisabled
+	}
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	upload, err := s.images.Save(ctx, media.KindAvatar, user.ID.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	previous := user.ProfileImageKey
+	user.ProfileImageKey = &upload.Key
+	user.ProfileImage = nil
+	user.UpdatedAt = time.Now().UTC()
+	if err := s.repo.Update(ctx, user); err != nil {
+		s.discardImage(ctx, &upload.Key)
+		return nil, err
+	}
+	s.discardImage(ctx, previous)
+	return s.present(user), nil
+}
+
+// applyProfileImage records a client-supplied URL. Our own signed links
+// echoed back keep the stored upload; any other URL replaces it.
+func (s *Service) applyProfileImage(ctx context.Context, user *User, raw string) {
+	if s.images != nil && user.ProfileImageKey != nil {
+		if key, ok := s.images.KeyFromURL(raw); ok && key == *user.ProfileImageKey {
+			return
+		}
+		s.discardImage(ctx, user.ProfileImageKey)
+		user.ProfileImageKey = nil
+	}
+	user.ProfileImage = &raw
+}
+
+// present swaps stored keys for signed URLs before a user leaves the service.
+func (s *Service) present(user *User) *User {
+	if user == nil || user.ProfileImageKey == nil || s.images == nil {
+		return user
+	}
+	url, thumb := s.images.URLs(*user.ProfileImageKey)
+	user.ProfileImage, user.ProfileThumb = &url, &thumb
+	return user
+}
+
+func (s *Service) discardImage(ctx context.Context, key *string) {
+	if key == nil || s.images == nil {
+		return
+	}
+	if err := s.images.Delete(ctx, *key); err != nil {
+		s.logger.Warn("delete avatar failed", zap.String("key", *key), zap.Error(err))
+	}
+}
@@ -0,0 +1,60 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ExpandProfiles embeds the user's profiles via ?expand=profiles.
+const ExpandProfiles = "profiles"
+
+// ErrInvalidExpand is returned for relations a resource cannot embed.
+var ErrInvalidExpand = errors.New("invalid expand")
+
+// ProfileLister loads the profile summaries embedded by ?expand=profiles.
+type ProfileLister interface {
+	ProfileSummaries(ctx context.Context, userID uuid.UUID) ([]ProfileSummary, error)
+}
+
+// WithProfiles enables ?expand=profiles.
+func WithProfiles(profiles ProfileLister) Option {
+	return func(s *Service) {
+		s.profiles = profiles
+	}
+}
+
+// Expand embeds the named relations into u.
+func (s *Service) Expand(ctx context.Context, u *User, relations []string) error {
+	for _, relation := range relations {
+		if relation != ExpandProfiles || s.profiles == nil {
+			return fmt.Errorf("%w: users can expand %s", ErrInvalidExpand, ExpandProfiles)
+		}
+	}
+	if len(relations) == 0 {
+		return nil
+	}
+	profiles, err := s.profiles.ProfileSummaries(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	u.Profiles = profiles
+	return nil
+}
+
+// Summaries returns the summaries of the live users among ids with one
+// lookup, keyed by id. Unknown and deleted users are left out.
+func (s *Service) Summaries(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]Summary, error) {
+	users, err := s.repo.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[uuid.UUID]Summary, len(users))
+	for i := range users {
+		u := s.present(&users[i])
+		out[u.ID] = Summary{ID: u.ID, Name: u.Name, ProfileImage: u.ProfileImage, ProfileThumb: u.ProfileThumb}
+	}
+	return out, nil
+}
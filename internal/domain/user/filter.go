@@ -0,0 +1,108 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+	"github.com/kidpech/api_free_demo/pkg/filterexpr"
+)
+
+// SortableFields whitelists the columns admins may order listings by.
+var SortableFields = map[string]bool{
+	"created_at":    true,
+	"updated_at":    true,
+	"last_login_at": true,
+	"name":          true,
+	"email":         true,
+}
+
+// FilterFields whitelists the user fields ?filter= expressions may test.
+var FilterFields = filterexpr.Schema{
+	"id":            {Column: "id", Kind: filterexpr.UUID},
+	"email":         {Column: "email", Kind: filterexpr.String},
+	"name":          {Column: "name", Kind: filterexpr.String},
+	"role":          {Column: "role", Kind: filterexpr.String},
+	"created_at":    {Column: "created_at", Kind: filterexpr.Time},
+	"updated_at":    {Column: "updated_at", Kind: filterexpr.Time},
+	"last_login_at": {Column: "last_login_at", Kind: filterexpr.Time, Nullable: true},
+	"suspended_at":  {Column: "suspended_at", Kind: filterexpr.Time, Nullable: true},
+	"deleted_at":    {Column: "deleted_at", Kind: filterexpr.Time, Nullable: true},
+}
+
+// ParseExpression parses a ?filter= expression against FilterFields.
+func ParseExpression(raw string) (filterexpr.Expr, error) {
+	expr, err := filterexpr.Parse(raw, FilterFields)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+	}
+	return expr, nil
+}
+
+// ParseSort accepts "field", "field_asc", "field_desc" or "-field" and
+// returns the whitelisted field plus direction.
+func ParseSort(raw string) (string, bool, error) {
+	raw = strings.TrimSpace(strings.ToLower(raw))
+	if raw == "" {
+		return "created_at", true, nil
+	}
+	field, desc := raw, false
+	switch {
+	case strings.HasPrefix(raw, "-"):
+		field, desc = raw[1:], true
+	case strings.HasSuffix(raw, "_desc"):
+		field, desc = strings.TrimSuffix(raw, "_desc"), true
+	case strings.HasSuffix(raw, "_asc"):
+		field = strings.TrimSuffix(raw, "_asc")
+	}
+	if !SortableFields[field] {
+		return "", false, fmt.Errorf("%w: unsupported sort field %q", ErrInvalidFilter, field)
+	}
+	return field, desc, nil
+}
+
+// ParseStatus validates the status filter.
+func ParseStatus(raw string) (string, error) {
+	switch raw = strings.ToLower(strings.TrimSpace(raw)); raw {
+	case "", StatusActive, StatusSuspended, StatusDeleted, StatusAll:
+		return raw, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported status %q", ErrInvalidFilter, raw)
+	}
+}
+
+// ParseTime accepts RFC3339 timestamps or plain dates for range filters.
+func ParseTime(name, raw string) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			t = t.UTC()
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s must be RFC3339 or YYYY-MM-DD", ErrInvalidFilter, name)
+}
+
+// SortValue renders the value of the given sort field for cursor encoding.
+// Users who never logged in sort by their signup time.
+func (u *User) SortValue(field string) string {
+	switch field {
+	case "name":
+		return u.Name
+	case "email":
+		return u.Email
+	case "updated_at":
+		return cursor.FormatTime(u.UpdatedAt)
+	case "last_login_at":
+		if u.LastLoginAt != nil {
+			return cursor.FormatTime(*u.LastLoginAt)
+		}
+		return cursor.FormatTime(u.CreatedAt)
+	default:
+		return cursor.FormatTime(u.CreatedAt)
+	}
+}
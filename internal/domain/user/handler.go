@@ -1,12 +1,21 @@
 package user
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 
+	"github.com/kidpech/api_free_demo/internal/domain/media"
+	"github.com/kidpech/api_free_demo/pkg/csvcell"
+	"github.com/kidpech/api_free_demo/pkg/cursor"
 	"github.com/kidpech/api_free_demo/pkg/response"
 )
 
@@ -27,17 +36,21 @@ func (h *Handler) RegisterRoutes(rg *gin.RouterGroup, authMW gin.HandlerFunc, ad
 		auth.POST("/register", h.register)
 		auth.POST("/login", h.login)
 		auth.POST("/refresh", h.refresh)
+		auth.POST("/password/set", h.setPassword)
 	}
 
 	me := rg.Group("/users/me", authMW)
 	{
 		me.GET("", h.getMe)
 		me.PUT("", h.updateMe)
+		me.POST("/avatar", h.uploadAvatar)
 	}
 
 	admin := rg.Group("/admin", authMW, adminMW)
 	{
 		admin.GET("/users", h.listUsers)
+		admin.GET("/users/export", h.exportUsers)
+		admin.POST("/users/import", h.importUsers)
 	}
 }
 
@@ -89,6 +102,19 @@ func (h *Handler) refresh(c *gin.Context) {
 	c.JSON(http.StatusOK, res)
 }
 
+func (h *Handler) setPassword(c *gin.Context) {
+	var req SetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	if err := h.service.SetPassword(c.Request.Context(), req); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
 func (h *Handler) getMe(c *gin.Context) {
 	userID, ok := c.Get("user_id")
 	if !ok {
@@ -101,6 +127,20 @@ func (h *Handler) getMe(c *gin.Context) {
 		h.handleError(c, err)
 		return
 	}
+	// Embedded profiles change without bumping the user's version, so
+	// expanded responses are not conditional.
+	if expand := response.GetList(c, "expand"); len(expand) > 0 {
+		if err := h.service.Expand(ctx, usr, expand); err != nil {
+			h.handleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, usr)
+		return
+	}
+	if response.NotModified(c, usr.Version) {
+		return
+	}
+	response.SetETag(c, usr.Version)
 	c.JSON(http.StatusOK, usr)
 }
 
@@ -115,32 +155,227 @@ func (h *Handler) updateMe(c *gin.Context) {
 		response.Unauthorized(c, "missing auth context")
 		return
 	}
+	if version, ok := response.IfMatch(c); ok {
+		req.Version = version
+	}
 	ctx := c.Request.Context()
 	usr, err := h.service.UpdateMe(ctx, userID.(uuid.UUID), req)
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
+	response.SetETag(c, usr.Version)
+	c.JSON(http.StatusOK, usr)
+}
+
+func (h *Handler) uploadAvatar(c *gin.Context) {
+	userID := response.MustUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+	file, err := media.ReadUpload(c)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	usr, err := h.service.SetAvatar(c.Request.Context(), userID, file)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.SetETag(c, usr.Version)
 	c.JSON(http.StatusOK, usr)
 }
 
 func (h *Handler) listUsers(c *gin.Context) {
-	filter := UserFilter{
-		Search: c.Query("search"),
-		Limit:  response.GetLimit(c, 50, 200),
-		Offset: response.GetOffset(c),
-		Sort:   c.DefaultQuery("sort", "created_at_desc"),
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	filter.Limit = response.GetLimit(c, 50, 200)
+	if filter.Cursor, err = cursor.Decode(c.Query("cursor")); err != nil {
+		h.handleError(c, err)
+		return
 	}
 	ctx := c.Request.Context()
-	users, total, err := h.service.List(ctx, filter)
+	page, err := h.service.List(ctx, filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	users := make([]AdminUser, len(page.Users))
+	for i := range page.Users {
+		users[i] = AdminView(&page.Users[i])
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":        users,
+		"total":       page.Total,
+		"limit":       filter.Limit,
+		"next_cursor": page.NextCursor,
+		"prev_cursor": page.PrevCursor,
+		"has_more":    page.HasMore,
+	})
+}
+
+func (h *Handler) exportUsers(c *gin.Context) {
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	format := c.DefaultQuery("format", "csv")
+	var write func(*User) error
+	switch format {
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="users.csv"`)
+		w := csv.NewWriter(c.Writer)
+		defer w.Flush()
+		if err := w.Write(exportColumns); err != nil {
+			return
+		}
+		write = func(u *User) error {
+			return w.Write(exportRow(u))
+		}
+	case "ndjson":
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", `attachment; filename="users.ndjson"`)
+		enc := json.NewEncoder(c.Writer)
+		write = func(u *User) error {
+			return enc.Encode(AdminView(u))
+		}
+	default:
+		response.BadRequest(c, "invalid_format", "format must be csv or ndjson")
+		return
+	}
+	c.Status(http.StatusOK)
+	rows := 0
+	err = h.service.Export(c.Request.Context(), filter, func(u *User) error {
+		if err := write(u); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// Headers are already on the wire; surface the failure to the logs only.
+		_ = c.Error(err)
+	}
+}
+
+// maxImportBytes caps the request body of an import.
+const maxImportBytes = 10 << 20
+
+func (h *Handler) importUsers(c *gin.Context) {
+	opts := ImportOptions{
+		DryRun:      c.Query("dry_run") == "true" || c.Query("dry_run") == "1",
+		OnDuplicate: c.DefaultQuery("on_duplicate", ImportOnDuplicateSkip),
+		Notify:      c.DefaultQuery("notify", ImportNotifyNone),
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	contentType := c.ContentType()
+	format := c.Query("format")
+	if format == "" {
+		switch contentType {
+		case "application/x-ndjson", "application/ndjson", "application/json":
+			format = "ndjson"
+		default:
+			format = "csv"
+		}
+	}
+	var body io.Reader = c.Request.Body
+	if contentType == "multipart/form-data" {
+		file, err := media.ReadUpload(c)
+		if err != nil {
+			response.BadRequest(c, "missing_file", err.Error())
+			return
+		}
+		body = file
+	}
+	rows, parseErrs, err := ParseImport(body, format)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = ErrImportTooLarge
+		}
+		h.handleError(c, err)
+		return
+	}
+	report, err := h.service.Import(c.Request.Context(), rows, parseErrs, opts)
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
-	response.Paginated(c, users, total, filter.Offset, filter.Limit)
+	c.JSON(http.StatusOK, report)
+}
+
+const exportFlushEvery = 100
+
+var exportColumns = []string{"id", "email", "name", "role", "created_at", "updated_at", "last_login_at", "suspended_at", "deleted_at"}
+
+// exportRow renders a user in exportColumns order, escaping cells a
+// spreadsheet would evaluate as formulas.
+func exportRow(u *User) []string {
+	return csvcell.EscapeRow([]string{
+		u.ID.String(),
+		u.Email,
+		u.Name,
+		u.Role,
+		u.CreatedAt.UTC().Format(time.RFC3339),
+		u.UpdatedAt.UTC().Format(time.RFC3339),
+		formatOptionalTime(u.LastLoginAt),
+		formatOptionalTime(u.SuspendedAt),
+		formatOptionalTime(u.DeletedAt),
+	})
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func parseUserFilter(c *gin.Context) (UserFilter, error) {
+	filter := UserFilter{
+		Search:      c.Query("search"),
+		Query:       strings.TrimSpace(c.Query("q")),
+		Role:        c.Query("role"),
+		EmailDomain: c.Query("email_domain"),
+	}
+	var err error
+	if filter.SortField, filter.SortDesc, err = ParseSort(c.DefaultQuery("sort", "created_at_desc")); err != nil {
+		return filter, err
+	}
+	if filter.Status, err = ParseStatus(c.Query("status")); err != nil {
+		return filter, err
+	}
+	if filter.CreatedFrom, err = ParseTime("created_from", c.Query("created_from")); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = ParseTime("created_to", c.Query("created_to")); err != nil {
+		return filter, err
+	}
+	if filter.LastLoginFrom, err = ParseTime("last_login_from", c.Query("last_login_from")); err != nil {
+		return filter, err
+	}
+	if filter.LastLoginTo, err = ParseTime("last_login_to", c.Query("last_login_to")); err != nil {
+		return filter, err
+	}
+	if filter.Where, err = ParseExpression(c.Query("filter")); err != nil {
+		return filter, err
+	}
+	return filter, nil
 }
 
 func (h *Handler) handleError(c *gin.Context, err error) {
+	if media.RespondError(c, err) {
+		return
+	}
 	switch {
 	case errors.Is(err, ErrDuplicateEmail):
 		response.Conflict(c, "duplicate_email", "email already registered")
@@ -152,9 +387,30 @@ func (h *Handler) handleError(c *gin.Context, err error) {
 		response.Forbidden(c, "forbidden")
 	case errors.Is(err, ErrUserNotFound):
 		response.NotFound(c, "user")
+	case errors.Is(err, ErrVersionConflict):
+		if _, conditional := response.IfMatch(c); conditional {
+			response.PreconditionFailed(c, "user updated elsewhere")
+			return
+		}
+		response.Conflict(c, "version_conflict", "user updated elsewhere")
 	case errors.Is(err, ErrInvalidToken):
 		response.Unauthorized(c, "invalid token")
+	case errors.Is(err, ErrInvalidFilter):
+		response.InvalidFilter(c, err)
+	case errors.Is(err, ErrInvalidExpand):
+		response.BadRequest(c, "invalid_expand", err.Error())
+	case errors.Is(err, cursor.ErrInvalid):
+		response.BadRequest(c, "invalid_cursor", err.Error())
+	case errors.Is(err, ErrImportTooLarge):
+		response.PayloadTooLarge(c, err.Error())
+	case errors.Is(err, ErrMailDisabled):
+		response.BadRequest(c, "mail_disabled", err.Error())
 	default:
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			response.ValidationError(c, err)
+			return
+		}
 		response.InternalServerError(c, err)
 	}
 }
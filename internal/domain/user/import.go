@@ -0,0 +1,284 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/kidpech/api_free_demo/pkg/csvcell"
+)
+
+// MaxImportRows bounds a single import request.
+const MaxImportRows = 5000
+
+// ErrImportTooLarge is returned when a file holds more than MaxImportRows.
+var ErrImportTooLarge = fmt.Errorf("import exceeds %d rows", MaxImportRows)
+
+// ParseImport decodes a CSV (with a header row) or NDJSON import file.
+// Rows that cannot be decoded are returned with a parse error so the
+// report can point at them; only unreadable input fails the whole call.
+// CSV email and name cells drop the guard quote user exports add, so an
+// exported file imports unchanged.
+func ParseImport(r io.Reader, format string) ([]ImportRow, map[int]error, error) {
+	switch format {
+	case "csv":
+		return parseImportCSV(r)
+	case "ndjson":
+		return parseImportNDJSON(r)
+	default:
+		return nil, nil, fmt.Errorf("%w: format must be csv or ndjson", ErrInvalidFilter)
+	}
+}
+
+func parseImportCSV(r io.Reader) ([]ImportRow, map[int]error, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: missing csv header", ErrInvalidFilter)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, nil, fmt.Errorf("%w: csv header must include email", ErrInvalidFilter)
+	}
+	get := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var rows []ImportRow
+	parseErrs := map[int]error{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if len(rows) >= MaxImportRows {
+			return nil, nil, ErrImportTooLarge
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			if !errors.As(err, &perr) {
+				return nil, nil, err
+			}
+			rows = append(rows, ImportRow{Line: perr.StartLine})
+			parseErrs[perr.StartLine] = perr.Err
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, ImportRow{
+			Line:         line,
+			Email:        csvcell.Unescape(get(record, "email")),
+			Name:         csvcell.Unescape(get(record, "name")),
+			Password:     get(record, "password"),
+			Role:         get(record, "role"),
+			ProfileImage: get(record, "profile_image"),
+		})
+	}
+	return rows, parseErrs, nil
+}
+
+func parseImportNDJSON(r io.Reader) ([]ImportRow, map[int]error, error) {
+	dec := json.NewDecoder(r)
+	var rows []ImportRow
+	parseErrs := map[int]error{}
+	for line := 1; ; line++ {
+		var row ImportRow
+		err := dec.Decode(&row)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if len(rows) >= MaxImportRows {
+			return nil, nil, ErrImportTooLarge
+		}
+		if err != nil {
+			// A syntax error leaves the decoder unusable, so stop here.
+			rows = append(rows, ImportRow{Line: line})
+			parseErrs[line] = err
+			break
+		}
+		row.Line = line
+		rows = append(rows, row)
+	}
+	return rows, parseErrs, nil
+}
+
+// Import creates or updates users in bulk. Every row gets the same
+// normalization and validation as Register; with DryRun nothing is written
+// and the report shows what would happen.
+func (s *Service) Import(ctx context.Context, rows []ImportRow, parseErrs map[int]error, opts ImportOptions) (*ImportReport, error) {
+	if opts.OnDuplicate == "" {
+		opts.OnDuplicate = ImportOnDuplicateSkip
+	}
+	if opts.Notify == "" {
+		opts.Notify = ImportNotifyNone
+	}
+	if err := s.validator.Struct(opts); err != nil {
+		return nil, err
+	}
+	if opts.Notify != ImportNotifyNone && s.mailer == nil {
+		return nil, ErrMailDisabled
+	}
+
+	report := &ImportReport{DryRun: opts.DryRun, Rows: make([]ImportResult, 0, len(rows))}
+	seen := make(map[string]int, len(rows))
+	for _, row := range rows {
+		var res ImportResult
+		if err, ok := parseErrs[row.Line]; ok {
+			res = ImportResult{Line: row.Line, Action: ImportActionError, Error: err.Error()}
+		} else {
+			res = s.importRow(ctx, row, opts, seen)
+		}
+		switch res.Action {
+		case ImportActionCreate:
+			report.Created++
+		case ImportActionUpdate:
+			report.Updated++
+		case ImportActionSkip:
+			report.Skipped++
+		default:
+			report.Failed++
+		}
+		report.Rows = append(report.Rows, res)
+	}
+	return report, nil
+}
+
+func (s *Service) importRow(ctx context.Context, row ImportRow, opts ImportOptions, seen map[string]int) ImportResult {
+	res := ImportResult{Line: row.Line, Email: strings.ToLower(strings.TrimSpace(row.Email))}
+	fail := func(err error) ImportResult {
+		res.Action = ImportActionError
+		res.Error = err.Error()
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			res.Error = "validation failed"
+			res.Details = make(map[string]string, len(verr))
+			for _, field := range verr {
+				res.Details[strings.ToLower(field.Field())] = field.Tag()
+			}
+		}
+		return res
+	}
+
+	row.Role = strings.ToLower(strings.TrimSpace(row.Role))
+	if err := s.validator.Struct(row); err != nil {
+		return fail(err)
+	}
+	req := RegisterRequest{Email: row.Email, Name: row.Name, Password: row.Password, ProfileImage: strings.TrimSpace(row.ProfileImage)}
+	hasPassword := strings.TrimSpace(row.Password) != ""
+	if !hasPassword {
+		// Placeholder that nobody knows; new accounts get an emailed link
+		// to replace it and existing ones keep their password.
+		placeholder, err := randomPassword()
+		if err != nil {
+			return fail(err)
+		}
+		req.Password = placeholder
+	}
+	if err := s.normalizeRegistration(&req); err != nil {
+		return fail(err)
+	}
+	res.Email = req.Email
+	if first, dup := seen[req.Email]; dup {
+		return fail(fmt.Errorf("duplicate of line %d", first))
+	}
+	seen[req.Email] = row.Line
+
+	existing, err := s.repo.GetByEmail(ctx, req.Email)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		return fail(err)
+	}
+
+	var target *User
+	switch {
+	case existing != nil && opts.OnDuplicate == ImportOnDuplicateSkip:
+		res.Action, res.UserID = ImportActionSkip, &existing.ID
+		return res
+	case existing != nil:
+		res.Action, res.UserID = ImportActionUpdate, &existing.ID
+		target = existing
+		target.Name = req.Name
+		if req.ProfileImage != "" {
+			s.applyProfileImage(ctx, target, req.ProfileImage)
+		}
+		if row.Role != "" {
+			target.Role = row.Role
+		}
+		target.UpdatedAt = time.Now().UTC()
+		if hasPassword {
+			hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+			if err != nil {
+				return fail(err)
+			}
+			setPasswordHash(target, string(hash), target.UpdatedAt)
+		}
+	default:
+		if !hasPassword && opts.Notify == ImportNotifyNone {
+			res.Details = map[string]string{"password": "required"}
+			return fail(errors.New("password required unless users are notified"))
+		}
+		res.Action = ImportActionCreate
+		if opts.DryRun {
+			return res
+		}
+		target, err = newUser(req)
+		if err != nil {
+			return fail(err)
+		}
+		if row.Role != "" {
+			target.Role = row.Role
+		}
+		res.UserID = &target.ID
+	}
+	if opts.DryRun {
+		return res
+	}
+
+	if res.Action == ImportActionCreate {
+		err = s.repo.Create(ctx, target)
+	} else {
+		err = s.repo.Update(ctx, target)
+	}
+	if err != nil {
+		res.UserID = nil
+		return fail(err)
+	}
+
+	var purpose string
+	switch {
+	case opts.Notify == ImportNotifyInvite && res.Action == ImportActionCreate:
+		purpose = TokenPurposeInvite
+	case opts.Notify == ImportNotifyReset:
+		purpose = TokenPurposeReset
+	}
+	if purpose != "" {
+		if err := s.sendActionLink(ctx, target, purpose); err != nil {
+			res.Warning = "notification not sent: " + err.Error()
+		}
+	}
+	return res
+}
+
+func randomPassword() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
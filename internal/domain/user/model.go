@@ -4,6 +4,9 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+	"github.com/kidpech/api_free_demo/pkg/filterexpr"
 )
 
 // User represents the persisted user entity.
@@ -13,14 +16,55 @@ type User struct {
 	Name             string     `json:"name" db:"name"`
 	PasswordHash     string     `json:"-" db:"password_hash"`
 	ProfileImage     *string    `json:"profile_image,omitempty" db:"profile_image"`
+	ProfileImageKey  *string    `json:"-" db:"profile_image_key"`
+	ProfileThumb     *string    `json:"profile_image_thumb,omitempty" db:"-"`
 	Role             string     `json:"role" db:"role"`
 	RefreshVersion   int        `json:"-" db:"refresh_version"`
 	LastLoginAt      *time.Time `json:"last_login_at,omitempty" db:"last_login_at"`
 	PasswordResetAt  *time.Time `json:"-" db:"password_reset_at"`
 	LastPasswordHash string     `json:"-" db:"last_password_hash"`
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty" db:"suspended_at"`
+	TenantID         *uuid.UUID `json:"tenant_id,omitempty" db:"-"`
 	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
 	DeletedAt        *time.Time `json:"-" db:"deleted_at"`
+	Version          int        `json:"version" db:"version"`
+	// Score and Snippet are only populated by full-text searches.
+	Score   *float64 `json:"score,omitempty" db:"score"`
+	Snippet *string  `json:"snippet,omitempty" db:"snippet"`
+	// Profiles is only filled by ?expand=profiles.
+	Profiles []ProfileSummary `json:"profiles,omitempty" db:"-"`
+}
+
+// AdminUser is the view of a user in admin listings and exports, which
+// also report deleted accounts.
+type AdminUser struct {
+	User
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// AdminView wraps u for admin responses.
+func AdminView(u *User) AdminUser {
+	return AdminUser{User: *u, DeletedAt: u.DeletedAt}
+}
+
+// Summary is the public face of a user embedded in related resources.
+type Summary struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	ProfileImage *string   `json:"profile_image,omitempty"`
+	ProfileThumb *string   `json:"profile_image_thumb,omitempty"`
+}
+
+// ProfileSummary is a profile embedded in its owner.
+type ProfileSummary struct {
+	ID           uuid.UUID `json:"id"`
+	Handle       *string   `json:"handle,omitempty"`
+	FirstName    string    `json:"first_name"`
+	LastName     string    `json:"last_name"`
+	Visibility   string    `json:"visibility"`
+	ProfileImage *string   `json:"profile_image,omitempty"`
+	ProfileThumb *string   `json:"profile_image_thumb,omitempty"`
 }
 
 // RegisterRequest captures incoming registration payloads.
@@ -31,25 +75,55 @@ type RegisterRequest struct {
 	ProfileImage string `json:"profile_image" validate:"omitempty,url"`
 }
 
-// LoginRequest models the login payload.
+// LoginRequest models the login payload. TenantID optionally scopes the
+// issued tokens to one of the user's tenants.
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8"`
+	TenantID string `json:"tenant_id" validate:"omitempty,uuid"`
 }
 
 // UpdateUserRequest updates the current user profile.
 type UpdateUserRequest struct {
 	Name         string  `json:"name" validate:"required,min=2"`
 	ProfileImage *string `json:"profile_image" validate:"omitempty,url"`
+	// Version, when set, must match the stored user.
+	Version *int `json:"version,omitempty"`
 }
 
+// Account status values accepted by UserFilter.Status.
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusDeleted   = "deleted"
+	StatusAll       = "all"
+)
+
 // UserFilter encapsulates pagination and filter params for administrative listings.
 type UserFilter struct {
-	Search   string
-	Limit    int
-	Offset   int
-	Sort     string
-	TenantID uuid.UUID
+	Search        string
+	Query         string
+	Role          string
+	Status        string
+	EmailDomain   string
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+	LastLoginFrom *time.Time
+	LastLoginTo   *time.Time
+	SortField     string
+	SortDesc      bool
+	Cursor        *cursor.Cursor
+	Limit         int
+	TenantID      uuid.UUID
+	// Where is a parsed ?filter= expression.
+	Where filterexpr.Expr
+}
+
+// UserPage is a keyset-paginated slice of users.
+type UserPage struct {
+	Users []User
+	Total int
+	cursor.Page
 }
 
 // AuthTokens groups issued tokens.
@@ -65,3 +139,83 @@ type AuthResponse struct {
 	User   *User      `json:"user"`
 	Tokens AuthTokens `json:"tokens"`
 }
+
+// Action token purposes.
+const (
+	TokenPurposeInvite = "invite"
+	TokenPurposeReset  = "reset"
+)
+
+// ActionToken is a single-use emailed link that lets a user set a password.
+// Only the SHA-256 of the token is stored.
+type ActionToken struct {
+	TokenHash string     `db:"token_hash"`
+	UserID    uuid.UUID  `db:"user_id"`
+	Purpose   string     `db:"purpose"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// SetPasswordRequest redeems an invite or reset token.
+type SetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// Import duplicate policies and notification modes.
+const (
+	ImportOnDuplicateSkip   = "skip"
+	ImportOnDuplicateUpdate = "update"
+
+	ImportNotifyNone   = "none"
+	ImportNotifyInvite = "invite"
+	ImportNotifyReset  = "reset"
+)
+
+// Per-row import actions.
+const (
+	ImportActionCreate = "create"
+	ImportActionUpdate = "update"
+	ImportActionSkip   = "skip"
+	ImportActionError  = "error"
+)
+
+// ImportOptions tunes a bulk import. With Notify set, rows may omit the
+// password and users receive a link to choose one instead.
+type ImportOptions struct {
+	DryRun      bool
+	OnDuplicate string `validate:"oneof=skip update"`
+	Notify      string `validate:"oneof=none invite reset"`
+}
+
+// ImportRow is one record of an import file.
+type ImportRow struct {
+	Line         int    `json:"-"`
+	Email        string `json:"email"`
+	Name         string `json:"name"`
+	Password     string `json:"password"`
+	Role         string `json:"role" validate:"omitempty,oneof=user admin"`
+	ProfileImage string `json:"profile_image"`
+}
+
+// ImportResult reports the outcome of a single row.
+type ImportResult struct {
+	Line    int               `json:"line"`
+	Email   string            `json:"email,omitempty"`
+	Action  string            `json:"action"`
+	UserID  *uuid.UUID        `json:"user_id,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+	Warning string            `json:"warning,omitempty"`
+}
+
+// ImportReport summarizes an import run.
+type ImportReport struct {
+	DryRun  bool           `json:"dry_run"`
+	Created int            `json:"created"`
+	Updated int            `json:"updated"`
+	Skipped int            `json:"skipped"`
+	Failed  int            `json:"failed"`
+	Rows    []ImportResult `json:"rows"`
+}
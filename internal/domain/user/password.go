@@ -0,0 +1,111 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrMailDisabled is returned when an operation needs email but no mailer
+// is configured.
+var ErrMailDisabled = errors.New("email delivery not configured")
+
+// Mailer delivers account emails.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// WithMailer enables emailed invite and reset links. setURL is the page
+// that collects the new password; the token is appended as a query parameter.
+func WithMailer(mailer Mailer, setURL string, ttl time.Duration) Option {
+	return func(s *Service) {
+		s.mailer = mailer
+		s.passwordURL = setURL
+		s.tokenTTL = ttl
+	}
+}
+
+// SetPassword redeems an invite or reset token and revokes existing sessions.
+func (s *Service) SetPassword(ctx context.Context, req SetPasswordRequest) error {
+	req.Password = strings.TrimSpace(req.Password)
+	if err := s.validator.Struct(req); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	token, err := s.repo.ConsumeActionToken(ctx, hashActionToken(req.Token), now)
+	if err != nil {
+		return err
+	}
+	user, err := s.repo.GetByID(ctx, token.UserID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	setPasswordHash(user, string(hash), now)
+	return s.repo.Update(ctx, user)
+}
+
+// setPasswordHash swaps the hash and bumps the refresh version so tokens
+// issued for the old password stop working.
+func setPasswordHash(user *User, hash string, now time.Time) {
+	user.LastPasswordHash = user.PasswordHash
+	user.PasswordHash = hash
+	user.PasswordResetAt = &now
+	user.RefreshVersion++
+	user.UpdatedAt = now
+}
+
+// sendActionLink issues a token for purpose and mails the link to the user.
+func (s *Service) sendActionLink(ctx context.Context, user *User, purpose string) error {
+	if s.mailer == nil {
+		return ErrMailDisabled
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	now := time.Now().UTC()
+	record := &ActionToken{
+		TokenHash: hashActionToken(token),
+		UserID:    user.ID,
+		Purpose:   purpose,
+		ExpiresAt: now.Add(s.tokenTTL),
+		CreatedAt: now,
+	}
+	if err := s.repo.CreateActionToken(ctx, record); err != nil {
+		return err
+	}
+
+	link := s.passwordURL
+	sep := "?"
+	if strings.Contains(link, "?") {
+		sep = "&"
+	}
+	link += sep + "token=" + url.QueryEscape(token)
+
+	subject, intro := "Reset your password", "A password reset was requested for your account."
+	if purpose == TokenPurposeInvite {
+		subject, intro = "Your account is ready", "An account has been created for you."
+	}
+	body := fmt.Sprintf("Hi %s,\n\n%s Choose a password before %s:\n%s\n",
+		user.Name, intro, record.ExpiresAt.Format(time.RFC1123), link)
+	return s.mailer.Send(ctx, user.Email, subject, body)
+}
+
+func hashActionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
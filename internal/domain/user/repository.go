@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -9,8 +10,20 @@ import (
 // Repository defines the persistence boundary for users.
 type Repository interface {
 	Create(ctx context.Context, user *User) error
+	// Update writes user only if the stored version still equals
+	// user.Version, then bumps it; otherwise it returns ErrVersionConflict.
 	Update(ctx context.Context, user *User) error
+	// TouchLogin records a login without bumping the version, so clients'
+	// If-Match preconditions survive logins from other devices.
+	TouchLogin(ctx context.Context, id uuid.UUID, at time.Time) error
 	GetByEmail(ctx context.Context, email string) (*User, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*User, error)
+	// ListByIDs returns the live users among ids, in no particular order.
+	ListByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error)
+	// List returns up to filter.Limit+1 rows so callers can detect a further page.
 	List(ctx context.Context, filter UserFilter) ([]User, int, error)
+	Stream(ctx context.Context, filter UserFilter, fn func(*User) error) error
+	CreateActionToken(ctx context.Context, token *ActionToken) error
+	// ConsumeActionToken marks an unused, unexpired token as used and returns it.
+	ConsumeActionToken(ctx context.Context, tokenHash string, now time.Time) (*ActionToken, error)
 }
@@ -11,6 +11,10 @@ import (
 	"github.com/microcosm-cc/bluemonday"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+	"github.com/kidpech/api_free_demo/pkg/search"
+	"github.com/kidpech/api_free_demo/pkg/tenancy"
 )
 
 // Sentinel errors for deterministic HTTP mapping.
@@ -21,6 +25,8 @@ var (
 	ErrUserNotFound         = errors.New("user not found")
 	ErrInvalidToken         = errors.New("invalid token")
 	ErrRegistrationDisabled = errors.New("registration disabled")
+	ErrInvalidFilter        = errors.New("invalid filter")
+	ErrVersionConflict      = errors.New("version mismatch")
 )
 
 // TokenManager abstracts JWT/refresh issuance.
@@ -30,26 +36,52 @@ type TokenManager interface {
 	ExtractUserID(refreshToken string) (uuid.UUID, error)
 }
 
+// MembershipChecker verifies tenant membership when a login targets a tenant.
+type MembershipChecker interface {
+	IsMember(ctx context.Context, tenantID, userID uuid.UUID) (bool, error)
+}
+
 // Service encapsulates user orchestration.
 type Service struct {
 	repo        Repository
 	tokens      TokenManager
+	memberships MembershipChecker
+	profiles    ProfileLister
+	images      ImageStore
+	mailer      Mailer
+	passwordURL string
+	tokenTTL    time.Duration
 	validator   *validator.Validate
 	sanitizer   *bluemonday.Policy
 	logger      *zap.Logger
 	allowSignup bool
 }
 
+// Option customizes optional Service collaborators.
+type Option func(*Service)
+
+// WithMemberships enables tenant-scoped logins.
+func WithMemberships(checker MembershipChecker) Option {
+	return func(s *Service) {
+		s.memberships = checker
+	}
+}
+
 // NewService wires a Service.
-func NewService(repo Repository, tokens TokenManager, logger *zap.Logger, allowSignup bool) *Service {
-	return &Service{
+func NewService(repo Repository, tokens TokenManager, logger *zap.Logger, allowSignup bool, opts ...Option) *Service {
+	s := &Service{
 		repo:        repo,
 		tokens:      tokens,
 		validator:   validator.New(),
 		sanitizer:   bluemonday.UGCPolicy(),
 		logger:      logger,
 		allowSignup: allowSignup,
+		tokenTTL:    72 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 // Register creates a new user and immediately issues tokens.
@@ -57,38 +89,30 @@ func (s *Service) Register(ctx context.Context, req RegisterRequest) (*AuthRespo
 	if !s.allowSignup {
 		return nil, ErrRegistrationDisabled
 	}
-	req.Email = strings.TrimSpace(req.Email)
-	req.Password = strings.TrimSpace(req.Password)
-	req.Name = strings.TrimSpace(s.sanitizer.Sanitize(req.Name))
-	if err := s.validator.Struct(req); err != nil {
+	return s.register(ctx, req)
+}
+
+// RegisterInvited registers an invited user. The invitation itself is the
+// authorization, so it works even when open registration is disabled.
+func (s *Service) RegisterInvited(ctx context.Context, req RegisterRequest) (*AuthResponse, error) {
+	return s.register(ctx, req)
+}
+
+func (s *Service) register(ctx context.Context, req RegisterRequest) (*AuthResponse, error) {
+	if err := s.normalizeRegistration(&req); err != nil {
 		return nil, err
 	}
 
-	existing, err := s.repo.GetByEmail(ctx, strings.ToLower(req.Email))
+	existing, err := s.repo.GetByEmail(ctx, req.Email)
 	if err == nil && existing != nil {
 		return nil, ErrDuplicateEmail
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	user, err := newUser(req)
 	if err != nil {
 		return nil, err
 	}
 
-	now := time.Now().UTC()
-	user := &User{
-		ID:             uuid.New(),
-		Email:          strings.ToLower(req.Email),
-		Name:           req.Name,
-		PasswordHash:   string(hash),
-		Role:           "user",
-		RefreshVersion: 1,
-		CreatedAt:      now,
-		UpdatedAt:      now,
-	}
-	if req.ProfileImage != "" {
-		user.ProfileImage = &req.ProfileImage
-	}
-
 	if err := s.repo.Create(ctx, user); err != nil {
 		if errors.Is(err, ErrDuplicateEmail) {
 			return nil, ErrDuplicateEmail
@@ -104,6 +128,43 @@ func (s *Service) Register(ctx context.Context, req RegisterRequest) (*AuthRespo
 	return &AuthResponse{User: user, Tokens: tokens}, nil
 }
 
+// normalizeRegistration trims, sanitizes and validates a registration
+// payload in place.
+func (s *Service) normalizeRegistration(req *RegisterRequest) error {
+	req.Email = strings.TrimSpace(req.Email)
+	req.Password = strings.TrimSpace(req.Password)
+	req.Name = strings.TrimSpace(s.sanitizer.Sanitize(req.Name))
+	if err := s.validator.Struct(req); err != nil {
+		return err
+	}
+	req.Email = strings.ToLower(req.Email)
+	return nil
+}
+
+// newUser builds a user from a normalized registration payload.
+func newUser(req RegisterRequest) (*User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	user := &User{
+		ID:             uuid.New(),
+		Email:          req.Email,
+		Name:           req.Name,
+		PasswordHash:   string(hash),
+		Role:           "user",
+		RefreshVersion: 1,
+		Version:        1,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if req.ProfileImage != "" {
+		user.ProfileImage = &req.ProfileImage
+	}
+	return user, nil
+}
+
 // Login authenticates by email/password.
 func (s *Service) Login(ctx context.Context, req LoginRequest) (*AuthResponse, error) {
 	req.Email = strings.TrimSpace(req.Email)
@@ -120,22 +181,29 @@ func (s *Service) Login(ctx context.Context, req LoginRequest) (*AuthResponse, e
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
 		return nil, ErrInvalidCreds
 	}
+	if req.TenantID != "" {
+		tenantID, err := s.checkMembership(ctx, user, req.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		user.TenantID = &tenantID
+	}
 
 	now := time.Now().UTC()
+	if err := s.repo.TouchLogin(ctx, user.ID, now); err != nil {
+		return nil, err
+	}
 	user.LastLoginAt = &now
 	user.UpdatedAt = now
-	_ = s.repo.Update(ctx, user)
 
 	tokens, err := s.tokens.IssueTokens(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
-	return &AuthResponse{User: user, Tokens: tokens}, nil
+	return &AuthResponse{User: s.present(user), Tokens: tokens}, nil
 }
 
-// Refresh issues new tokens based on a refresh token.
-
 // Refresh uses refresh token to rotate credentials.
 func (s *Service) Refresh(ctx context.Context, refreshToken string) (*AuthResponse, error) {
 	userID, err := s.tokens.ExtractUserID(refreshToken)
@@ -150,7 +218,37 @@ func (s *Service) Refresh(ctx context.Context, refreshToken string) (*AuthRespon
 	if err != nil {
 		return nil, ErrInvalidToken
 	}
-	return &AuthResponse{User: user, Tokens: tokens}, nil
+	return &AuthResponse{User: s.present(user), Tokens: tokens}, nil
+}
+
+func (s *Service) checkMembership(ctx context.Context, user *User, raw string) (uuid.UUID, error) {
+	tenantID, err := uuid.Parse(raw)
+	if err != nil || s.memberships == nil {
+		return uuid.Nil, ErrForbidden
+	}
+	if user.Role == "admin" {
+		return tenantID, nil
+	}
+	ok, err := s.memberships.IsMember(ctx, tenantID, user.ID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !ok {
+		return uuid.Nil, ErrForbidden
+	}
+	return tenantID, nil
+}
+
+// LookupByEmail finds an active account by email.
+func (s *Service) LookupByEmail(ctx context.Context, email string) (*User, error) {
+	user, err := s.repo.GetByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
 }
 
 // GetMe returns the authed profile.
@@ -159,7 +257,7 @@ func (s *Service) GetMe(ctx context.Context, userID uuid.UUID) (*User, error) {
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
-	return user, nil
+	return s.present(user), nil
 }
 
 // UpdateMe mutates the authed user.
@@ -171,18 +269,58 @@ func (s *Service) UpdateMe(ctx context.Context, userID uuid.UUID, req UpdateUser
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
+	if req.Version != nil && *req.Version != user.Version {
+		return nil, ErrVersionConflict
+	}
 	user.Name = strings.TrimSpace(s.sanitizer.Sanitize(req.Name))
 	if req.ProfileImage != nil {
-		user.ProfileImage = req.ProfileImage
+		s.applyProfileImage(ctx, user, *req.ProfileImage)
 	}
 	user.UpdatedAt = time.Now().UTC()
 	if err := s.repo.Update(ctx, user); err != nil {
 		return nil, err
 	}
-	return user, nil
+	return s.present(user), nil
+}
+
+// List returns a keyset page of users for admin dashboards.
+func (s *Service) List(ctx context.Context, filter UserFilter) (*UserPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+	filter = scopeToTenant(ctx, filter)
+	if err := filter.Cursor.Expect(filter.SortField); err != nil {
+		return nil, err
+	}
+	users, total, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	page := &UserPage{Total: total}
+	page.Users, page.Page = cursor.Paginate(users, filter.Limit, filter.Cursor, func(u User) cursor.Cursor {
+		return cursor.Cursor{Sort: filter.SortField, Value: u.SortValue(filter.SortField), ID: u.ID}
+	})
+	for i := range page.Users {
+		u := &page.Users[i]
+		s.present(u)
+		if filter.Query != "" && u.Snippet == nil {
+			snippet := search.Highlight(u.Name+" "+u.Email, filter.Query, 24)
+			u.Snippet = &snippet
+		}
+	}
+	return page, nil
 }
 
-// List returns paginated users for admin dashboards.
-func (s *Service) List(ctx context.Context, filter UserFilter) ([]User, int, error) {
-	return s.repo.List(ctx, filter)
+// Export streams every user matching filter, ignoring limit and cursor.
+func (s *Service) Export(ctx context.Context, filter UserFilter, fn func(*User) error) error {
+	filter.Cursor = nil
+	return s.repo.Stream(ctx, scopeToTenant(ctx, filter), fn)
+}
+
+// scopeToTenant restricts admin listings to the active tenant's members.
+func scopeToTenant(ctx context.Context, filter UserFilter) UserFilter {
+	if id, ok := tenancy.FromContext(ctx); ok && filter.TenantID == uuid.Nil {
+		filter.TenantID = id
+	}
+	return filter
 }
@@ -2,13 +2,18 @@ package user
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+	"github.com/kidpech/api_free_demo/pkg/filterexpr"
 )
 
 func TestRegisterCreatesUser(t *testing.T) {
@@ -76,6 +81,24 @@ func TestLoginInvalidPassword(t *testing.T) {
 	require.True(t, errors.Is(err, ErrInvalidCreds))
 }
 
+func TestLoginKeepsVersion(t *testing.T) {
+	repo := newFakeRepo()
+	service := NewService(repo, &fakeTokens{}, zap.NewNop(), true)
+	registered, err := service.Register(context.Background(), RegisterRequest{
+		Email:    "demo4@example.com",
+		Password: "Passw0rd!",
+		Name:     "Demo",
+	})
+	require.NoError(t, err)
+
+	resp, err := service.Login(context.Background(), LoginRequest{Email: "demo4@example.com", Password: "Passw0rd!"})
+	require.NoError(t, err)
+	require.NotNil(t, resp.User.LastLoginAt)
+	stored := repo.users[registered.User.ID]
+	require.Equal(t, registered.User.Version, stored.Version)
+	require.NotNil(t, stored.LastLoginAt)
+}
+
 func TestRefreshSuccess(t *testing.T) {
 	repo := newFakeRepo()
 	tokens := &fakeTokens{}
@@ -116,6 +139,152 @@ func TestRegisterDisabled(t *testing.T) {
 	require.True(t, errors.Is(err, ErrRegistrationDisabled))
 }
 
+func TestParseSortWhitelist(t *testing.T) {
+	field, desc, err := ParseSort("-last_login_at")
+	require.NoError(t, err)
+	require.Equal(t, "last_login_at", field)
+	require.True(t, desc)
+
+	field, desc, err = ParseSort("name_asc")
+	require.NoError(t, err)
+	require.Equal(t, "name", field)
+	require.False(t, desc)
+
+	_, _, err = ParseSort("password_hash")
+	require.True(t, errors.Is(err, ErrInvalidFilter))
+}
+
+func TestParseExpressionWhitelist(t *testing.T) {
+	expr, err := ParseExpression(`role eq "admin" and last_login_at eq null`)
+	require.NoError(t, err)
+	require.NotNil(t, expr)
+
+	_, err = ParseExpression(`password_hash ne null`)
+	require.ErrorIs(t, err, ErrInvalidFilter)
+	var ferr *filterexpr.Error
+	require.ErrorAs(t, err, &ferr)
+	require.Equal(t, "password_hash", ferr.Token)
+}
+
+func TestListRejectsForeignCursor(t *testing.T) {
+	service := NewService(newFakeRepo(), &fakeTokens{}, zap.NewNop(), true)
+
+	_, err := service.List(context.Background(), UserFilter{
+		SortField: "name",
+		Limit:     10,
+		Cursor:    &cursor.Cursor{Sort: "created_at", Value: "2025-01-01T00:00:00Z", ID: uuid.New()},
+	})
+
+	require.True(t, errors.Is(err, cursor.ErrInvalid))
+}
+
+func TestImportDryRunReportsEveryRowWithoutWriting(t *testing.T) {
+	repo := newFakeRepo()
+	service := NewService(repo, &fakeTokens{}, zap.NewNop(), false)
+	_, err := service.RegisterInvited(context.Background(), RegisterRequest{Email: "old@example.com", Password: "Passw0rd!", Name: "Old"})
+	require.NoError(t, err)
+
+	csvFile := "email,name,password,role\n" +
+		"new@example.com,New User,Passw0rd!,\n" +
+		"OLD@example.com,Old Renamed,,\n" +
+		"bad-email,X,short,\n" +
+		"new@example.com,Again,Passw0rd!,\n" +
+		"nopass@example.com,No Pass,,\n"
+	rows, parseErrs, err := ParseImport(strings.NewReader(csvFile), "csv")
+	require.NoError(t, err)
+
+	report, err := service.Import(context.Background(), rows, parseErrs,
+		ImportOptions{DryRun: true, OnDuplicate: ImportOnDuplicateUpdate})
+	require.NoError(t, err)
+	require.Equal(t, 1, repo.count())
+	require.Equal(t, []string{ImportActionCreate, ImportActionUpdate, ImportActionError, ImportActionError, ImportActionError},
+		[]string{report.Rows[0].Action, report.Rows[1].Action, report.Rows[2].Action, report.Rows[3].Action, report.Rows[4].Action})
+	require.Equal(t, 2, report.Rows[0].Line)
+	require.Contains(t, report.Rows[2].Details, "email")
+	require.Equal(t, "required", report.Rows[4].Details["password"])
+	require.Equal(t, 3, report.Failed)
+}
+
+func TestExportEscapesFormulasForImport(t *testing.T) {
+	u := &User{ID: uuid.New(), Email: "x@example.com", Name: "=HYPERLINK(\"http://evil\")", Role: "user"}
+	row := exportRow(u)
+	require.Equal(t, "'"+u.Name, row[2])
+
+	rows, _, err := ParseImport(strings.NewReader("email,name\n"+
+		u.Email+",'=SUM(A1)\n"+
+		u.Email+",''=quoted\n"+
+		u.Email+",'plain\n"), "csv")
+	require.NoError(t, err)
+	require.Equal(t, []string{"=SUM(A1)", "'=quoted", "'plain"}, []string{rows[0].Name, rows[1].Name, rows[2].Name})
+}
+
+func TestImportInviteLinkSetsPassword(t *testing.T) {
+	repo := newFakeRepo()
+	mailer := &fakeMailer{sent: map[string]string{}}
+	service := NewService(repo, &fakeTokens{}, zap.NewNop(), false,
+		WithMailer(mailer, "https://app.example.com/password/set", time.Hour))
+
+	rows, parseErrs, err := ParseImport(strings.NewReader(`{"email":"invitee@example.com","name":"Invitee"}`+"\n"), "ndjson")
+	require.NoError(t, err)
+	report, err := service.Import(context.Background(), rows, parseErrs,
+		ImportOptions{Notify: ImportNotifyInvite})
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Created)
+
+	body := mailer.sent["invitee@example.com"]
+	token := body[strings.Index(body, "token=")+len("token="):]
+	token = strings.TrimSpace(token)
+	require.NoError(t, service.SetPassword(context.Background(), SetPasswordRequest{Token: token, Password: "Chosen123!"}))
+	_, err = service.Login(context.Background(), LoginRequest{Email: "invitee@example.com", Password: "Chosen123!"})
+	require.NoError(t, err)
+
+	err = service.SetPassword(context.Background(), SetPasswordRequest{Token: token, Password: "Again1234!"})
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestUpdateMeChecksVersion(t *testing.T) {
+	service := NewService(newFakeRepo(), &fakeTokens{}, zap.NewNop(), true)
+	resp, err := service.Register(context.Background(), RegisterRequest{Email: "ver@example.com", Password: "Passw0rd!", Name: "Ver"})
+	require.NoError(t, err)
+	require.Equal(t, 1, resp.User.Version)
+
+	stale := 1
+	updated, err := service.UpdateMe(context.Background(), resp.User.ID, UpdateUserRequest{Name: "First", Version: &stale})
+	require.NoError(t, err)
+	require.Equal(t, 2, updated.Version)
+
+	_, err = service.UpdateMe(context.Background(), resp.User.ID, UpdateUserRequest{Name: "Second", Version: &stale})
+	require.ErrorIs(t, err, ErrVersionConflict)
+}
+
+func TestSummariesAndExpand(t *testing.T) {
+	profiles := &fakeProfiles{}
+	service := NewService(newFakeRepo(), &fakeTokens{}, zap.NewNop(), true, WithProfiles(profiles))
+	resp, err := service.Register(context.Background(), RegisterRequest{Email: "sum@example.com", Password: "Passw0rd!", Name: "Summed"})
+	require.NoError(t, err)
+
+	summaries, err := service.Summaries(context.Background(), []uuid.UUID{resp.User.ID, uuid.New()})
+	require.NoError(t, err)
+	require.Equal(t, map[uuid.UUID]Summary{resp.User.ID: {ID: resp.User.ID, Name: "Summed"}}, summaries)
+
+	profiles.list = []ProfileSummary{{ID: uuid.New(), FirstName: "Ada", LastName: "Lovelace", Visibility: "private"}}
+	me := resp.User
+	require.NoError(t, service.Expand(context.Background(), me, []string{ExpandProfiles}))
+	require.Equal(t, profiles.list, me.Profiles)
+	require.Equal(t, []uuid.UUID{me.ID}, profiles.calls)
+	require.ErrorIs(t, service.Expand(context.Background(), me, []string{"tenants"}), ErrInvalidExpand)
+}
+
+type fakeProfiles struct {
+	list  []ProfileSummary
+	calls []uuid.UUID
+}
+
+func (f *fakeProfiles) ProfileSummaries(ctx context.Context, userID uuid.UUID) ([]ProfileSummary, error) {
+	f.calls = append(f.calls, userID)
+	return f.list, nil
+}
+
 type fakeTokens struct {
 	userID uuid.UUID
 }
@@ -143,12 +312,14 @@ func (f *fakeTokens) ExtractUserID(refreshToken string) (uuid.UUID, error) {
 type fakeUserRepo struct {
 	users      map[uuid.UUID]*User
 	emailIndex map[string]uuid.UUID
+	tokens     map[string]*ActionToken
 }
 
 func newFakeRepo() *fakeUserRepo {
 	return &fakeUserRepo{
 		users:      make(map[uuid.UUID]*User),
 		emailIndex: make(map[string]uuid.UUID),
+		tokens:     make(map[string]*ActionToken),
 	}
 }
 
@@ -163,15 +334,29 @@ func (f *fakeUserRepo) Create(ctx context.Context, u *User) error {
 }
 
 func (f *fakeUserRepo) Update(ctx context.Context, u *User) error {
-	if _, ok := f.users[u.ID]; !ok {
+	stored, ok := f.users[u.ID]
+	if !ok {
 		return ErrUserNotFound
 	}
+	if stored.Version != u.Version {
+		return ErrVersionConflict
+	}
+	u.Version++
 	clone := *u
 	f.users[u.ID] = &clone
 	f.emailIndex[u.Email] = u.ID
 	return nil
 }
 
+func (f *fakeUserRepo) TouchLogin(ctx context.Context, id uuid.UUID, at time.Time) error {
+	stored, ok := f.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+	stored.LastLoginAt, stored.UpdatedAt = &at, at
+	return nil
+}
+
 func (f *fakeUserRepo) GetByEmail(ctx context.Context, email string) (*User, error) {
 	if id, ok := f.emailIndex[email]; ok {
 		clone := *f.users[id]
@@ -188,6 +373,16 @@ func (f *fakeUserRepo) GetByID(ctx context.Context, id uuid.UUID) (*User, error)
 	return nil, ErrUserNotFound
 }
 
+func (f *fakeUserRepo) ListByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	var result []User
+	for _, id := range ids {
+		if user, ok := f.users[id]; ok {
+			result = append(result, *user)
+		}
+	}
+	return result, nil
+}
+
 func (f *fakeUserRepo) List(ctx context.Context, filter UserFilter) ([]User, int, error) {
 	result := make([]User, 0, len(f.users))
 	for _, user := range f.users {
@@ -197,6 +392,54 @@ func (f *fakeUserRepo) List(ctx context.Context, filter UserFilter) ([]User, int
 	return result, len(result), nil
 }
 
+func (f *fakeUserRepo) Stream(ctx context.Context, filter UserFilter, fn func(*User) error) error {
+	for _, user := range f.users {
+		clone := *user
+		if err := fn(&clone); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeUserRepo) CreateActionToken(ctx context.Context, t *ActionToken) error {
+	clone := *t
+	f.tokens[t.TokenHash] = &clone
+	return nil
+}
+
+func (f *fakeUserRepo) ConsumeActionToken(ctx context.Context, hash string, now time.Time) (*ActionToken, error) {
+	t, ok := f.tokens[hash]
+	if !ok || t.UsedAt != nil || !t.ExpiresAt.After(now) {
+		return nil, ErrInvalidToken
+	}
+	t.UsedAt = &now
+	clone := *t
+	return &clone, nil
+}
+
+type fakeMailer struct {
+	sent map[string]string
+}
+
+func (m *fakeMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.sent[to] = body
+	return nil
+}
+
 func (f *fakeUserRepo) count() int {
 	return len(f.users)
 }
+
+func TestDeletedAtOnlyInAdminView(t *testing.T) {
+	deletedAt := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	u := &User{ID: uuid.New(), Email: "gone@example.com", DeletedAt: &deletedAt}
+
+	raw, err := json.Marshal(u)
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), "deleted_at")
+	raw, err = json.Marshal(AdminView(u))
+	require.NoError(t, err)
+	require.Contains(t, string(raw), `"deleted_at":"2025-01-02T00:00:00Z"`)
+	require.Contains(t, string(raw), `"email":"gone@example.com"`)
+}
@@ -17,10 +17,11 @@ import (
 
 // Claims extends JWT registered claims with app metadata.
 type Claims struct {
-	UserID    uuid.UUID `json:"user_id"`
-	Role      string    `json:"role"`
-	SecretVer string    `json:"sv"`
-	TokenType string    `json:"type"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Role      string     `json:"role"`
+	TenantID  *uuid.UUID `json:"tid,omitempty"`
+	SecretVer string     `json:"sv"`
+	TokenType string     `json:"type"`
 	jwt.RegisteredClaims
 }
 
@@ -64,6 +65,7 @@ func (m *Manager) RefreshTokens(ctx context.Context, u *user.User, token string)
 	if err := m.ensureRefreshValid(ctx, claims, token); err != nil {
 		return user.AuthTokens{}, err
 	}
+	u.TenantID = claims.TenantID
 	access, exp, err := m.issueAccess(u)
 	if err != nil {
 		return user.AuthTokens{}, err
@@ -99,6 +101,7 @@ func (m *Manager) issueAccess(u *user.User) (string, int64, error) {
 	claims := Claims{
 		UserID:    u.ID,
 		Role:      u.Role,
+		TenantID:  u.TenantID,
 		SecretVer: m.cfg.SecretVersion,
 		TokenType: "access",
 		RegisteredClaims: jwt.RegisteredClaims{
@@ -123,6 +126,7 @@ func (m *Manager) issueRefresh(u *user.User) (string, *Claims, error) {
 	claims := &Claims{
 		UserID:    u.ID,
 		Role:      u.Role,
+		TenantID:  u.TenantID,
 		SecretVer: m.cfg.SecretVersion,
 		TokenType: "refresh",
 		RegisteredClaims: jwt.RegisteredClaims{
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/kidpech/api_free_demo/internal/domain/customfield"
+)
+
+// CustomFieldRepository persists custom field definitions via sqlx.
+type CustomFieldRepository struct {
+	db *sqlx.DB
+}
+
+// NewCustomFieldRepository builds repo.
+func NewCustomFieldRepository(db *sqlx.DB) customfield.Repository {
+	return &CustomFieldRepository{db: db}
+}
+
+func (r *CustomFieldRepository) Create(ctx context.Context, def *customfield.Definition) error {
+	query := `INSERT INTO profile_field_definitions (id, tenant_id, field_key, label, type, required, enum_values, pattern, description, created_at, updated_at)
+		VALUES (:id, :tenant_id, :field_key, :label, :type, :required, :enum_values, :pattern, :description, :created_at, :updated_at)`
+	_, err := r.db.NamedExecContext(ctx, query, def)
+	return customFieldWriteError(err)
+}
+
+func (r *CustomFieldRepository) Update(ctx context.Context, def *customfield.Definition) error {
+	query := `UPDATE profile_field_definitions SET label = :label, type = :type, required = :required, enum_values = :enum_values,
+		pattern = :pattern, description = :description, updated_at = :updated_at WHERE id = :id`
+	res, err := r.db.NamedExecContext(ctx, query, def)
+	if err != nil {
+		return err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return customfield.ErrNotFound
+	}
+	return nil
+}
+
+func (r *CustomFieldRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, r.db.Rebind(`DELETE FROM profile_field_definitions WHERE id = ?`), id)
+	if err != nil {
+		return err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return customfield.ErrNotFound
+	}
+	return nil
+}
+
+func (r *CustomFieldRepository) GetByID(ctx context.Context, id uuid.UUID) (*customfield.Definition, error) {
+	var def customfield.Definition
+	if err := r.db.GetContext(ctx, &def, r.db.Rebind(`SELECT * FROM profile_field_definitions WHERE id = ?`), id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, customfield.ErrNotFound
+		}
+		return nil, err
+	}
+	return &def, nil
+}
+
+func (r *CustomFieldRepository) List(ctx context.Context, filter customfield.Filter) ([]customfield.Definition, error) {
+	query := `SELECT * FROM profile_field_definitions`
+	var args []interface{}
+	switch {
+	case filter.AllTenants:
+	case filter.TenantID != nil:
+		query += ` WHERE tenant_id IS NULL OR tenant_id = ?`
+		args = append(args, *filter.TenantID)
+	default:
+		query += ` WHERE tenant_id IS NULL`
+	}
+	query += ` ORDER BY field_key ASC, tenant_id IS NOT NULL, tenant_id ASC`
+	var defs []customfield.Definition
+	if err := r.db.SelectContext(ctx, &defs, r.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
+
+func customFieldWriteError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case isDuplicate(err):
+		return customfield.ErrKeyTaken
+	case isForeignKeyViolation(err):
+		return customfield.ErrTenantNotFound
+	}
+	return err
+}
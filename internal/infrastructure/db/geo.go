@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/kidpech/api_free_demo/pkg/geo"
+)
+
+// Distance backends, best first. Postgres uses PostGIS or earthdistance
+// when the extension is installed; MySQL and bare Postgres evaluate the
+// haversine formula.
+const (
+	distancePostGIS       = "postgis"
+	distanceEarthDistance = "earthdistance"
+	distanceHaversine     = "haversine"
+)
+
+// distanceSupport detects the distance backend once per pool.
+type distanceSupport struct {
+	mu       sync.Mutex
+	detected bool
+	backend  string
+}
+
+// detect returns the backend for db. A failed probe, for example under a
+// cancelled ctx, falls back to haversine for this call only and is retried
+// by the next one.
+func (d *distanceSupport) detect(ctx context.Context, db conn) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.detected {
+		return d.backend
+	}
+	backend, err := probeDistance(ctx, db)
+	if err != nil {
+		return distanceHaversine
+	}
+	d.detected, d.backend = true, backend
+	return backend
+}
+
+// probeDistance returns the best distance backend installed on db.
+func probeDistance(ctx context.Context, db conn) (string, error) {
+	if !isPostgres(db) {
+		return distanceHaversine, nil
+	}
+	var installed []string
+	query := `SELECT extname FROM pg_extension WHERE extname IN ('postgis', 'earthdistance')`
+	if err := db.SelectContext(ctx, &installed, query); err != nil {
+		return "", err
+	}
+	for _, backend := range []string{distancePostGIS, distanceEarthDistance} {
+		for _, name := range installed {
+			if name == backend {
+				return backend, nil
+			}
+		}
+	}
+	return distanceHaversine, nil
+}
+
+// distanceExpr returns the km distance between the latitude and longitude
+// columns and origin, with its arguments.
+func distanceExpr(backend string, origin geo.Point) (string, []interface{}) {
+	switch backend {
+	case distancePostGIS:
+		return "(ST_DistanceSphere(ST_MakePoint(longitude, latitude), ST_MakePoint(?::float8, ?::float8)) / 1000)",
+			[]interface{}{origin.Lng, origin.Lat}
+	case distanceEarthDistance:
+		// <@> measures statute miles.
+		return "((point(longitude, latitude) <@> point(?::float8, ?::float8)) * 1.609344)",
+			[]interface{}{origin.Lng, origin.Lat}
+	}
+	return haversineKm, []interface{}{origin.Lat, origin.Lat, origin.Lng}
+}
+
+// haversineKm takes the origin latitude twice, then its longitude. LEAST
+// guards ASIN against rounding just past 1 for antipodal points.
+var haversineKm = fmt.Sprintf("(2 * %g * ASIN(SQRT(LEAST(1, POWER(SIN(RADIANS(latitude - ?) / 2), 2) + "+
+	"COS(RADIANS(?)) * COS(RADIANS(latitude)) * POWER(SIN(RADIANS(longitude - ?) / 2), 2)))))", geo.EarthRadiusKm)
+
+// withinRadius returns a predicate keeping rows within radiusKm of origin:
+// a bounding box the (latitude, longitude) index can serve, then the exact
+// distance.
+func withinRadius(backend string, origin geo.Point, radiusKm float64) (string, []interface{}) {
+	box := geo.BoundingBox(origin, radiusKm)
+	clause := " AND latitude BETWEEN ? AND ?"
+	args := []interface{}{box.MinLat, box.MaxLat}
+	if box.CrossesAntimeridian() {
+		clause += " AND (longitude >= ? OR longitude <= ?)"
+	} else {
+		clause += " AND longitude BETWEEN ? AND ?"
+	}
+	args = append(args, box.MinLng, box.MaxLng)
+	distance, distanceArgs := distanceExpr(backend, origin)
+	return clause + " AND " + distance + " <= ?", append(append(args, distanceArgs...), radiusKm)
+}
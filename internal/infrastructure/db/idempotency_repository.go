@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/kidpech/api_free_demo/internal/infrastructure/idempotency"
+)
+
+// IdempotencyRepository stores idempotency records for deployments without
+// Redis. Expired rows are swept whenever a key is claimed.
+type IdempotencyRepository struct {
+	db *sqlx.DB
+}
+
+// NewIdempotencyRepository builds repo.
+func NewIdempotencyRepository(db *sqlx.DB) idempotency.Store {
+	return &IdempotencyRepository{db: db}
+}
+
+func (r *IdempotencyRepository) Begin(ctx context.Context, key, requestHash string, ttl time.Duration) (*idempotency.Record, bool, error) {
+	now := time.Now().UTC()
+	if _, err := r.db.ExecContext(ctx, r.db.Rebind(`DELETE FROM idempotency_keys WHERE expires_at < ?`), now); err != nil {
+		return nil, false, err
+	}
+	raw, err := json.Marshal(idempotency.Record{RequestHash: requestHash})
+	if err != nil {
+		return nil, false, err
+	}
+	query := r.db.Rebind(`INSERT INTO idempotency_keys (idempotency_key, record, expires_at) VALUES (?, ?, ?)`)
+	if _, err := r.db.ExecContext(ctx, query, key, string(raw), now.Add(ttl)); err == nil {
+		return nil, true, nil
+	} else if !isDuplicate(err) {
+		return nil, false, err
+	}
+	var stored string
+	err = r.db.GetContext(ctx, &stored, r.db.Rebind(`SELECT record FROM idempotency_keys WHERE idempotency_key = ?`), key)
+	if err == sql.ErrNoRows {
+		// Released between the two statements; claim it again.
+		return r.Begin(ctx, key, requestHash, ttl)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var rec idempotency.Record
+	if err := json.Unmarshal([]byte(stored), &rec); err != nil {
+		return nil, false, err
+	}
+	return &rec, false, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, key string, rec idempotency.Record, ttl time.Duration) error {
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	query := r.db.Rebind(`UPDATE idempotency_keys SET record = ?, expires_at = ? WHERE idempotency_key = ?`)
+	_, err = r.db.ExecContext(ctx, query, string(raw), time.Now().UTC().Add(ttl), key)
+	return err
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(`DELETE FROM idempotency_keys WHERE idempotency_key = ?`), key)
+	return err
+}
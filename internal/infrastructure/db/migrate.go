@@ -0,0 +1,509 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Migration errors.
+var (
+	ErrMigrationChecksum = errors.New("applied migration no longer matches its file")
+	ErrUnknownMigration  = errors.New("unknown migration version")
+	ErrIrreversible      = errors.New("migration has no down file")
+	ErrLegacyDirty       = errors.New("legacy schema_migrations table is dirty")
+)
+
+// migrationLockKey is the Postgres advisory lock key held while migrating.
+const migrationLockKey int64 = 0x6d6967726174 // "migrat"
+
+// Migration is one versioned schema change. Checksum is the hex SHA-256 of
+// Up; it is recorded when the migration is applied so later edits to an
+// applied file are caught.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus reports one migration known to this build, the database
+// or both.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	// Modified marks applied migrations whose file changed since.
+	Modified bool
+	// Missing marks applied migrations this build has no file for.
+	Missing bool
+}
+
+type appliedMigration struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+var migrationFile = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// LoadMigrations reads the NNN_name.up.sql and NNN_name.down.sql files in
+// dir, ordered by version. Other files are ignored and the down file is
+// optional.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %s: version must be a positive number", entry.Name())
+		}
+		body, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies versioned migrations and records them, with their
+// checksums, in schema_migrations. Every run holds a database-wide advisory
+// lock on a single connection, so replicas starting together apply each
+// migration once.
+type Migrator struct {
+	db         *sqlx.DB
+	postgres   bool
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+// NewMigrator loads the migrations for db's dialect from the mysql or
+// postgres directory of fsys.
+func NewMigrator(db *sqlx.DB, fsys fs.FS, logger *zap.Logger) (*Migrator, error) {
+	postgres := isPostgres(db)
+	dir := "mysql"
+	if postgres {
+		dir = "postgres"
+	}
+	migrations, err := LoadMigrations(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("load migrations: %w", err)
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Migrator{db: db, postgres: postgres, migrations: migrations, logger: logger}, nil
+}
+
+// migrationStep applies or reverts one migration.
+type migrationStep struct {
+	migration Migration
+	up        bool
+}
+
+// Up applies every pending migration and returns how many it applied.
+// Applied versions this build does not know, left by a newer release, are
+// kept as they are.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.run(ctx, func(applied map[int64]appliedMigration) ([]migrationStep, error) {
+		return m.pending(applied, math.MaxInt64), nil
+	})
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	return m.run(ctx, func(applied map[int64]appliedMigration) ([]migrationStep, error) {
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		if steps < len(versions) {
+			versions = versions[:steps]
+		}
+		return m.reverts(versions)
+	})
+}
+
+// To migrates to version: migrations above it are reverted, newest first,
+// then pending ones up to it are applied. Version 0 reverts everything.
+func (m *Migrator) To(ctx context.Context, version int64) (int, error) {
+	if version != 0 {
+		if _, ok := m.find(version); !ok {
+			return 0, fmt.Errorf("%w: %d", ErrUnknownMigration, version)
+		}
+	}
+	return m.run(ctx, func(applied map[int64]appliedMigration) ([]migrationStep, error) {
+		var above []int64
+		for v := range applied {
+			if v > version {
+				above = append(above, v)
+			}
+		}
+		sort.Slice(above, func(i, j int) bool { return above[i] > above[j] })
+		steps, err := m.reverts(above)
+		if err != nil {
+			return nil, err
+		}
+		return append(steps, m.pending(applied, version)...), nil
+	})
+}
+
+// Status lists every migration this build knows, plus applied ones it
+// does not, ordered by version. It takes no lock and changes nothing.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	columns, err := m.tableColumns(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	applied := map[int64]appliedMigration{}
+	if hasColumn(columns, "checksum") {
+		if applied, err = m.applied(ctx, m.db); err != nil {
+			return nil, err
+		}
+	}
+	var statuses []MigrationStatus
+	for _, mig := range m.migrations {
+		status := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if row, ok := applied[mig.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+			status.Modified = row.Checksum != mig.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+	for version, row := range applied {
+		if _, ok := m.find(version); !ok {
+			appliedAt := row.AppliedAt
+			statuses = append(statuses, MigrationStatus{Version: version, Name: row.Name, AppliedAt: &appliedAt, Missing: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// run takes the lock, checks applied checksums and executes the steps plan
+// picks, returning how many completed.
+func (m *Migrator) run(ctx context.Context, plan func(map[int64]appliedMigration) ([]migrationStep, error)) (int, error) {
+	done := 0
+	err := m.locked(ctx, func(c *sqlx.Conn) error {
+		if err := m.prepare(ctx, c); err != nil {
+			return err
+		}
+		applied, err := m.applied(ctx, c)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if row, ok := applied[mig.Version]; ok && row.Checksum != mig.Checksum {
+				return fmt.Errorf("%w: %d_%s", ErrMigrationChecksum, mig.Version, mig.Name)
+			}
+		}
+		steps, err := plan(applied)
+		if err != nil {
+			return err
+		}
+		for _, step := range steps {
+			if err := m.execute(ctx, c, step); err != nil {
+				return err
+			}
+			done++
+		}
+		return nil
+	})
+	return done, err
+}
+
+// pending returns the unapplied migrations up to target, oldest first.
+func (m *Migrator) pending(applied map[int64]appliedMigration, target int64) []migrationStep {
+	var steps []migrationStep
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok && mig.Version <= target {
+			steps = append(steps, migrationStep{migration: mig, up: true})
+		}
+	}
+	return steps
+}
+
+// reverts returns steps reverting versions in the order given.
+func (m *Migrator) reverts(versions []int64) ([]migrationStep, error) {
+	steps := make([]migrationStep, 0, len(versions))
+	for _, version := range versions {
+		mig, ok := m.find(version)
+		if !ok {
+			return nil, fmt.Errorf("%w: %d is applied but this build has no file for it", ErrUnknownMigration, version)
+		}
+		if strings.TrimSpace(mig.Down) == "" {
+			return nil, fmt.Errorf("%w: %d_%s", ErrIrreversible, mig.Version, mig.Name)
+		}
+		steps = append(steps, migrationStep{migration: mig})
+	}
+	return steps, nil
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	i := sort.Search(len(m.migrations), func(i int) bool { return m.migrations[i].Version >= version })
+	if i < len(m.migrations) && m.migrations[i].Version == version {
+		return m.migrations[i], true
+	}
+	return Migration{}, false
+}
+
+// execute runs one step and records it in schema_migrations. Postgres DDL
+// is transactional, so a failed step there leaves nothing behind. MySQL
+// commits DDL implicitly; its statements run one at a time and a failure
+// part way leaves the earlier ones applied.
+func (m *Migrator) execute(ctx context.Context, c *sqlx.Conn, step migrationStep) error {
+	mig, direction := step.migration, "up"
+	script := mig.Up
+	record := `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`
+	args := []interface{}{mig.Version, mig.Name, mig.Checksum, time.Now().UTC()}
+	if !step.up {
+		direction, script = "down", mig.Down
+		record, args = `DELETE FROM schema_migrations WHERE version = ?`, []interface{}{mig.Version}
+	}
+	started := time.Now()
+	err := m.transact(ctx, c, func(ex sqlx.ExecerContext) error {
+		if m.postgres {
+			// Without arguments pgx uses the simple protocol, which runs a
+			// whole script in one round trip.
+			if _, err := ex.ExecContext(ctx, script); err != nil {
+				return err
+			}
+		} else {
+			statements := splitStatements(script)
+			for i, stmt := range statements {
+				if _, err := ex.ExecContext(ctx, stmt); err != nil {
+					return fmt.Errorf("statement %d of %d: %w", i+1, len(statements), err)
+				}
+			}
+		}
+		_, err := ex.ExecContext(ctx, c.Rebind(record), args...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migrate %s %d_%s: %w", direction, mig.Version, mig.Name, err)
+	}
+	m.logger.Info("migration applied", zap.Int64("version", mig.Version), zap.String("name", mig.Name),
+		zap.String("direction", direction), zap.Duration("took", time.Since(started)))
+	return nil
+}
+
+// transact runs fn in a transaction on Postgres and directly on MySQL,
+// where DDL would commit the transaction anyway.
+func (m *Migrator) transact(ctx context.Context, c *sqlx.Conn, fn func(sqlx.ExecerContext) error) error {
+	if !m.postgres {
+		return fn(c)
+	}
+	tx, err := c.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// locked runs fn on a dedicated connection holding the migration lock.
+// Both backends tie the lock to the session, so every statement of a run
+// goes through that connection.
+func (m *Migrator) locked(ctx context.Context, fn func(*sqlx.Conn) error) (err error) {
+	c, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	unlock := `SELECT RELEASE_LOCK(CONCAT(DATABASE(), '.schema_migrations'))`
+	var unlockArgs []interface{}
+	if m.postgres {
+		_, err = c.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey)
+		unlock, unlockArgs = `SELECT pg_advisory_unlock($1)`, []interface{}{migrationLockKey}
+	} else {
+		// MySQL lock names are server-wide, so the name carries the schema.
+		var locked sql.NullInt64
+		err = c.GetContext(ctx, &locked, `SELECT GET_LOCK(CONCAT(DATABASE(), '.schema_migrations'), -1)`)
+		if err == nil && locked.Int64 != 1 {
+			err = errors.New("GET_LOCK failed")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("lock migrations: %w", err)
+	}
+	defer func() {
+		// Unlock even when ctx is done; a connection that may still hold the
+		// lock is discarded rather than returned to the pool.
+		if _, unlockErr := c.ExecContext(context.Background(), unlock, unlockArgs...); unlockErr != nil {
+			_ = c.Raw(func(interface{}) error { return driver.ErrBadConn })
+			if err == nil {
+				err = fmt.Errorf("unlock migrations: %w", unlockErr)
+			}
+		}
+	}()
+	return fn(c)
+}
+
+// prepare creates schema_migrations. A table left by the golang-migrate
+// CLI, which tracked only the last version, is renamed to
+// schema_migrations_legacy and every migration runs again; the Postgres
+// files are written to be re-runnable.
+func (m *Migrator) prepare(ctx context.Context, c *sqlx.Conn) error {
+	columns, err := m.tableColumns(ctx, c)
+	if err != nil {
+		return err
+	}
+	if hasColumn(columns, "checksum") {
+		return nil
+	}
+	if len(columns) > 0 {
+		var dirty []int64
+		if err := c.SelectContext(ctx, &dirty, `SELECT version FROM schema_migrations WHERE dirty`); err != nil {
+			return fmt.Errorf("read legacy schema_migrations: %w", err)
+		}
+		if len(dirty) > 0 {
+			return fmt.Errorf("%w at version %d; repair it before migrating", ErrLegacyDirty, dirty[0])
+		}
+		if _, err := c.ExecContext(ctx, `ALTER TABLE schema_migrations RENAME TO schema_migrations_legacy`); err != nil {
+			return err
+		}
+		m.logger.Warn("renamed legacy schema_migrations to schema_migrations_legacy; replaying all migrations")
+	}
+	create := `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`
+	if m.postgres {
+		create = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`
+	}
+	_, err = c.ExecContext(ctx, create)
+	return err
+}
+
+// tableColumns lists the columns of schema_migrations in the current
+// schema, none when the table does not exist.
+func (m *Migrator) tableColumns(ctx context.Context, q sqlx.QueryerContext) ([]string, error) {
+	schema := "DATABASE()"
+	if m.postgres {
+		schema = "current_schema()"
+	}
+	var columns []string
+	query := `SELECT column_name FROM information_schema.columns
+		WHERE table_schema = ` + schema + ` AND table_name = 'schema_migrations'`
+	if err := sqlx.SelectContext(ctx, q, &columns, query); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+func (m *Migrator) applied(ctx context.Context, q sqlx.QueryerContext) (map[int64]appliedMigration, error) {
+	var rows []appliedMigration
+	if err := sqlx.SelectContext(ctx, q, &rows, `SELECT version, name, checksum, applied_at FROM schema_migrations`); err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]appliedMigration, len(rows))
+	for _, row := range rows {
+		// CHAR columns may come back padded.
+		row.Checksum = strings.TrimSpace(row.Checksum)
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func hasColumn(columns []string, name string) bool {
+	for _, column := range columns {
+		if strings.EqualFold(column, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitStatements splits a MySQL script on semicolons outside quotes and
+// -- comments, dropping statements that hold nothing but comments.
+func splitStatements(script string) []string {
+	var statements []string
+	var quote byte
+	start, comment, code := 0, false, false
+	for i := 0; i < len(script); i++ {
+		ch := script[i]
+		switch {
+		case comment:
+			comment = ch != '\n'
+		case quote != 0:
+			if ch == '\\' && quote != '`' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '-' && strings.HasPrefix(script[i:], "--"):
+			comment = true
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote, code = ch, true
+		case ch == ';':
+			if code {
+				statements = append(statements, strings.TrimSpace(script[start:i]))
+			}
+			start, code = i+1, false
+		case ch != ' ' && ch != '\t' && ch != '\n' && ch != '\r':
+			code = true
+		}
+	}
+	if code {
+		statements = append(statements, strings.TrimSpace(script[start:]))
+	}
+	return statements
+}
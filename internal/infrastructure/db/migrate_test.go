@@ -0,0 +1,68 @@
+package db
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kidpech/api_free_demo/migrations"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pg/002_tags.up.sql":   {Data: []byte("CREATE TABLE tags (id INT);")},
+		"pg/002_tags.down.sql": {Data: []byte("DROP TABLE tags;")},
+		"pg/001_init.up.sql":   {Data: []byte("CREATE TABLE users (id INT);")},
+		"pg/README.md":         {Data: []byte("ignored")},
+	}
+	loaded, err := LoadMigrations(fsys, "pg")
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+	require.Equal(t, int64(1), loaded[0].Version)
+	require.Equal(t, "init", loaded[0].Name)
+	require.Empty(t, loaded[0].Down)
+	require.Equal(t, "DROP TABLE tags;", loaded[1].Down)
+	require.Len(t, loaded[1].Checksum, 64)
+
+	fsys["pg/003_orphan.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE x;")}
+	_, err = LoadMigrations(fsys, "pg")
+	require.ErrorContains(t, err, "no up file")
+
+	delete(fsys, "pg/003_orphan.down.sql")
+	fsys["pg/002_labels.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	_, err = LoadMigrations(fsys, "pg")
+	require.ErrorContains(t, err, "named both")
+}
+
+func TestEmbeddedMigrationsPairUp(t *testing.T) {
+	mysql, err := LoadMigrations(migrations.FS, "mysql")
+	require.NoError(t, err)
+	postgres, err := LoadMigrations(migrations.FS, "postgres")
+	require.NoError(t, err)
+	require.Equal(t, len(postgres), len(mysql))
+	for i := range mysql {
+		require.Equal(t, postgres[i].Version, mysql[i].Version)
+		require.Equal(t, postgres[i].Name, mysql[i].Name)
+		require.NotEmpty(t, mysql[i].Down, "mysql %d_%s", mysql[i].Version, mysql[i].Name)
+		require.NotEmpty(t, postgres[i].Down, "postgres %d_%s", postgres[i].Version, postgres[i].Name)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- leading comment; not a statement
+CREATE TABLE a (
+    -- inline; comment
+    note VARCHAR(10) DEFAULT 'x;y'
+);
+
+INSERT INTO a (note) VALUES ('it\'s; fine'), ("semi;colon");
+-- trailing comment
+`
+	require.Equal(t, []string{
+		"-- leading comment; not a statement\nCREATE TABLE a (\n    -- inline; comment\n    note VARCHAR(10) DEFAULT 'x;y'\n)",
+		`INSERT INTO a (note) VALUES ('it\'s; fine'), ("semi;colon")`,
+	}, splitStatements(script))
+	require.Empty(t, splitStatements("-- nothing here\n"))
+	require.Equal(t, []string{"SELECT 1"}, splitStatements("SELECT 1"))
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/internal/domain/profile"
+)
+
+// SaveGrant upserts with UPDATE-then-INSERT so the same SQL works on both
+// drivers. MySQL reports no affected rows for an update that changes
+// nothing, which then surfaces as a duplicate insert and is not an error.
+func (r *ProfileRepository) SaveGrant(ctx context.Context, g *profile.Grant) error {
+	update := r.db.Rebind(`UPDATE profile_grants SET access = ?, expires_at = ?, granted_by = ?, updated_at = ?
+		WHERE profile_id = ? AND user_id = ?`)
+	res, err := r.db.ExecContext(ctx, update, g.Access, g.ExpiresAt, g.GrantedBy, g.UpdatedAt, g.ProfileID, g.UserID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected > 0 {
+		return nil
+	}
+	insert := `INSERT INTO profile_grants (profile_id, user_id, access, granted_by, expires_at, created_at, updated_at)
+		VALUES (:profile_id, :user_id, :access, :granted_by, :expires_at, :created_at, :updated_at)`
+	_, err = r.db.NamedExecContext(ctx, insert, g)
+	switch {
+	case err == nil, isDuplicate(err):
+		return nil
+	case isForeignKeyViolation(err):
+		return profile.ErrGranteeNotFound
+	}
+	return err
+}
+
+func (r *ProfileRepository) ListGrants(ctx context.Context, profileID uuid.UUID) ([]profile.Grant, error) {
+	var grants []profile.Grant
+	query := r.db.Rebind(`SELECT * FROM profile_grants WHERE profile_id = ? ORDER BY created_at ASC, user_id ASC`)
+	if err := r.db.SelectContext(ctx, &grants, query, profileID); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+func (r *ProfileRepository) DeleteGrant(ctx context.Context, profileID, userID uuid.UUID) (bool, error) {
+	query := r.db.Rebind(`DELETE FROM profile_grants WHERE profile_id = ? AND user_id = ?`)
+	res, err := r.db.ExecContext(ctx, query, profileID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *ProfileRepository) ListShared(ctx context.Context, userID uuid.UUID, limit, offset int) ([]profile.Profile, int, error) {
+	scope, scopeArgs := tenantClause(ctx)
+	base := `FROM profiles p JOIN profile_grants g ON g.profile_id = p.id
+		WHERE g.user_id = ? AND (g.expires_at IS NULL OR g.expires_at > ?) AND p.deleted_at IS NULL` + scope
+	args := append([]interface{}{userID, time.Now().UTC()}, scopeArgs...)
+	var profiles []profile.Profile
+	query := r.db.Rebind(`SELECT p.*, g.access AS access, g.expires_at AS access_expires_at ` + base +
+		` ORDER BY g.updated_at DESC, p.id DESC LIMIT ? OFFSET ?`)
+	if err := r.db.SelectContext(ctx, &profiles, query, append(append([]interface{}{}, args...), limit, offset)...); err != nil {
+		return nil, 0, err
+	}
+	var total int
+	if err := r.db.GetContext(ctx, &total, r.db.Rebind("SELECT COUNT(*) "+base), args...); err != nil {
+		return nil, 0, err
+	}
+	return profiles, total, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -11,59 +12,57 @@ import (
 	"github.com/jmoiron/sqlx"
 
 	"github.com/kidpech/api_free_demo/internal/domain/profile"
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+	"github.com/kidpech/api_free_demo/pkg/filterexpr"
+	"github.com/kidpech/api_free_demo/pkg/tenancy"
 )
 
 // ProfileRepository persists profiles via sqlx.
 type ProfileRepository struct {
-	db *sqlx.DB
+	db       conn
+	distance *distanceSupport
 }
 
 // NewProfileRepository builds repo.
 func NewProfileRepository(db *sqlx.DB) profile.Repository {
-	return &ProfileRepository{db: db}
+	return &ProfileRepository{db: db, distance: &distanceSupport{}}
 }
 
+const insertProfileQuery = `INSERT INTO profiles (id, user_id, tenant_id, handle, visibility, field_visibility, first_name, last_name,
+	bio, profile_image, cover_image, date_of_birth, phone, phone_e164, website, location, country, latitude, longitude, custom_fields, created_at, updated_at, version)
+	VALUES (:id, :user_id, :tenant_id, :handle, :visibility, :field_visibility, :first_name, :last_name,
+		:bio, :profile_image, :cover_image, :date_of_birth, :phone, :phone_e164, :website, :location, :country, :latitude, :longitude, :custom_fields, :created_at, :updated_at, :version)`
+
 func (r *ProfileRepository) Create(ctx context.Context, p *profile.Profile) error {
-	query := `INSERT INTO profiles (id, user_id, first_name, last_name, bio, profile_image, cover_image, date_of_birth,
-		phone, website, location, created_at, updated_at, version)
-		VALUES (:id, :user_id, :first_name, :last_name, :bio, :profile_image, :cover_image, :date_of_birth,
-			:phone, :website, :location, :created_at, :updated_at, :version)`
-	_, err := r.db.NamedExecContext(ctx, query, p)
-	return err
+	p.TenantID = activeTenant(ctx)
+	_, err := r.db.NamedExecContext(ctx, insertProfileQuery, p)
+	return profileWriteError(err)
 }
 
-func (r *ProfileRepository) BulkCreate(ctx context.Context, profiles []*profile.Profile) error {
-	if len(profiles) == 0 {
-		return nil
-	}
-	query := `INSERT INTO profiles (id, user_id, first_name, last_name, bio, profile_image, cover_image, date_of_birth,
-		phone, website, location, created_at, updated_at, version)
-		VALUES (:id, :user_id, :first_name, :last_name, :bio, :profile_image, :cover_image, :date_of_birth,
-			:phone, :website, :location, :created_at, :updated_at, :version)`
-	tx, err := r.db.BeginTxx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	for _, p := range profiles {
-		if _, err := tx.NamedExecContext(ctx, query, p); err != nil {
-			tx.Rollback()
-			return err
-		}
-	}
-	return tx.Commit()
+// Transact runs fn against a repository bound to a single transaction.
+func (r *ProfileRepository) Transact(ctx context.Context, fn func(profile.Repository) error) error {
+	return transact(ctx, r.db, func(tx conn) error {
+		return fn(&ProfileRepository{db: tx, distance: r.distance})
+	})
 }
 
 func (r *ProfileRepository) Update(ctx context.Context, p *profile.Profile) error {
 	query := `UPDATE profiles SET first_name = :first_name, last_name = :last_name, bio = :bio, profile_image = :profile_image,
-		cover_image = :cover_image, date_of_birth = :date_of_birth, phone = :phone, website = :website, location = :location,
-		updated_at = :updated_at, version = :version WHERE id = :id AND user_id = :user_id`
-	res, err := r.db.NamedExecContext(ctx, query, p)
+		cover_image = :cover_image, profile_image_key = :profile_image_key, cover_image_key = :cover_image_key, date_of_birth = :date_of_birth, phone = :phone, phone_e164 = :phone_e164, website = :website, location = :location,
+		country = :country, latitude = :latitude, longitude = :longitude, handle = :handle, visibility = :visibility, field_visibility = :field_visibility, custom_fields = :custom_fields,
+		updated_at = :updated_at, version = :version WHERE id = :id AND user_id = :user_id AND version = :version - 1`
+	scope, scopeArgs := tenantClause(ctx)
+	query, args, err := sqlx.Named(query+scope, p)
 	if err != nil {
 		return err
 	}
+	res, err := r.db.ExecContext(ctx, r.db.Rebind(query), append(args, scopeArgs...)...)
+	if err != nil {
+		return profileWriteError(err)
+	}
 	affected, _ := res.RowsAffected()
 	if affected == 0 {
-		return profile.ErrNotFound
+		return profile.ErrVersionConflict
 	}
 	return nil
 }
@@ -78,13 +77,15 @@ func (r *ProfileRepository) Patch(ctx context.Context, profileID uuid.UUID, user
 	setParts = append(setParts, "updated_at = ?", "version = version + 1")
 	args = append(args, time.Now().UTC())
 
-	query := fmt.Sprintf("UPDATE profiles SET %s WHERE id = ? AND user_id = ? AND version = ?", strings.Join(setParts, ", "))
+	scope, scopeArgs := tenantClause(ctx)
+	query := fmt.Sprintf("UPDATE profiles SET %s WHERE id = ? AND user_id = ? AND version = ?%s", strings.Join(setParts, ", "), scope)
 	args = append(args, profileID, userID, version)
+	args = append(args, scopeArgs...)
 
 	rebind := r.db.Rebind(query)
 	res, err := r.db.ExecContext(ctx, rebind, args...)
 	if err != nil {
-		return nil, err
+		return nil, profileWriteError(err)
 	}
 	rows, _ := res.RowsAffected()
 	if rows == 0 {
@@ -94,9 +95,10 @@ func (r *ProfileRepository) Patch(ctx context.Context, profileID uuid.UUID, user
 }
 
 func (r *ProfileRepository) Delete(ctx context.Context, profileID uuid.UUID, userID uuid.UUID, hard bool, version int) error {
+	scope, scopeArgs := tenantClause(ctx)
 	if hard {
-		query := r.db.Rebind(`DELETE FROM profiles WHERE id = ? AND user_id = ? AND version = ?`)
-		res, err := r.db.ExecContext(ctx, query, profileID, userID, version)
+		query := r.db.Rebind(`DELETE FROM profiles WHERE id = ? AND user_id = ? AND version = ?` + scope)
+		res, err := r.db.ExecContext(ctx, query, append([]interface{}{profileID, userID, version}, scopeArgs...)...)
 		if err != nil {
 			return err
 		}
@@ -105,8 +107,8 @@ func (r *ProfileRepository) Delete(ctx context.Context, profileID uuid.UUID, use
 		}
 		return nil
 	}
-	query := r.db.Rebind(`UPDATE profiles SET deleted_at = ?, version = version + 1 WHERE id = ? AND user_id = ? AND version = ?`)
-	res, err := r.db.ExecContext(ctx, query, time.Now().UTC(), profileID, userID, version)
+	query := r.db.Rebind(`UPDATE profiles SET deleted_at = ?, version = version + 1 WHERE id = ? AND user_id = ? AND version = ?` + scope)
+	res, err := r.db.ExecContext(ctx, query, append([]interface{}{time.Now().UTC(), profileID, userID, version}, scopeArgs...)...)
 	if err != nil {
 		return err
 	}
@@ -116,33 +118,55 @@ func (r *ProfileRepository) Delete(ctx context.Context, profileID uuid.UUID, use
 	return nil
 }
 
-func (r *ProfileRepository) BulkDelete(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, hard bool) (int, error) {
+func (r *ProfileRepository) BulkDelete(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, hard bool) (int, []string, error) {
 	if len(ids) == 0 {
-		return 0, nil
-	}
-	query := `UPDATE profiles SET deleted_at = ? WHERE user_id = ? AND id IN (?)`
-	if hard {
-		query = `DELETE FROM profiles WHERE user_id = ? AND id IN (?)`
+		return 0, nil, nil
 	}
-	var args []interface{}
-	if hard {
-		query, args, _ = sqlx.In(query, userID, ids)
-	} else {
-		query, args, _ = sqlx.In(query, time.Now().UTC(), userID, ids)
+	scope, scopeArgs := tenantClause(ctx)
+	if !hard {
+		query, args, _ := sqlx.In(`UPDATE profiles SET deleted_at = ?, version = version + 1 WHERE user_id = ? AND id IN (?) AND deleted_at IS NULL`+scope,
+			time.Now().UTC(), userID, ids)
+		res, err := r.db.ExecContext(ctx, r.db.Rebind(query), append(args, scopeArgs...)...)
+		if err != nil {
+			return 0, nil, err
+		}
+		count, _ := res.RowsAffected()
+		return int(count), nil, nil
 	}
-	rebind := r.db.Rebind(query)
-	res, err := r.db.ExecContext(ctx, rebind, args...)
+	where, args, _ := sqlx.In(` FROM profiles WHERE user_id = ? AND id IN (?)`+scope, userID, ids)
+	args = append(args, scopeArgs...)
+	var count int
+	var keys []string
+	err := transact(ctx, r.db, func(tx conn) error {
+		var err error
+		if keys, err = imageKeys(ctx, tx, where, args...); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, tx.Rebind("DELETE"+where), args...)
+		if err != nil {
+			return err
+		}
+		affected, _ := res.RowsAffected()
+		count = int(affected)
+		return nil
+	})
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
-	count, _ := res.RowsAffected()
-	return int(count), nil
+	return count, keys, nil
 }
 
 func (r *ProfileRepository) GetByID(ctx context.Context, profileID uuid.UUID, userID uuid.UUID) (*profile.Profile, error) {
 	var p profile.Profile
-	query := r.db.Rebind(`SELECT * FROM profiles WHERE id = ? AND user_id = ? AND (deleted_at IS NULL)`)
-	err := r.db.GetContext(ctx, &p, query, profileID, userID)
+	scope, scopeArgs := tenantClause(ctx)
+	query := r.db.Rebind(`SELECT p.*,
+		CASE WHEN p.user_id = ? THEN 'owner' ELSE g.access END AS access,
+		CASE WHEN p.user_id = ? THEN NULL ELSE g.expires_at END AS access_expires_at
+		FROM profiles p
+		LEFT JOIN profile_grants g ON g.profile_id = p.id AND g.user_id = ? AND (g.expires_at IS NULL OR g.expires_at > ?)
+		WHERE p.id = ? AND p.deleted_at IS NULL AND (p.user_id = ? OR g.user_id IS NOT NULL)` + scope)
+	args := []interface{}{userID, userID, userID, time.Now().UTC(), profileID, userID}
+	err := r.db.GetContext(ctx, &p, query, append(args, scopeArgs...)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, profile.ErrNotFound
@@ -153,19 +177,81 @@ func (r *ProfileRepository) GetByID(ctx context.Context, profileID uuid.UUID, us
 }
 
 func (r *ProfileRepository) List(ctx context.Context, filter profile.Filter) ([]profile.Profile, int, error) {
-	base := `FROM profiles WHERE user_id = ? AND (deleted_at IS NULL)`
-	args := []interface{}{filter.UserID}
+	scope, scopeArgs := tenantClause(ctx)
+	base := `FROM profiles WHERE user_id = ? AND (deleted_at IS NULL)` + scope
+	args := append([]interface{}{filter.UserID}, scopeArgs...)
 	if filter.Search != "" {
-		args = append(args, "%"+filter.Search+"%", "%"+filter.Search+"%")
+		like := "%" + likeEscaper.Replace(filter.Search) + "%"
+		args = append(args, like, like)
 		base += ` AND (LOWER(first_name) LIKE LOWER(?) OR LOWER(last_name) LIKE LOWER(?))`
 	}
-	if filter.CursorTime != nil {
-		args = append(args, *filter.CursorTime)
-		base += ` AND created_at < ?`
+	if filter.Query != "" {
+		base += " AND " + profileFullText.where(isPostgres(r.db))
+		args = append(args, filter.Query)
+	}
+	if filter.Country != "" {
+		base += ` AND country = ?`
+		args = append(args, filter.Country)
+	}
+	if filter.PhoneE164 != "" {
+		base += ` AND phone_e164 = ?`
+		args = append(args, filter.PhoneE164)
+	}
+	for _, key := range sortedKeys(filter.CustomFields) {
+		base += " AND " + customFieldMatch(isPostgres(r.db))
+		args = append(args, customFieldPath(key, isPostgres(r.db)), filter.CustomFields[key])
+	}
+	if filter.Origin != nil {
+		clause, nearArgs := withinRadius(r.distance.detect(ctx, r.db), *filter.Origin, filter.RadiusKm)
+		base += clause
+		args = append(args, nearArgs...)
+	}
+	if len(filter.Tags) > 0 {
+		clause, tagArgs := tagMatch(filter.Tags, filter.TagMatch == profile.TagMatchAll)
+		base += clause
+		args = append(args, tagArgs...)
+	}
+	if filter.Where != nil {
+		clause, exprArgs := filterexpr.SQL(filter.Where, isPostgres(r.db))
+		base += " AND " + clause
+		args = append(args, exprArgs...)
 	}
 	countArgs := append([]interface{}{}, args...)
-	query := r.db.Rebind("SELECT * " + base + " ORDER BY created_at DESC LIMIT ? OFFSET ?")
-	queryArgs := append(countArgs, filter.Limit, filter.Offset)
+
+	// Full-text results rank by score and near results by distance;
+	// everything else walks the (created_at, id) keyset, using OFFSET only
+	// before the first cursor.
+	// Sparse fieldsets arrive as whitelisted column names.
+	columns := "*"
+	if len(filter.Columns) > 0 {
+		columns = strings.Join(filter.Columns, ", ")
+	}
+	selectList, where := "SELECT "+columns+" ", base
+	var selectArgs []interface{}
+	dir, cmp := cursor.Order(true, filter.Cursor)
+	order := fmt.Sprintf(" ORDER BY created_at %s, id %s", dir, dir)
+	offset := filter.Offset
+	if filter.Query != "" {
+		rank, n := profileFullText.selectColumns(isPostgres(r.db))
+		selectList = "SELECT " + columns + ", " + rank + " "
+		selectArgs = repeat(filter.Query, n)
+		order = " ORDER BY score DESC, created_at DESC, id DESC"
+	} else if filter.Origin != nil {
+		distance, distanceArgs := distanceExpr(r.distance.detect(ctx, r.db), *filter.Origin)
+		selectList = "SELECT " + columns + ", " + distance + " AS distance_km "
+		selectArgs = distanceArgs
+		order = " ORDER BY distance_km ASC, id ASC"
+	} else if filter.Cursor != nil {
+		ts, err := filter.Cursor.Time()
+		if err != nil {
+			return nil, 0, err
+		}
+		where += fmt.Sprintf(" AND (created_at %s ? OR (created_at = ? AND id %s ?))", cmp, cmp)
+		args = append(args, ts, ts, filter.Cursor.ID)
+		offset = 0
+	}
+	query := r.db.Rebind(selectList + where + order + " LIMIT ? OFFSET ?")
+	queryArgs := append(append(selectArgs, args...), filter.Limit+1, offset)
 	var profilesList []profile.Profile
 	if err := r.db.SelectContext(ctx, &profilesList, query, queryArgs...); err != nil {
 		return nil, 0, err
@@ -178,6 +264,105 @@ func (r *ProfileRepository) List(ctx context.Context, filter profile.Filter) ([]
 	return profilesList, total, nil
 }
 
+// Stream walks the user's live profiles with a single cursor so exports do
+// not hold the whole set in memory.
+func (r *ProfileRepository) Stream(ctx context.Context, userID uuid.UUID, fn func(*profile.Profile) error) error {
+	scope, scopeArgs := tenantClause(ctx)
+	query := r.db.Rebind(`SELECT * FROM profiles WHERE user_id = ? AND deleted_at IS NULL` + scope + ` ORDER BY created_at, id`)
+	rows, err := r.db.QueryxContext(ctx, query, append([]interface{}{userID}, scopeArgs...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p profile.Profile
+		if err := rows.StructScan(&p); err != nil {
+			return err
+		}
+		if err := fn(&p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *ProfileRepository) GetByHandle(ctx context.Context, handle string) (*profile.Profile, error) {
+	var p profile.Profile
+	scope, scopeArgs := tenantClause(ctx)
+	query := r.db.Rebind(`SELECT * FROM profiles WHERE handle = ? AND deleted_at IS NULL` + scope)
+	if err := r.db.GetContext(ctx, &p, query, append([]interface{}{handle}, scopeArgs...)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, profile.ErrNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ProfileRepository) SearchPublic(ctx context.Context, filter profile.PublicFilter) ([]profile.Profile, int, error) {
+	scope, scopeArgs := tenantClause(ctx)
+	base := `FROM profiles WHERE visibility = ? AND handle IS NOT NULL AND deleted_at IS NULL` + scope
+	args := append([]interface{}{profile.VisibilityPublic}, scopeArgs...)
+	if filter.Search != "" {
+		like := "%" + likeEscaper.Replace(strings.ToLower(filter.Search)) + "%"
+		base += ` AND (handle LIKE ? OR LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?)`
+		args = append(args, like, like, like)
+	}
+	if len(filter.Exclude) > 0 {
+		clause, excludeArgs, err := sqlx.In(` AND user_id NOT IN (?)`, filter.Exclude)
+		if err != nil {
+			return nil, 0, err
+		}
+		base += clause
+		args = append(args, excludeArgs...)
+	}
+	var profiles []profile.Profile
+	query := r.db.Rebind("SELECT * " + base + " ORDER BY handle ASC LIMIT ? OFFSET ?")
+	if err := r.db.SelectContext(ctx, &profiles, query, append(append([]interface{}{}, args...), filter.Limit, filter.Offset)...); err != nil {
+		return nil, 0, err
+	}
+	var total int
+	if err := r.db.GetContext(ctx, &total, r.db.Rebind("SELECT COUNT(*) "+base), args...); err != nil {
+		return nil, 0, err
+	}
+	return profiles, total, nil
+}
+
+// customFieldMatch compares one custom field, rendered as text, with a
+// value; it takes the field path and the value as placeholders. Keys are
+// restricted to identifiers, so the MySQL path needs no escaping.
+func customFieldMatch(pg bool) string {
+	if pg {
+		return "custom_fields ->> ?::text = ?"
+	}
+	return "JSON_UNQUOTE(JSON_EXTRACT(custom_fields, ?)) = ?"
+}
+
+func customFieldPath(key string, pg bool) string {
+	if pg {
+		return key
+	}
+	return "$." + key
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// profileWriteError maps the unique handle index onto the domain error;
+// the primary key is generated and cannot collide.
+func profileWriteError(err error) error {
+	if err != nil && isDuplicate(err) {
+		return profile.ErrHandleTaken
+	}
+	return err
+}
+
 func (r *ProfileRepository) fetchByID(ctx context.Context, id uuid.UUID) (*profile.Profile, error) {
 	var p profile.Profile
 	query := r.db.Rebind(`SELECT * FROM profiles WHERE id = ?`)
@@ -187,3 +372,19 @@ func (r *ProfileRepository) fetchByID(ctx context.Context, id uuid.UUID) (*profi
 	}
 	return &p, nil
 }
+
+// tenantClause scopes a query to the tenant carried by ctx. Rows created
+// outside any tenant are only visible when no tenant is active.
+func tenantClause(ctx context.Context) (string, []interface{}) {
+	if id, ok := tenancy.FromContext(ctx); ok {
+		return " AND tenant_id = ?", []interface{}{id}
+	}
+	return " AND tenant_id IS NULL", nil
+}
+
+func activeTenant(ctx context.Context) *uuid.UUID {
+	if id, ok := tenancy.FromContext(ctx); ok {
+		return &id
+	}
+	return nil
+}
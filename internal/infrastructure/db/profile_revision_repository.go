@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/internal/domain/profile"
+)
+
+func (r *ProfileRepository) CreateRevision(ctx context.Context, rev *profile.Revision) error {
+	query := `INSERT INTO profile_revisions (profile_id, version, snapshot, actor_id, created_at)
+		VALUES (:profile_id, :version, :snapshot, :actor_id, :created_at)`
+	_, err := r.db.NamedExecContext(ctx, query, rev)
+	return err
+}
+
+func (r *ProfileRepository) ListRevisions(ctx context.Context, profileID uuid.UUID, limit, offset int) ([]profile.Revision, int, error) {
+	var total int
+	if err := r.db.GetContext(ctx, &total, r.db.Rebind(`SELECT COUNT(*) FROM profile_revisions WHERE profile_id = ?`), profileID); err != nil {
+		return nil, 0, err
+	}
+	var revisions []profile.Revision
+	query := r.db.Rebind(`SELECT * FROM profile_revisions WHERE profile_id = ? ORDER BY version DESC LIMIT ? OFFSET ?`)
+	if err := r.db.SelectContext(ctx, &revisions, query, profileID, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return revisions, total, nil
+}
+
+func (r *ProfileRepository) GetRevision(ctx context.Context, profileID uuid.UUID, version int) (*profile.Revision, error) {
+	var rev profile.Revision
+	query := r.db.Rebind(`SELECT * FROM profile_revisions WHERE profile_id = ? AND version = ?`)
+	if err := r.db.GetContext(ctx, &rev, query, profileID, version); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, profile.ErrRevisionNotFound
+		}
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func (r *ProfileRepository) PreviousRevision(ctx context.Context, profileID uuid.UUID, version int) (*profile.Revision, error) {
+	var rev profile.Revision
+	query := r.db.Rebind(`SELECT * FROM profile_revisions WHERE profile_id = ? AND version < ? ORDER BY version DESC LIMIT 1`)
+	if err := r.db.GetContext(ctx, &rev, query, profileID, version); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, profile.ErrRevisionNotFound
+		}
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func (r *ProfileRepository) PruneRevisions(ctx context.Context, profileID uuid.UUID, version int) error {
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(`DELETE FROM profile_revisions WHERE profile_id = ? AND version <= ?`), profileID, version)
+	return err
+}
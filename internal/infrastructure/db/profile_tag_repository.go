@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/kidpech/api_free_demo/internal/domain/profile"
+)
+
+func (r *ProfileRepository) AddTags(ctx context.Context, profileID uuid.UUID, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	insert, conflict := "INSERT IGNORE INTO", ""
+	if isPostgres(r.db) {
+		insert, conflict = "INSERT INTO", " ON CONFLICT DO NOTHING"
+	}
+	now := time.Now().UTC()
+	rows := make([]string, len(tags))
+	args := make([]interface{}, 0, 3*len(tags))
+	for i, tag := range tags {
+		rows[i] = "(?, ?, ?)"
+		args = append(args, profileID, tag, now)
+	}
+	query := insert + " profile_tags (profile_id, tag, created_at) VALUES " + strings.Join(rows, ", ") + conflict
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(query), args...)
+	return err
+}
+
+func (r *ProfileRepository) RemoveTags(ctx context.Context, profileID uuid.UUID, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(`DELETE FROM profile_tags WHERE profile_id = ? AND tag IN (?)`, profileID, tags)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, r.db.Rebind(query), args...)
+	return err
+}
+
+func (r *ProfileRepository) ListTags(ctx context.Context, profileIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
+	out := make(map[uuid.UUID][]string, len(profileIDs))
+	if len(profileIDs) == 0 {
+		return out, nil
+	}
+	query, args, err := sqlx.In(`SELECT profile_id, tag FROM profile_tags WHERE profile_id IN (?) ORDER BY tag ASC`, profileIDs)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		ProfileID uuid.UUID `db:"profile_id"`
+		Tag       string    `db:"tag"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, r.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		out[row.ProfileID] = append(out[row.ProfileID], row.Tag)
+	}
+	return out, nil
+}
+
+func (r *ProfileRepository) TagCounts(ctx context.Context, userID uuid.UUID) ([]profile.TagCount, error) {
+	// profile_tags has no tenant_id, so the unqualified scope binds to p.
+	scope, scopeArgs := tenantClause(ctx)
+	query := r.db.Rebind(`SELECT t.tag, COUNT(*) AS count FROM profile_tags t JOIN profiles p ON p.id = t.profile_id
+		WHERE p.user_id = ? AND p.deleted_at IS NULL` + scope + ` GROUP BY t.tag ORDER BY count DESC, t.tag ASC`)
+	var counts []profile.TagCount
+	if err := r.db.SelectContext(ctx, &counts, query, append([]interface{}{userID}, scopeArgs...)...); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// tagMatch restricts a profile query to rows carrying any of tags, or all
+// of them. Tags must be distinct for the all count to hold.
+func tagMatch(tags []string, all bool) (string, []interface{}) {
+	marks := strings.TrimSuffix(strings.Repeat("?, ", len(tags)), ", ")
+	clause := " AND id IN (SELECT profile_id FROM profile_tags WHERE tag IN (" + marks + ")"
+	args := make([]interface{}, 0, len(tags)+1)
+	for _, tag := range tags {
+		args = append(args, tag)
+	}
+	if all {
+		clause += " GROUP BY profile_id HAVING COUNT(*) = ?"
+		args = append(args, len(tags))
+	}
+	return clause + ")", args
+}
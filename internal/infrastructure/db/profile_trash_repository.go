@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/internal/domain/profile"
+)
+
+func (r *ProfileRepository) GetDeleted(ctx context.Context, profileID uuid.UUID, userID uuid.UUID) (*profile.Profile, error) {
+	var p profile.Profile
+	scope, scopeArgs := tenantClause(ctx)
+	query := r.db.Rebind(`SELECT * FROM profiles WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL` + scope)
+	if err := r.db.GetContext(ctx, &p, query, append([]interface{}{profileID, userID}, scopeArgs...)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, profile.ErrNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ProfileRepository) ListTrash(ctx context.Context, userID uuid.UUID, limit, offset int) ([]profile.Profile, int, error) {
+	scope, scopeArgs := tenantClause(ctx)
+	base := `FROM profiles WHERE user_id = ? AND deleted_at IS NOT NULL` + scope
+	args := append([]interface{}{userID}, scopeArgs...)
+	var total int
+	if err := r.db.GetContext(ctx, &total, r.db.Rebind("SELECT COUNT(*) "+base), args...); err != nil {
+		return nil, 0, err
+	}
+	var profiles []profile.Profile
+	query := r.db.Rebind("SELECT * " + base + " ORDER BY deleted_at DESC, id DESC LIMIT ? OFFSET ?")
+	if err := r.db.SelectContext(ctx, &profiles, query, append(args, limit, offset)...); err != nil {
+		return nil, 0, err
+	}
+	return profiles, total, nil
+}
+
+func (r *ProfileRepository) Restore(ctx context.Context, profileID uuid.UUID, userID uuid.UUID, version int) (*profile.Profile, error) {
+	scope, scopeArgs := tenantClause(ctx)
+	query := r.db.Rebind(`UPDATE profiles SET deleted_at = NULL, updated_at = ?, version = version + 1
+		WHERE id = ? AND user_id = ? AND version = ? AND deleted_at IS NOT NULL` + scope)
+	res, err := r.db.ExecContext(ctx, query, append([]interface{}{time.Now().UTC(), profileID, userID, version}, scopeArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return nil, profile.ErrVersionConflict
+	}
+	return r.fetchByID(ctx, profileID)
+}
+
+func (r *ProfileRepository) PurgeDeleted(ctx context.Context, before time.Time) (count int, keys []string, err error) {
+	where := ` FROM profiles WHERE deleted_at IS NOT NULL AND deleted_at < ?`
+	err = transact(ctx, r.db, func(tx conn) error {
+		if keys, err = imageKeys(ctx, tx, where, before); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, tx.Rebind("DELETE"+where), before)
+		if err != nil {
+			return err
+		}
+		affected, _ := res.RowsAffected()
+		count = int(affected)
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return count, keys, nil
+}
+
+// imageKeys locks the rows matched by from (a FROM ... WHERE clause) and
+// returns their stored image keys, so the blobs can be discarded once the
+// rows are gone.
+func imageKeys(ctx context.Context, c conn, from string, args ...interface{}) ([]string, error) {
+	var rows []struct {
+		ProfileKey *string `db:"profile_image_key"`
+		CoverKey   *string `db:"cover_image_key"`
+	}
+	query := c.Rebind("SELECT profile_image_key, cover_image_key" + from + " FOR UPDATE")
+	if err := c.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, row := range rows {
+		for _, key := range []*string{row.ProfileKey, row.CoverKey} {
+			if key != nil && *key != "" {
+				keys = append(keys, *key)
+			}
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// likeEscaper escapes LIKE wildcards in user input; both dialects default
+// to backslash as the escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// isPostgres reports whether db talks to Postgres (via the pgx driver).
+func isPostgres(db conn) bool {
+	switch db.DriverName() {
+	case "pgx", "postgres":
+		return true
+	}
+	return false
+}
+
+// fullText describes a searchable document in both dialects. The Postgres
+// document and the MySQL column list must match the indexes created by the
+// migrations, otherwise the planner falls back to sequential scans.
+type fullText struct {
+	document string // Postgres tsvector expression
+	columns  string // MySQL FULLTEXT column list
+	text     string // plain text Postgres builds headlines from
+}
+
+var profileFullText = fullText{
+	document: `(setweight(to_tsvector('simple', coalesce(first_name, '') || ' ' || coalesce(last_name, '')), 'A') || ` +
+		`setweight(to_tsvector('simple', coalesce(bio, '')), 'B') || ` +
+		`setweight(to_tsvector('simple', coalesce(location, '')), 'C'))`,
+	columns: "first_name, last_name, bio, location",
+	text:    "concat_ws(' ', first_name, last_name, bio, location)",
+}
+
+var userFullText = fullText{
+	document: `(setweight(to_tsvector('simple', coalesce(name, '')), 'A') || ` +
+		`setweight(to_tsvector('simple', coalesce(email, '')), 'B'))`,
+	columns: "name, email",
+	text:    "concat_ws(' ', name, email)",
+}
+
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MinWords=8, MaxWords=24, MaxFragments=2"
+
+// where returns a predicate taking the query as its single placeholder.
+func (f fullText) where(pg bool) string {
+	if pg {
+		return fmt.Sprintf("%s @@ websearch_to_tsquery('simple', ?)", f.document)
+	}
+	return fmt.Sprintf("MATCH(%s) AGAINST (? IN NATURAL LANGUAGE MODE)", f.columns)
+}
+
+// selectColumns returns the score and snippet columns plus how many query
+// placeholders they use. MySQL has no headline function, so its snippet is
+// NULL and the service highlights in Go.
+func (f fullText) selectColumns(pg bool) (string, int) {
+	if pg {
+		return fmt.Sprintf("ts_rank(%s, websearch_to_tsquery('simple', ?)) AS score, "+
+			"ts_headline('simple', %s, websearch_to_tsquery('simple', ?), '%s') AS snippet",
+			f.document, f.text, headlineOptions), 2
+	}
+	return fmt.Sprintf("MATCH(%s) AGAINST (? IN NATURAL LANGUAGE MODE) AS score, NULL AS snippet", f.columns), 1
+}
+
+// repeat returns n copies of v for placeholder argument lists.
+func repeat(v interface{}, n int) []interface{} {
+	out := make([]interface{}, n)
+	for i := range out {
+		out[i] = v
+	}
+	return out
+}
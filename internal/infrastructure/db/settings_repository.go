@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/kidpech/api_free_demo/internal/domain/settings"
+)
+
+// SettingsRepository stores settings documents as JSON columns.
+type SettingsRepository struct {
+	db *sqlx.DB
+}
+
+// NewSettingsRepository builds repo.
+func NewSettingsRepository(db *sqlx.DB) settings.Repository {
+	return &SettingsRepository{db: db}
+}
+
+func (r *SettingsRepository) GetUser(ctx context.Context, userID uuid.UUID) (map[string]interface{}, error) {
+	return r.load(ctx, `SELECT data FROM user_settings WHERE user_id = ?`, userID)
+}
+
+func (r *SettingsRepository) SaveUser(ctx context.Context, userID uuid.UUID, values map[string]interface{}) error {
+	return r.save(ctx, "user_settings", "user_id", userID, values)
+}
+
+func (r *SettingsRepository) GetTenantDefaults(ctx context.Context, tenantID uuid.UUID) (map[string]interface{}, error) {
+	return r.load(ctx, `SELECT data FROM tenant_settings WHERE tenant_id = ?`, tenantID)
+}
+
+func (r *SettingsRepository) SaveTenantDefaults(ctx context.Context, tenantID uuid.UUID, values map[string]interface{}) error {
+	err := r.save(ctx, "tenant_settings", "tenant_id", tenantID, values)
+	if err != nil && isForeignKeyViolation(err) {
+		return settings.ErrTenantNotFound
+	}
+	return err
+}
+
+func (r *SettingsRepository) load(ctx context.Context, query string, id uuid.UUID) (map[string]interface{}, error) {
+	var raw []byte
+	if err := r.db.GetContext(ctx, &raw, r.db.Rebind(query), id); err != nil {
+		if err == sql.ErrNoRows {
+			return map[string]interface{}{}, nil
+		}
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	if len(raw) == 0 {
+		return values, nil
+	}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// save upserts with UPDATE-then-INSERT so the same SQL works on both drivers.
+func (r *SettingsRepository) save(ctx context.Context, table, keyColumn string, id uuid.UUID, values map[string]interface{}) error {
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	update := r.db.Rebind(`UPDATE ` + table + ` SET data = ?, updated_at = ? WHERE ` + keyColumn + ` = ?`)
+	res, err := r.db.ExecContext(ctx, update, string(raw), now, id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected > 0 {
+		return nil
+	}
+	insert := r.db.Rebind(`INSERT INTO ` + table + ` (` + keyColumn + `, data, updated_at) VALUES (?, ?, ?)`)
+	_, err = r.db.ExecContext(ctx, insert, id, string(raw), now)
+	return err
+}
@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/kidpech/api_free_demo/internal/domain/social"
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+)
+
+// SocialRepository persists the user graph via sqlx.
+type SocialRepository struct {
+	db conn
+}
+
+// NewSocialRepository builds repo.
+func NewSocialRepository(db *sqlx.DB) social.Repository {
+	return &SocialRepository{db: db}
+}
+
+// Transact runs fn against a repository bound to a single transaction.
+func (r *SocialRepository) Transact(ctx context.Context, fn func(social.Repository) error) error {
+	return transact(ctx, r.db, func(tx conn) error {
+		return fn(&SocialRepository{db: tx})
+	})
+}
+
+// insertIgnore returns the dialect's insert prefix and suffix for rows that
+// may already exist.
+func (r *SocialRepository) insertIgnore() (string, string) {
+	if isPostgres(r.db) {
+		return "INSERT INTO", " ON CONFLICT DO NOTHING"
+	}
+	return "INSERT IGNORE INTO", ""
+}
+
+func (r *SocialRepository) AddFollow(ctx context.Context, followerID, followeeID uuid.UUID, at time.Time) error {
+	insert, conflict := r.insertIgnore()
+	query := insert + " user_follows (follower_id, followee_id, created_at) VALUES (?, ?, ?)" + conflict
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(query), followerID, followeeID, at)
+	return err
+}
+
+func (r *SocialRepository) RemoveFollow(ctx context.Context, followerID, followeeID uuid.UUID) error {
+	query := r.db.Rebind(`DELETE FROM user_follows WHERE follower_id = ? AND followee_id = ?`)
+	_, err := r.db.ExecContext(ctx, query, followerID, followeeID)
+	return err
+}
+
+func (r *SocialRepository) GetConnection(ctx context.Context, userA, userB uuid.UUID) (*social.Connection, error) {
+	var c social.Connection
+	query := r.db.Rebind(`SELECT * FROM user_connections WHERE user_a = ? AND user_b = ?`)
+	if err := r.db.GetContext(ctx, &c, query, userA, userB); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *SocialRepository) CreateConnection(ctx context.Context, c *social.Connection) error {
+	query := `INSERT INTO user_connections (user_a, user_b, requester_id, status, created_at, updated_at)
+		VALUES (:user_a, :user_b, :requester_id, :status, :created_at, :updated_at)`
+	_, err := r.db.NamedExecContext(ctx, query, c)
+	if err != nil && isDuplicate(err) {
+		return social.ErrRequestPending
+	}
+	return err
+}
+
+func (r *SocialRepository) UpdateConnection(ctx context.Context, c *social.Connection) error {
+	query := r.db.Rebind(`UPDATE user_connections SET status = ?, updated_at = ? WHERE user_a = ? AND user_b = ?`)
+	_, err := r.db.ExecContext(ctx, query, c.Status, c.UpdatedAt, c.UserA, c.UserB)
+	return err
+}
+
+func (r *SocialRepository) DeleteConnection(ctx context.Context, userA, userB uuid.UUID) (bool, error) {
+	query := r.db.Rebind(`DELETE FROM user_connections WHERE user_a = ? AND user_b = ?`)
+	res, err := r.db.ExecContext(ctx, query, userA, userB)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *SocialRepository) AddBlock(ctx context.Context, blockerID, blockedID uuid.UUID, at time.Time) error {
+	insert, conflict := r.insertIgnore()
+	query := insert + " user_blocks (blocker_id, blocked_id, created_at) VALUES (?, ?, ?)" + conflict
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(query), blockerID, blockedID, at)
+	return err
+}
+
+func (r *SocialRepository) RemoveBlock(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	query := r.db.Rebind(`DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?`)
+	_, err := r.db.ExecContext(ctx, query, blockerID, blockedID)
+	return err
+}
+
+func (r *SocialRepository) Blocked(ctx context.Context, a, b uuid.UUID) (bool, error) {
+	var n int
+	query := r.db.Rebind(`SELECT COUNT(*) FROM user_blocks
+		WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)`)
+	if err := r.db.GetContext(ctx, &n, query, a, b, b, a); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *SocialRepository) BlockedIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	query := r.db.Rebind(`SELECT blocked_id FROM user_blocks WHERE blocker_id = ?
+		UNION SELECT blocker_id FROM user_blocks WHERE blocked_id = ?`)
+	if err := r.db.SelectContext(ctx, &ids, query, userID, userID); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *SocialRepository) Sever(ctx context.Context, a, b uuid.UUID) error {
+	follows := r.db.Rebind(`DELETE FROM user_follows
+		WHERE (follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)`)
+	if _, err := r.db.ExecContext(ctx, follows, a, b, b, a); err != nil {
+		return err
+	}
+	connections := r.db.Rebind(`DELETE FROM user_connections
+		WHERE (user_a = ? AND user_b = ?) OR (user_a = ? AND user_b = ?)`)
+	if _, err := r.db.ExecContext(ctx, connections, a, b, b, a); err != nil {
+		return err
+	}
+	grants := r.db.Rebind(`DELETE FROM profile_grants
+		WHERE (user_id = ? AND profile_id IN (SELECT id FROM profiles WHERE user_id = ?))
+		OR (user_id = ? AND profile_id IN (SELECT id FROM profiles WHERE user_id = ?))`)
+	_, err := r.db.ExecContext(ctx, grants, a, b, b, a)
+	return err
+}
+
+func (r *SocialRepository) ConnectedIDs(ctx context.Context, userID uuid.UUID, candidates []uuid.UUID) ([]uuid.UUID, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	query, args, err := sqlx.In(`SELECT user_b FROM user_connections WHERE user_a = ? AND status = ? AND user_b IN (?)
+		UNION SELECT user_a FROM user_connections WHERE user_b = ? AND status = ? AND user_a IN (?)`,
+		userID, social.StatusAccepted, candidates, userID, social.StatusAccepted, candidates)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uuid.UUID
+	if err := r.db.SelectContext(ctx, &ids, r.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// otherParty selects the connected user that is not the placeholder user.
+const otherParty = "CASE WHEN user_a = ? THEN user_b ELSE user_a END"
+
+// edgeQueries select (user_id, since) rows for each listing kind, taking
+// the listed user as every placeholder.
+var edgeQueries = map[string]string{
+	social.KindFollowers: `SELECT follower_id AS user_id, created_at AS since FROM user_follows WHERE followee_id = ?`,
+	social.KindFollowing: `SELECT followee_id AS user_id, created_at AS since FROM user_follows WHERE follower_id = ?`,
+	social.KindConnections: `SELECT ` + otherParty + ` AS user_id, updated_at AS since FROM user_connections
+		WHERE (user_a = ? OR user_b = ?) AND status = '` + social.StatusAccepted + `'`,
+	social.KindIncoming: `SELECT requester_id AS user_id, created_at AS since FROM user_connections
+		WHERE (user_a = ? OR user_b = ?) AND status = '` + social.StatusPending + `' AND requester_id <> ?`,
+	social.KindOutgoing: `SELECT ` + otherParty + ` AS user_id, created_at AS since FROM user_connections
+		WHERE requester_id = ? AND status = '` + social.StatusPending + `'`,
+	social.KindBlocks: `SELECT blocked_id AS user_id, created_at AS since FROM user_blocks WHERE blocker_id = ?`,
+}
+
+func (r *SocialRepository) List(ctx context.Context, filter social.Filter) ([]social.Edge, error) {
+	inner, ok := edgeQueries[filter.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown graph listing %q", filter.Kind)
+	}
+	query := `SELECT e.user_id, e.since FROM (` + inner + `) e
+		JOIN users u ON u.id = e.user_id AND u.deleted_at IS NULL WHERE 1 = 1`
+	args := repeat(filter.UserID, strings.Count(inner, "?"))
+	if filter.Viewer != uuid.Nil {
+		query += ` AND e.user_id NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = ?)
+			AND e.user_id NOT IN (SELECT blocker_id FROM user_blocks WHERE blocked_id = ?)`
+		args = append(args, filter.Viewer, filter.Viewer)
+	}
+	dir, cmp := cursor.Order(true, filter.Cursor)
+	if filter.Cursor != nil {
+		since, err := filter.Cursor.Time()
+		if err != nil {
+			return nil, err
+		}
+		query += fmt.Sprintf(" AND (e.since %s ? OR (e.since = ? AND e.user_id %s ?))", cmp, cmp)
+		args = append(args, since, since, filter.Cursor.ID)
+	}
+	query += fmt.Sprintf(" ORDER BY e.since %s, e.user_id %s LIMIT ?", dir, dir)
+	args = append(args, filter.Limit+1)
+	var edges []social.Edge
+	if err := r.db.SelectContext(ctx, &edges, r.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	return edges, nil
+}
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/kidpech/api_free_demo/internal/domain/tenant"
+)
+
+// TenantRepository persists tenants and memberships via sqlx.
+type TenantRepository struct {
+	db conn
+}
+
+// NewTenantRepository builds repo.
+func NewTenantRepository(db *sqlx.DB) tenant.Repository {
+	return &TenantRepository{db: db}
+}
+
+// Transact runs fn against a repository bound to a single transaction.
+func (r *TenantRepository) Transact(ctx context.Context, fn func(tenant.Repository) error) error {
+	return transact(ctx, r.db, func(tx conn) error {
+		return fn(&TenantRepository{db: tx})
+	})
+}
+
+func (r *TenantRepository) Create(ctx context.Context, t *tenant.Tenant) error {
+	query := `INSERT INTO tenants (id, slug, name, created_at, updated_at)
+		VALUES (:id, :slug, :name, :created_at, :updated_at)`
+	_, err := r.db.NamedExecContext(ctx, query, t)
+	if err != nil && isDuplicate(err) {
+		return tenant.ErrDuplicateSlug
+	}
+	return err
+}
+
+func (r *TenantRepository) GetByID(ctx context.Context, id uuid.UUID) (*tenant.Tenant, error) {
+	var t tenant.Tenant
+	query := r.db.Rebind(`SELECT * FROM tenants WHERE id = ?`)
+	if err := r.db.GetContext(ctx, &t, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, tenant.ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *TenantRepository) GetBySlug(ctx context.Context, slug string) (*tenant.Tenant, error) {
+	var t tenant.Tenant
+	query := r.db.Rebind(`SELECT * FROM tenants WHERE slug = ?`)
+	if err := r.db.GetContext(ctx, &t, query, slug); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, tenant.ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *TenantRepository) List(ctx context.Context, limit, offset int) ([]tenant.Tenant, int, error) {
+	var tenants []tenant.Tenant
+	query := r.db.Rebind(`SELECT * FROM tenants ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`)
+	if err := r.db.SelectContext(ctx, &tenants, query, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM tenants`); err != nil {
+		return nil, 0, err
+	}
+	return tenants, total, nil
+}
+
+func (r *TenantRepository) UpsertMember(ctx context.Context, m *tenant.Membership) error {
+	update := r.db.Rebind(`UPDATE tenant_memberships SET role = ?, updated_at = ? WHERE tenant_id = ? AND user_id = ?`)
+	res, err := r.db.ExecContext(ctx, update, m.Role, m.UpdatedAt, m.TenantID, m.UserID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected > 0 {
+		return nil
+	}
+	insert := `INSERT INTO tenant_memberships (tenant_id, user_id, role, created_at, updated_at)
+		VALUES (:tenant_id, :user_id, :role, :created_at, :updated_at)`
+	if _, err := r.db.NamedExecContext(ctx, insert, m); err != nil {
+		if isForeignKeyViolation(err) {
+			return tenant.ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *TenantRepository) RemoveMember(ctx context.Context, tenantID, userID uuid.UUID) error {
+	query := r.db.Rebind(`DELETE FROM tenant_memberships WHERE tenant_id = ? AND user_id = ?`)
+	res, err := r.db.ExecContext(ctx, query, tenantID, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return tenant.ErrMemberNotFound
+	}
+	return nil
+}
+
+func (r *TenantRepository) GetMember(ctx context.Context, tenantID, userID uuid.UUID) (*tenant.Membership, error) {
+	var m tenant.Membership
+	query := r.db.Rebind(`SELECT * FROM tenant_memberships WHERE tenant_id = ? AND user_id = ?`)
+	if err := r.db.GetContext(ctx, &m, query, tenantID, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, tenant.ErrMemberNotFound
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *TenantRepository) ListMembers(ctx context.Context, tenantID uuid.UUID) ([]tenant.Membership, error) {
+	var members []tenant.Membership
+	query := r.db.Rebind(`SELECT * FROM tenant_memberships WHERE tenant_id = ? ORDER BY created_at ASC`)
+	if err := r.db.SelectContext(ctx, &members, query, tenantID); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *TenantRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]tenant.Membership, error) {
+	var members []tenant.Membership
+	query := r.db.Rebind(`SELECT * FROM tenant_memberships WHERE user_id = ? ORDER BY created_at ASC`)
+	if err := r.db.SelectContext(ctx, &members, query, userID); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *TenantRepository) CountOwners(ctx context.Context, tenantID uuid.UUID) (int, error) {
+	var count int
+	query := r.db.Rebind(`SELECT COUNT(*) FROM tenant_memberships WHERE tenant_id = ? AND role = ?`)
+	err := r.db.GetContext(ctx, &count, query, tenantID, tenant.RoleOwner)
+	return count, err
+}
+
+func (r *TenantRepository) CreateInvitation(ctx context.Context, inv *tenant.Invitation) error {
+	query := `INSERT INTO tenant_invitations (id, tenant_id, email, role, token_hash, invited_by, expires_at, created_at)
+		VALUES (:id, :tenant_id, :email, :role, :token_hash, :invited_by, :expires_at, :created_at)`
+	_, err := r.db.NamedExecContext(ctx, query, inv)
+	return err
+}
+
+func (r *TenantRepository) GetInvitation(ctx context.Context, tenantID, id uuid.UUID) (*tenant.Invitation, error) {
+	var inv tenant.Invitation
+	query := r.db.Rebind(`SELECT * FROM tenant_invitations WHERE id = ? AND tenant_id = ?`)
+	if err := r.db.GetContext(ctx, &inv, query, id, tenantID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, tenant.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *TenantRepository) GetInvitationByTokenHash(ctx context.Context, hash string) (*tenant.Invitation, error) {
+	var inv tenant.Invitation
+	query := r.db.Rebind(`SELECT * FROM tenant_invitations WHERE token_hash = ?`)
+	if err := r.db.GetContext(ctx, &inv, query, hash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, tenant.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *TenantRepository) ListInvitations(ctx context.Context, tenantID uuid.UUID) ([]tenant.Invitation, error) {
+	var invitations []tenant.Invitation
+	query := r.db.Rebind(`SELECT * FROM tenant_invitations WHERE tenant_id = ? ORDER BY created_at DESC`)
+	if err := r.db.SelectContext(ctx, &invitations, query, tenantID); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (r *TenantRepository) UpdateInvitation(ctx context.Context, inv *tenant.Invitation) error {
+	query := `UPDATE tenant_invitations SET accepted_at = :accepted_at, revoked_at = :revoked_at
+		WHERE id = :id AND accepted_at IS NULL AND revoked_at IS NULL`
+	res, err := r.db.NamedExecContext(ctx, query, inv)
+	if err != nil {
+		return err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return tenant.ErrInvitationInactive
+	}
+	return nil
+}
+
+func isForeignKeyViolation(err error) bool {
+	s := strings.ToLower(err.Error())
+	return strings.Contains(s, "foreign key")
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// conn is what repositories need from either a pool or a transaction.
+type conn interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+}
+
+// transact runs fn inside a transaction on c, committing when fn returns
+// nil and rolling back otherwise. When c already is a transaction fn joins
+// it, so nested calls commit once with the outermost.
+func transact(ctx context.Context, c conn, fn func(conn) error) (err error) {
+	db, ok := c.(*sqlx.DB)
+	if !ok {
+		return fn(c)
+	}
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
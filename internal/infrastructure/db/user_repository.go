@@ -3,12 +3,16 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 
 	"github.com/kidpech/api_free_demo/internal/domain/user"
+	"github.com/kidpech/api_free_demo/pkg/cursor"
+	"github.com/kidpech/api_free_demo/pkg/filterexpr"
 )
 
 // UserRepository implements user.Repository using sqlx.
@@ -22,8 +26,8 @@ func NewUserRepository(db *sqlx.DB) user.Repository {
 }
 
 func (r *UserRepository) Create(ctx context.Context, u *user.User) error {
-	query := `INSERT INTO users (id, email, name, password_hash, profile_image, role, refresh_version, created_at, updated_at)
-		VALUES (:id, :email, :name, :password_hash, :profile_image, :role, :refresh_version, :created_at, :updated_at)`
+	query := `INSERT INTO users (id, email, name, password_hash, profile_image, role, refresh_version, version, created_at, updated_at)
+		VALUES (:id, :email, :name, :password_hash, :profile_image, :role, :refresh_version, :version, :created_at, :updated_at)`
 	_, err := r.db.NamedExecContext(ctx, query, u)
 	if err != nil {
 		if isDuplicate(err) {
@@ -34,9 +38,23 @@ func (r *UserRepository) Create(ctx context.Context, u *user.User) error {
 }
 
 func (r *UserRepository) Update(ctx context.Context, u *user.User) error {
-	query := `UPDATE users SET name = :name, profile_image = :profile_image, password_hash = :password_hash,
-		refresh_version = :refresh_version, updated_at = :updated_at, last_login_at = :last_login_at WHERE id = :id`
-	_, err := r.db.NamedExecContext(ctx, query, u)
+	query := `UPDATE users SET name = :name, profile_image = :profile_image, profile_image_key = :profile_image_key, password_hash = :password_hash,
+		refresh_version = :refresh_version, updated_at = :updated_at, version = :version + 1
+		WHERE id = :id AND version = :version`
+	res, err := r.db.NamedExecContext(ctx, query, u)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return user.ErrVersionConflict
+	}
+	u.Version++
+	return nil
+}
+
+func (r *UserRepository) TouchLogin(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := r.db.Rebind(`UPDATE users SET last_login_at = ?, updated_at = ? WHERE id = ?`)
+	_, err := r.db.ExecContext(ctx, query, at, at, id)
 	return err
 }
 
@@ -66,32 +84,190 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*user.User,
 	return &u, nil
 }
 
-func (r *UserRepository) List(ctx context.Context, filter user.UserFilter) ([]user.User, int, error) {
-	where := []string{"deleted_at IS NULL"}
-	params := []interface{}{}
-	if filter.Search != "" {
-		where = append(where, "(LOWER(email) LIKE LOWER(?) OR LOWER(name) LIKE LOWER(?))")
-		params = append(params, "%"+filter.Search+"%", "%"+filter.Search+"%")
+func (r *UserRepository) ListByIDs(ctx context.Context, ids []uuid.UUID) ([]user.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
 	}
-	base := "FROM users WHERE " + strings.Join(where, " AND ")
-	order := "created_at DESC"
-	if strings.Contains(filter.Sort, "name") {
-		order = "name ASC"
+	query, args, err := sqlx.In(`SELECT * FROM users WHERE id IN (?) AND deleted_at IS NULL`, ids)
+	if err != nil {
+		return nil, err
 	}
-	query := r.db.Rebind("SELECT * " + base + " ORDER BY " + order + " LIMIT ? OFFSET ?")
 	var users []user.User
-	queryArgs := append(append([]interface{}{}, params...), filter.Limit, filter.Offset)
-	if err := r.db.SelectContext(ctx, &users, query, queryArgs...); err != nil {
-		return nil, 0, err
+	if err := r.db.SelectContext(ctx, &users, r.db.Rebind(query), args...); err != nil {
+		return nil, err
 	}
+	return users, nil
+}
+
+// userSortColumns maps whitelisted sort fields onto SQL expressions. They must
+// stay in sync with user.User.SortValue so cursors round-trip.
+var userSortColumns = map[string]string{
+	"created_at":    "created_at",
+	"updated_at":    "updated_at",
+	"last_login_at": "COALESCE(last_login_at, created_at)",
+	"name":          "name",
+	"email":         "email",
+}
+
+func (r *UserRepository) List(ctx context.Context, filter user.UserFilter) ([]user.User, int, error) {
+	pg := isPostgres(r.db)
+	where, params := userFilterClause(filter, pg)
+	base := "FROM users WHERE " + strings.Join(where, " AND ")
 	countQuery := r.db.Rebind("SELECT COUNT(*) " + base)
 	var total int
 	if err := r.db.GetContext(ctx, &total, countQuery, params...); err != nil {
 		return nil, 0, err
 	}
+
+	column, dir, cmp := userSortSpec(filter)
+	if filter.Cursor != nil {
+		value, err := userCursorValue(filter.SortField, filter.Cursor)
+		if err != nil {
+			return nil, 0, err
+		}
+		where = append(where, fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", column, cmp, column, cmp))
+		params = append(params, value, value, filter.Cursor.ID)
+	}
+	selectList := "*"
+	var selectArgs []interface{}
+	if filter.Query != "" {
+		columns, n := userFullText.selectColumns(pg)
+		selectList = "*, " + columns
+		selectArgs = repeat(filter.Query, n)
+	}
+	query := r.db.Rebind(fmt.Sprintf("SELECT %s FROM users WHERE %s ORDER BY %s %s, id %s LIMIT ?",
+		selectList, strings.Join(where, " AND "), column, dir, dir))
+	var users []user.User
+	if err := r.db.SelectContext(ctx, &users, query, append(append(selectArgs, params...), filter.Limit+1)...); err != nil {
+		return nil, 0, err
+	}
 	return users, total, nil
 }
 
+func (r *UserRepository) Stream(ctx context.Context, filter user.UserFilter, fn func(*user.User) error) error {
+	where, params := userFilterClause(filter, isPostgres(r.db))
+	column, dir, _ := userSortSpec(filter)
+	query := r.db.Rebind(fmt.Sprintf("SELECT * FROM users WHERE %s ORDER BY %s %s, id %s",
+		strings.Join(where, " AND "), column, dir, dir))
+	rows, err := r.db.QueryxContext(ctx, query, params...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u user.User
+		if err := rows.StructScan(&u); err != nil {
+			return err
+		}
+		if err := fn(&u); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *UserRepository) CreateActionToken(ctx context.Context, t *user.ActionToken) error {
+	query := `INSERT INTO user_action_tokens (token_hash, user_id, purpose, expires_at, created_at)
+		VALUES (:token_hash, :user_id, :purpose, :expires_at, :created_at)`
+	_, err := r.db.NamedExecContext(ctx, query, t)
+	return err
+}
+
+// ConsumeActionToken claims the token with a conditional UPDATE so two
+// concurrent redemptions cannot both succeed.
+func (r *UserRepository) ConsumeActionToken(ctx context.Context, tokenHash string, now time.Time) (*user.ActionToken, error) {
+	res, err := r.db.ExecContext(ctx, r.db.Rebind(`UPDATE user_action_tokens SET used_at = ?
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?`), now, tokenHash, now)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, user.ErrInvalidToken
+	}
+	var t user.ActionToken
+	if err := r.db.GetContext(ctx, &t, r.db.Rebind(`SELECT * FROM user_action_tokens WHERE token_hash = ?`), tokenHash); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func userFilterClause(filter user.UserFilter, pg bool) ([]string, []interface{}) {
+	where := []string{}
+	params := []interface{}{}
+	switch filter.Status {
+	case user.StatusActive:
+		where = append(where, "deleted_at IS NULL", "suspended_at IS NULL")
+	case user.StatusSuspended:
+		where = append(where, "deleted_at IS NULL", "suspended_at IS NOT NULL")
+	case user.StatusDeleted:
+		where = append(where, "deleted_at IS NOT NULL")
+	case user.StatusAll:
+		where = append(where, "1 = 1")
+	default:
+		where = append(where, "deleted_at IS NULL")
+	}
+	if filter.Search != "" {
+		where = append(where, "(LOWER(email) LIKE LOWER(?) OR LOWER(name) LIKE LOWER(?))")
+		like := "%" + likeEscaper.Replace(filter.Search) + "%"
+		params = append(params, like, like)
+	}
+	if filter.Query != "" {
+		where = append(where, userFullText.where(pg))
+		params = append(params, filter.Query)
+	}
+	if filter.Role != "" {
+		where = append(where, "role = ?")
+		params = append(params, filter.Role)
+	}
+	if filter.EmailDomain != "" {
+		where = append(where, "LOWER(email) LIKE ?")
+		params = append(params, "%@"+likeEscaper.Replace(strings.ToLower(strings.TrimPrefix(filter.EmailDomain, "@"))))
+	}
+	if filter.CreatedFrom != nil {
+		where = append(where, "created_at >= ?")
+		params = append(params, *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		where = append(where, "created_at < ?")
+		params = append(params, *filter.CreatedTo)
+	}
+	if filter.LastLoginFrom != nil {
+		where = append(where, "last_login_at >= ?")
+		params = append(params, *filter.LastLoginFrom)
+	}
+	if filter.LastLoginTo != nil {
+		where = append(where, "last_login_at < ?")
+		params = append(params, *filter.LastLoginTo)
+	}
+	if filter.TenantID != uuid.Nil {
+		where = append(where, "id IN (SELECT user_id FROM tenant_memberships WHERE tenant_id = ?)")
+		params = append(params, filter.TenantID)
+	}
+	if filter.Where != nil {
+		clause, exprArgs := filterexpr.SQL(filter.Where, pg)
+		where = append(where, clause)
+		params = append(params, exprArgs...)
+	}
+	return where, params
+}
+
+func userSortSpec(filter user.UserFilter) (column, dir, cmp string) {
+	column, ok := userSortColumns[filter.SortField]
+	if !ok {
+		column = "created_at"
+	}
+	dir, cmp = cursor.Order(filter.SortDesc, filter.Cursor)
+	return column, dir, cmp
+}
+
+func userCursorValue(field string, c *cursor.Cursor) (interface{}, error) {
+	switch field {
+	case "name", "email":
+		return c.Value, nil
+	}
+	return c.Time()
+}
+
 func isDuplicate(err error) bool {
 	s := strings.ToLower(err.Error())
 	return strings.Contains(s, "duplicate") || strings.Contains(s, "unique")
@@ -0,0 +1,15 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kidpech/api_free_demo/internal/domain/user"
+)
+
+func TestUserFilterClauseEscapesLikeWildcards(t *testing.T) {
+	_, params := userFilterClause(user.UserFilter{Search: `50%_off\`, EmailDomain: "@Ex_ample.com"}, true)
+	require.Contains(t, params, `%50\%\_off\\%`)
+	require.Contains(t, params, `%@ex\_ample.com`)
+}
@@ -0,0 +1,80 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// Record is what is stored under an idempotency key: the hash of the first
+// request and, once it finished, the response to replay.
+type Record struct {
+	RequestHash string            `json:"request_hash"`
+	Completed   bool              `json:"completed"`
+	Status      int               `json:"status,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+}
+
+// Store persists idempotency records.
+type Store interface {
+	// Begin claims key for a request with the given hash. When the key is
+	// already held it returns the existing record and false.
+	Begin(ctx context.Context, key, requestHash string, ttl time.Duration) (*Record, bool, error)
+	// Complete stores the response for a claimed key.
+	Complete(ctx context.Context, key string, rec Record, ttl time.Duration) error
+	// Release forgets a claimed key so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// RedisStore keeps records in Redis with native expiry.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore builds a redis-backed store.
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Begin implements Store.
+func (r *RedisStore) Begin(ctx context.Context, key, requestHash string, ttl time.Duration) (*Record, bool, error) {
+	pending, err := json.Marshal(Record{RequestHash: requestHash})
+	if err != nil {
+		return nil, false, err
+	}
+	ok, err := r.client.SetNX(ctx, r.prefix+":"+key, pending, ttl).Result()
+	if err != nil || ok {
+		return nil, ok, err
+	}
+	raw, err := r.client.Get(ctx, r.prefix+":"+key).Bytes()
+	if err == redis.Nil {
+		// Expired between the two calls; claim it again.
+		return r.Begin(ctx, key, requestHash, ttl)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var rec Record
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return nil, false, err
+	}
+	return &rec, false, nil
+}
+
+// Complete implements Store.
+func (r *RedisStore) Complete(ctx context.Context, key string, rec Record, ttl time.Duration) error {
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.prefix+":"+key, raw, ttl).Err()
+}
+
+// Release implements Store.
+func (r *RedisStore) Release(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.prefix+":"+key).Err()
+}
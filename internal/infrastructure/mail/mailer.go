@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kidpech/api_free_demo/internal/config"
+)
+
+// Mailer delivers plain-text transactional email.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// New returns an SMTP mailer when a host is configured, otherwise a mailer
+// that only logs messages (useful for local demos).
+func New(cfg config.MailConfig, logger *zap.Logger) Mailer {
+	if cfg.Host == "" {
+		return &LogMailer{logger: logger}
+	}
+	return &SMTPMailer{cfg: cfg}
+}
+
+// LogMailer writes messages to the logger instead of sending them.
+type LogMailer struct {
+	logger *zap.Logger
+}
+
+// Send implements Mailer. Bodies carry tokens and links, so they are only
+// logged at debug level.
+func (m *LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	if m.logger != nil {
+		m.logger.Info("mail (not sent, no SMTP host configured)",
+			zap.String("to", to),
+			zap.String("subject", subject),
+		)
+		m.logger.Debug("mail body", zap.String("to", to), zap.String("body", body))
+	}
+	return nil
+}
+
+// SMTPMailer sends through a plain SMTP relay using PLAIN auth when credentials are set.
+type SMTPMailer struct {
+	cfg config.MailConfig
+}
+
+// Send implements Mailer.
+func (m *SMTPMailer) Send(ctx context.Context, to, subject, body string) error {
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+	addr := net.JoinHostPort(m.cfg.Host, fmt.Sprint(m.cfg.Port))
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+	msg := strings.Join([]string{
+		"From: " + m.cfg.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().UTC().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		body,
+	}, "\r\n")
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(addr, auth, m.cfg.From, []string{to}, []byte(msg))
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		return err
+	}
+}
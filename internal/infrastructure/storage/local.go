@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/kidpech/api_free_demo/internal/domain/media"
+)
+
+// LocalStore keeps blobs on the local filesystem under a root directory.
+// Content types are derived from the key extension.
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore ensures root exists and returns a store rooted there.
+func NewLocalStore(root string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{root: root}, nil
+}
+
+// Put writes data atomically by renaming a temp file into place.
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	dst := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// Get opens a stored blob.
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, *media.Object, error) {
+	f, err := os.Open(s.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, media.ErrNotFound
+		}
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, &media.Object{Key: key, Size: info.Size(), ContentType: mime.TypeByExtension(path.Ext(key))}, nil
+}
+
+// Delete removes a blob.
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return media.ErrNotFound
+	}
+	return err
+}
+
+// path maps a key below root; path.Clean on a rooted key strips any "..".
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(path.Clean("/"+key)))
+}
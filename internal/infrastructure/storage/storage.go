@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kidpech/api_free_demo/internal/config"
+	"github.com/kidpech/api_free_demo/internal/domain/media"
+)
+
+// New builds the blob store selected by cfg.Driver.
+func New(cfg config.StorageConfig) (media.BlobStore, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocalStore(cfg.LocalDir)
+	case "s3":
+		return &S3Store{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+			Client:    &http.Client{Timeout: 30 * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage driver %s", cfg.Driver)
+	}
+}
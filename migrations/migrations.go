@@ -0,0 +1,10 @@
+// Package migrations embeds the versioned SQL schema. Each dialect has its
+// own directory of NNN_name.up.sql and NNN_name.down.sql files.
+package migrations
+
+import "embed"
+
+// FS holds the mysql and postgres migration directories.
+//
+//go:embed mysql/*.sql postgres/*.sql
+var FS embed.FS
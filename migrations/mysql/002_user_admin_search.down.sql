@@ -0,0 +1,5 @@
+DROP INDEX idx_users_role ON users;
+DROP INDEX idx_users_last_login_at ON users;
+DROP INDEX idx_users_created_at_id ON users;
+
+ALTER TABLE users DROP COLUMN suspended_at;
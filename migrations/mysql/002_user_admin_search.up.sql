@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN suspended_at DATETIME NULL;
+
+CREATE INDEX idx_users_created_at_id ON users(created_at, id);
+CREATE INDEX idx_users_last_login_at ON users(last_login_at);
+CREATE INDEX idx_users_role ON users(role);
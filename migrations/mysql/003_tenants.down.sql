@@ -0,0 +1,6 @@
+ALTER TABLE profiles DROP FOREIGN KEY fk_profiles_tenant;
+DROP INDEX idx_profiles_tenant_user ON profiles;
+ALTER TABLE profiles DROP COLUMN tenant_id;
+
+DROP TABLE IF EXISTS tenant_memberships;
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,3 @@
+ALTER TABLE profiles DROP COLUMN cover_image_key;
+ALTER TABLE profiles DROP COLUMN profile_image_key;
+ALTER TABLE users DROP COLUMN profile_image_key;
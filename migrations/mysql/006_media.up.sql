@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN profile_image_key VARCHAR(255) NULL;
+ALTER TABLE profiles ADD COLUMN profile_image_key VARCHAR(255) NULL;
+ALTER TABLE profiles ADD COLUMN cover_image_key VARCHAR(255) NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_action_tokens (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL,
+    purpose VARCHAR(16) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user_action_tokens_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE INDEX idx_user_action_tokens_user ON user_action_tokens(user_id);
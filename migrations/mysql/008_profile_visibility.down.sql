@@ -0,0 +1,7 @@
+DROP INDEX idx_profiles_visibility ON profiles;
+DROP INDEX idx_profiles_handle ON profiles;
+
+ALTER TABLE profiles
+    DROP COLUMN field_visibility,
+    DROP COLUMN visibility,
+    DROP COLUMN handle;
@@ -0,0 +1,6 @@
+ALTER TABLE profiles ADD COLUMN handle VARCHAR(30) NULL;
+ALTER TABLE profiles ADD COLUMN visibility VARCHAR(16) NOT NULL DEFAULT 'private';
+ALTER TABLE profiles ADD COLUMN field_visibility JSON NULL;
+
+CREATE UNIQUE INDEX idx_profiles_handle ON profiles(handle);
+CREATE INDEX idx_profiles_visibility ON profiles(visibility, handle);
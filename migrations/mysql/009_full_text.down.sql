@@ -0,0 +1,2 @@
+DROP INDEX ft_users_search ON users;
+DROP INDEX ft_profiles_search ON profiles;
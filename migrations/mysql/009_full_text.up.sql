@@ -0,0 +1,3 @@
+-- Column lists must match the MATCH() clauses in internal/infrastructure/db/search.go.
+CREATE FULLTEXT INDEX ft_profiles_search ON profiles(first_name, last_name, bio, location);
+CREATE FULLTEXT INDEX ft_users_search ON users(name, email);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS profile_revisions (
+    profile_id CHAR(36) NOT NULL,
+    version INT NOT NULL,
+    snapshot JSON NOT NULL,
+    actor_id CHAR(36) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (profile_id, version),
+    CONSTRAINT fk_profile_revisions_profile FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1 @@
+DROP INDEX idx_profiles_deleted_at ON profiles;
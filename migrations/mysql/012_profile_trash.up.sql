@@ -0,0 +1 @@
+CREATE INDEX idx_profiles_deleted_at ON profiles(deleted_at);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    idempotency_key CHAR(64) PRIMARY KEY,
+    record MEDIUMTEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    INDEX idx_idempotency_keys_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS profile_field_definitions (
+    id CHAR(36) PRIMARY KEY,
+    tenant_id CHAR(36) NULL,
+    field_key VARCHAR(40) NOT NULL,
+    label VARCHAR(100) NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    enum_values JSON NULL,
+    pattern VARCHAR(500) NULL,
+    description VARCHAR(500) NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- NULL tenants never collide here; the service keeps global keys unique.
+    UNIQUE KEY idx_profile_field_definitions_scope (tenant_id, field_key),
+    CONSTRAINT fk_profile_field_definitions_tenant FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE profiles ADD COLUMN custom_fields JSON NULL;
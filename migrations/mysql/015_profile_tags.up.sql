@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS profile_tags (
+    profile_id CHAR(36) NOT NULL,
+    tag VARCHAR(50) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (profile_id, tag),
+    KEY idx_profile_tags_tag (tag),
+    CONSTRAINT fk_profile_tags_profile FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,4 @@
+ALTER TABLE profiles
+    DROP KEY idx_profiles_phone_e164,
+    DROP COLUMN country,
+    DROP COLUMN phone_e164;
@@ -0,0 +1,6 @@
+-- phone keeps the number as typed; phone_e164 is filled by the API and is
+-- left NULL for rows saved before normalization until their phone changes.
+ALTER TABLE profiles
+    ADD COLUMN phone_e164 VARCHAR(16) NULL,
+    ADD COLUMN country CHAR(2) NULL,
+    ADD KEY idx_profiles_phone_e164 (user_id, phone_e164);
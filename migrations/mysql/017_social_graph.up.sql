@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS user_follows (
+    follower_id CHAR(36) NOT NULL,
+    followee_id CHAR(36) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, followee_id),
+    KEY idx_user_follows_followee (followee_id, created_at),
+    CONSTRAINT fk_user_follows_follower FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_follows_followee FOREIGN KEY (followee_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- One row per pair of users, keyed with user_a < user_b; status is pending
+-- until the user who did not send the request accepts.
+CREATE TABLE IF NOT EXISTS user_connections (
+    user_a CHAR(36) NOT NULL,
+    user_b CHAR(36) NOT NULL,
+    requester_id CHAR(36) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_a, user_b),
+    KEY idx_user_connections_user_b (user_b),
+    CONSTRAINT fk_user_connections_a FOREIGN KEY (user_a) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_connections_b FOREIGN KEY (user_b) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_connections_requester FOREIGN KEY (requester_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS user_blocks (
+    blocker_id CHAR(36) NOT NULL,
+    blocked_id CHAR(36) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blocker_id, blocked_id),
+    KEY idx_user_blocks_blocked (blocked_id),
+    CONSTRAINT fk_user_blocks_blocker FOREIGN KEY (blocker_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_blocks_blocked FOREIGN KEY (blocked_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
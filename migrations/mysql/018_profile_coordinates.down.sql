@@ -0,0 +1,4 @@
+ALTER TABLE profiles
+    DROP KEY idx_profiles_coordinates,
+    DROP COLUMN longitude,
+    DROP COLUMN latitude;
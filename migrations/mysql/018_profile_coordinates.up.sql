@@ -0,0 +1,6 @@
+-- Coordinates are WGS 84 degrees, both set or both NULL. Radius searches
+-- prefilter on a bounding box over this index, then rank by haversine.
+ALTER TABLE profiles
+    ADD COLUMN latitude DOUBLE NULL,
+    ADD COLUMN longitude DOUBLE NULL,
+    ADD KEY idx_profiles_coordinates (user_id, latitude, longitude);
@@ -0,0 +1,16 @@
+-- Access another user holds on a profile; access is read or write and rows
+-- past expires_at no longer resolve.
+CREATE TABLE IF NOT EXISTS profile_grants (
+    profile_id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    access VARCHAR(16) NOT NULL,
+    granted_by CHAR(36) NOT NULL,
+    expires_at DATETIME NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (profile_id, user_id),
+    KEY idx_profile_grants_user (user_id, expires_at),
+    CONSTRAINT fk_profile_grants_profile FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE,
+    CONSTRAINT fk_profile_grants_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_profile_grants_granted_by FOREIGN KEY (granted_by) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_role;
+DROP INDEX IF EXISTS idx_users_last_login_at;
+DROP INDEX IF EXISTS idx_users_created_at_id;
+
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_at;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_profiles_tenant_user;
+ALTER TABLE profiles DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenant_memberships;
+DROP TABLE IF EXISTS tenants;
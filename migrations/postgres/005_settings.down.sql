@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS tenant_settings;
+DROP TABLE IF EXISTS user_settings;
@@ -0,0 +1,3 @@
+ALTER TABLE profiles DROP COLUMN IF EXISTS cover_image_key;
+ALTER TABLE profiles DROP COLUMN IF EXISTS profile_image_key;
+ALTER TABLE users DROP COLUMN IF EXISTS profile_image_key;
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS profile_image_key TEXT;
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS profile_image_key TEXT;
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS cover_image_key TEXT;
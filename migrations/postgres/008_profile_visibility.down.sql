@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_profiles_visibility;
+DROP INDEX IF EXISTS idx_profiles_handle;
+
+ALTER TABLE profiles DROP COLUMN IF EXISTS field_visibility;
+ALTER TABLE profiles DROP COLUMN IF EXISTS visibility;
+ALTER TABLE profiles DROP COLUMN IF EXISTS handle;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_fts;
+DROP INDEX IF EXISTS idx_profiles_fts;
+
+CREATE INDEX IF NOT EXISTS idx_profiles_search ON profiles USING GIN (to_tsvector('simple', first_name || ' ' || last_name));
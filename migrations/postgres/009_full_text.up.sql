@@ -0,0 +1,13 @@
+-- Expressions must match the search documents built in internal/infrastructure/db/search.go.
+DROP INDEX IF EXISTS idx_profiles_search;
+
+CREATE INDEX IF NOT EXISTS idx_profiles_fts ON profiles USING GIN ((
+    setweight(to_tsvector('simple', coalesce(first_name, '') || ' ' || coalesce(last_name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(bio, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(location, '')), 'C')
+));
+
+CREATE INDEX IF NOT EXISTS idx_users_fts ON users USING GIN ((
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(email, '')), 'B')
+));
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS profile_revisions (
+    profile_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    snapshot JSONB NOT NULL,
+    actor_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (profile_id, version)
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    idempotency_key CHAR(64) PRIMARY KEY,
+    record TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,3 @@
+ALTER TABLE profiles DROP COLUMN IF EXISTS custom_fields;
+
+DROP TABLE IF EXISTS profile_field_definitions;
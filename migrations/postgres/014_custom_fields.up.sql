@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS profile_field_definitions (
+    id UUID PRIMARY KEY,
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    field_key TEXT NOT NULL,
+    label TEXT NOT NULL,
+    type TEXT NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    enum_values JSONB NOT NULL DEFAULT '[]'::jsonb,
+    pattern TEXT,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One definition per key in the global scope and in each tenant.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_profile_field_definitions_scope
+    ON profile_field_definitions(COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'::uuid), field_key);
+
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '{}'::jsonb;
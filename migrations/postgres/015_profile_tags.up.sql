@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS profile_tags (
+    profile_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (profile_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_profile_tags_tag ON profile_tags(tag);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_profiles_phone_e164;
+
+ALTER TABLE profiles DROP COLUMN IF EXISTS country;
+ALTER TABLE profiles DROP COLUMN IF EXISTS phone_e164;
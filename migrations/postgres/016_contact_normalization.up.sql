@@ -0,0 +1,6 @@
+-- phone keeps the number as typed; phone_e164 is filled by the API and is
+-- left NULL for rows saved before normalization until their phone changes.
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS phone_e164 VARCHAR(16);
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS country CHAR(2);
+
+CREATE INDEX IF NOT EXISTS idx_profiles_phone_e164 ON profiles(user_id, phone_e164) WHERE phone_e164 IS NOT NULL;
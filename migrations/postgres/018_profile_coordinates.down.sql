@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_profiles_coordinates;
+
+ALTER TABLE profiles DROP COLUMN IF EXISTS longitude;
+ALTER TABLE profiles DROP COLUMN IF EXISTS latitude;
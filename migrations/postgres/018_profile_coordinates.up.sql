@@ -0,0 +1,7 @@
+-- Coordinates are WGS 84 degrees, both set or both NULL. Radius searches
+-- prefilter on a bounding box over this index, then rank with PostGIS or
+-- earthdistance when either extension is installed, or haversine otherwise.
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+
+CREATE INDEX IF NOT EXISTS idx_profiles_coordinates ON profiles(user_id, latitude, longitude) WHERE latitude IS NOT NULL;
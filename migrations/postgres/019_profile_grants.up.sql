@@ -0,0 +1,14 @@
+-- Access another user holds on a profile; access is read or write and rows
+-- past expires_at no longer resolve.
+CREATE TABLE IF NOT EXISTS profile_grants (
+    profile_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    access TEXT NOT NULL,
+    granted_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (profile_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_profile_grants_user ON profile_grants(user_id, expires_at);
@@ -0,0 +1,2 @@
+DELETE FROM profiles WHERE id = '10000000-0000-0000-0000-000000000001';
+DELETE FROM users WHERE id = '00000000-0000-0000-0000-000000000001';
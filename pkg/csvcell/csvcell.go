@@ -0,0 +1,51 @@
+// Package csvcell guards CSV exports against formula injection. Spreadsheet
+// applications evaluate cells that start with =, +, -, @, tab or carriage
+// return, so such cells are prefixed with a single quote on export and the
+// quote is stripped again on import. Cells that already start with a quote
+// get one more, so every value survives the round trip.
+package csvcell
+
+import "strconv"
+
+const guard = '\''
+
+// Escape prefixes s with a quote when a spreadsheet would evaluate it or
+// when it already starts with one. Plain numbers such as -12.5 are left
+// alone.
+func Escape(s string) string {
+	if !guarded(s) {
+		return s
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return s
+	}
+	return string(guard) + s
+}
+
+// Unescape reverses Escape.
+func Unescape(s string) string {
+	if len(s) > 1 && s[0] == guard && guarded(s[1:]) {
+		return s[1:]
+	}
+	return s
+}
+
+// EscapeRow escapes every cell of row in place and returns it.
+func EscapeRow(row []string) []string {
+	for i := range row {
+		row[i] = Escape(row[i])
+	}
+	return row
+}
+
+// guarded reports whether Escape prefixes s.
+func guarded(s string) bool {
+	if s == "" {
+		return false
+	}
+	switch s[0] {
+	case '=', '+', '-', '@', '\t', '\r', guard:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package csvcell
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscape(t *testing.T) {
+	cases := map[string]string{
+		"":                   "",
+		"Jane":               "Jane",
+		"=HYPERLINK(\"x\")":  "'=HYPERLINK(\"x\")",
+		"+cmd|' /C calc'!A0": "'+cmd|' /C calc'!A0",
+		"-2+3":               "'-2+3",
+		"@SUM(A1:A2)":        "'@SUM(A1:A2)",
+		"\t=1":               "'\t=1",
+		"\r=1":               "'\r=1",
+		"-12.5":              "-12.5",
+		"+66812345678":       "+66812345678",
+		"'quoted":            "''quoted",
+		"'=SUM(A1)":          "''=SUM(A1)",
+		"'-12.5":             "''-12.5",
+		"'":                  "''",
+		"''":                 "'''",
+		"a=b":                "a=b",
+	}
+	for in, want := range cases {
+		got := Escape(in)
+		require.Equal(t, want, got, "escape %q", in)
+		require.Equal(t, in, Unescape(got), "round trip %q", in)
+	}
+	require.Equal(t, []string{"1", "'=1"}, EscapeRow([]string{"1", "=1"}))
+}
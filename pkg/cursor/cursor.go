@@ -0,0 +1,140 @@
+// Package cursor implements the opaque keyset pagination tokens shared by the
+// listing endpoints.
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Direction tells a listing which way to walk from the cursor row.
+type Direction string
+
+// Supported directions. An empty direction means Next.
+const (
+	Next Direction = "next"
+	Prev Direction = "prev"
+)
+
+// ErrInvalid reports a cursor that is malformed or does not belong to the
+// requested listing.
+var ErrInvalid = errors.New("invalid cursor")
+
+// Cursor marks a boundary row of a keyset page: the sort field and its value
+// plus the id tie-breaker, and the direction to continue in.
+type Cursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+	Dir   Direction `json:"d,omitempty"`
+}
+
+// Page carries the pagination links returned next to a listing.
+type Page struct {
+	NextCursor string `json:"next_cursor"`
+	PrevCursor string `json:"prev_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// Encode serializes a cursor into an opaque URL-safe token.
+func Encode(c Cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decode parses a token produced by Encode. An empty token yields nil.
+func Decode(token string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalid)
+	}
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalid)
+	}
+	if c.Sort == "" || c.ID == uuid.Nil {
+		return nil, fmt.Errorf("%w: incomplete token", ErrInvalid)
+	}
+	switch c.Dir {
+	case "", Next, Prev:
+	default:
+		return nil, fmt.Errorf("%w: unknown direction %q", ErrInvalid, c.Dir)
+	}
+	return &c, nil
+}
+
+// Expect rejects cursors issued for a different sort key.
+func (c *Cursor) Expect(sort string) error {
+	if c != nil && c.Sort != sort {
+		return fmt.Errorf("%w: issued for sort %q, not %q", ErrInvalid, c.Sort, sort)
+	}
+	return nil
+}
+
+// Backward reports whether the cursor walks towards earlier pages.
+func (c *Cursor) Backward() bool {
+	return c != nil && c.Dir == Prev
+}
+
+// FormatTime renders a timestamp sort value without losing precision.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// Time parses a timestamp sort value written by FormatTime.
+func (c *Cursor) Time() (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, c.Value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: malformed sort value", ErrInvalid)
+	}
+	return t, nil
+}
+
+// Order returns the SQL sort direction and the keyset comparison operator
+// for a listing sorted desc (or ascending) when walked from c. Walking
+// backward flips both; Paginate restores the natural order afterwards.
+func Order(desc bool, c *Cursor) (dir, cmp string) {
+	if desc != c.Backward() {
+		return "DESC", "<"
+	}
+	return "ASC", ">"
+}
+
+// Paginate trims a result fetched with limit+1 rows, restores the natural
+// order of backward pages and builds the links for the neighbouring pages.
+// key returns the sort field, value and id of a row.
+func Paginate[T any](rows []T, limit int, c *Cursor, key func(T) Cursor) ([]T, Page) {
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+	hasNext, hasPrev := more, c != nil
+	if c.Backward() {
+		slices.Reverse(rows)
+		hasNext, hasPrev = true, more
+	}
+	if len(rows) == 0 {
+		return rows, Page{}
+	}
+	page := Page{HasMore: hasNext}
+	if hasNext {
+		next := key(rows[len(rows)-1])
+		next.Dir = Next
+		page.NextCursor = Encode(next)
+	}
+	if hasPrev {
+		prev := key(rows[0])
+		prev.Dir = Prev
+		page.PrevCursor = Encode(prev)
+	}
+	return rows, page
+}
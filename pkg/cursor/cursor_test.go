@@ -0,0 +1,43 @@
+package cursor
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeRejectsGarbage(t *testing.T) {
+	for _, token := range []string{"2025-01-01T00:00:00Z", "e30", Encode(Cursor{Sort: "created_at"})} {
+		_, err := Decode(token)
+		require.ErrorIs(t, err, ErrInvalid, token)
+	}
+	c, err := Decode("")
+	require.NoError(t, err)
+	require.Nil(t, c)
+}
+
+func TestPaginateWalksBothDirections(t *testing.T) {
+	key := func(n int) Cursor { return Cursor{Sort: "n", Value: string(rune('a' + n)), ID: uuid.New()} }
+
+	rows, page := Paginate([]int{1, 2, 3}, 2, nil, key)
+	require.Equal(t, []int{1, 2}, rows)
+	require.True(t, page.HasMore)
+	require.Empty(t, page.PrevCursor)
+	next, err := Decode(page.NextCursor)
+	require.NoError(t, err)
+	require.Equal(t, "c", next.Value)
+	require.False(t, next.Backward())
+
+	// A backward walk fetches rows in reverse order.
+	prev := &Cursor{Sort: "n", Value: "e", ID: uuid.New(), Dir: Prev}
+	rows, page = Paginate([]int{3, 2}, 2, prev, key)
+	require.Equal(t, []int{2, 3}, rows)
+	require.True(t, page.HasMore)
+	require.Empty(t, page.PrevCursor)
+	require.NotEmpty(t, page.NextCursor)
+
+	dir, cmp := Order(true, prev)
+	require.Equal(t, "ASC", dir)
+	require.Equal(t, ">", cmp)
+}
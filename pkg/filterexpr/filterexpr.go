@@ -0,0 +1,377 @@
+// Package filterexpr parses the ?filter= expression language of the listing
+// endpoints, e.g.
+//
+//	location eq "Bangkok" and created_at gt 2025-01-01 and bio ne null
+//
+// Comparisons are field op value, with op one of eq, ne, gt, ge, lt, le and
+// contains, joined by and, or and not with parentheses for grouping. Fields
+// are checked against a per-resource Schema and values converted to the
+// field's kind, so compiled SQL only ever references whitelisted columns
+// and binds every value as a parameter.
+package filterexpr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Limits on a single expression.
+const (
+	MaxLength      = 1024
+	MaxComparisons = 20
+)
+
+// ErrInvalid is wrapped by every *Error.
+var ErrInvalid = errors.New("invalid filter expression")
+
+// Error points at the token an expression was rejected on.
+type Error struct {
+	// Position is the 1-based character offset of Token.
+	Position int    `json:"position"`
+	Token    string `json:"token"`
+	Reason   string `json:"reason"`
+}
+
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("%s at position %d", e.Reason, e.Position)
+	}
+	return fmt.Sprintf("%s at position %d near %q", e.Reason, e.Position, e.Token)
+}
+
+// Unwrap lets callers match ErrInvalid.
+func (e *Error) Unwrap() error { return ErrInvalid }
+
+// Kind is the type a field's values are converted to.
+type Kind int
+
+// Field kinds.
+const (
+	String Kind = iota
+	Number
+	Time
+	Bool
+	UUID
+)
+
+// Field describes one filterable member of a resource.
+type Field struct {
+	Column   string
+	Kind     Kind
+	Nullable bool
+}
+
+// Schema whitelists the fields of a resource by name.
+type Schema map[string]Field
+
+// Op is a comparison operator.
+type Op string
+
+// Comparison operators.
+const (
+	Eq       Op = "eq"
+	Ne       Op = "ne"
+	Gt       Op = "gt"
+	Ge       Op = "ge"
+	Lt       Op = "lt"
+	Le       Op = "le"
+	Contains Op = "contains"
+)
+
+// Expr is a node of a parsed expression.
+type Expr interface {
+	expr()
+}
+
+// And matches when both sides do.
+type And struct{ Left, Right Expr }
+
+// Or matches when either side does.
+type Or struct{ Left, Right Expr }
+
+// Not negates an expression.
+type Not struct{ Expr Expr }
+
+// Compare tests one field. A nil Value compares against null.
+type Compare struct {
+	Field Field
+	Op    Op
+	Value interface{}
+}
+
+func (And) expr()     {}
+func (Or) expr()      {}
+func (Not) expr()     {}
+func (Compare) expr() {}
+
+// Parse parses input and checks it against schema. An empty input yields
+// a nil Expr.
+func Parse(input string, schema Schema) (Expr, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+	if len(input) > MaxLength {
+		return nil, &Error{Position: MaxLength + 1, Reason: fmt.Sprintf("expression is longer than %d characters", MaxLength)}
+	}
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, schema: schema}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, tok.errorf("expected and, or or end of expression")
+	}
+	return expr, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) errorf(format string, args ...interface{}) *Error {
+	text := t.text
+	if t.kind == tokString {
+		text = strconv.Quote(t.text)
+	}
+	return &Error{Position: t.pos + 1, Token: text, Reason: fmt.Sprintf(format, args...)}
+}
+
+// isWordRune covers names, numbers, dates, timestamps and UUIDs.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-.:+", r)
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var b strings.Builder
+			for i++; ; i++ {
+				if i >= len(runes) {
+					return nil, &Error{Position: start + 1, Token: string(runes[start:]), Reason: "unterminated string"}
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					b.WriteRune(runes[i])
+					continue
+				}
+				if runes[i] == r {
+					i++
+					break
+				}
+				b.WriteRune(runes[i])
+			}
+			tokens = append(tokens, token{kind: tokString, text: b.String(), pos: start})
+		case isWordRune(r):
+			start := i
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokWord, text: string(runes[start:i]), pos: start})
+		default:
+			return nil, &Error{Position: i + 1, Token: string(r), Reason: "unexpected character"}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(runes)}), nil
+}
+
+type parser struct {
+	tokens      []token
+	next        int
+	schema      Schema
+	comparisons int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokEOF {
+		p.next++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the given keyword and consumes
+// it if so.
+func (p *parser) keyword(word string) bool {
+	if tok := p.peek(); tok.kind == tokWord && strings.EqualFold(tok.text, word) {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (Expr, error) {
+	if p.keyword("not") {
+		expr, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: expr}, nil
+	}
+	if p.peek().kind == tokLParen {
+		open := p.take()
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			if p.peek().kind == tokEOF {
+				return nil, open.errorf("unclosed parenthesis")
+			}
+			return nil, p.peek().errorf("expected )")
+		}
+		p.take()
+		return expr, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (Expr, error) {
+	name := p.take()
+	if name.kind != tokWord {
+		return nil, name.errorf("expected a field name")
+	}
+	field, ok := p.schema[name.text]
+	if !ok {
+		return nil, name.errorf("unknown field %q", name.text)
+	}
+	p.comparisons++
+	if p.comparisons > MaxComparisons {
+		return nil, name.errorf("expressions are limited to %d comparisons", MaxComparisons)
+	}
+	opTok := p.take()
+	op := Op(strings.ToLower(opTok.text))
+	switch {
+	case opTok.kind != tokWord:
+		return nil, opTok.errorf("expected an operator")
+	case op == Eq || op == Ne:
+	case op == Gt || op == Ge || op == Lt || op == Le:
+		if field.Kind == Bool || field.Kind == UUID {
+			return nil, opTok.errorf("%s cannot be ordered", name.text)
+		}
+	case op == Contains:
+		if field.Kind != String {
+			return nil, opTok.errorf("contains only applies to text fields")
+		}
+	default:
+		return nil, opTok.errorf("unknown operator %q", opTok.text)
+	}
+	valTok := p.take()
+	if valTok.kind != tokWord && valTok.kind != tokString {
+		return nil, valTok.errorf("expected a value")
+	}
+	if valTok.kind == tokWord && strings.EqualFold(valTok.text, "null") {
+		if op != Eq && op != Ne {
+			return nil, valTok.errorf("null only compares with eq or ne")
+		}
+		if !field.Nullable {
+			return nil, valTok.errorf("%s is never null", name.text)
+		}
+		return Compare{Field: field, Op: op, Value: nil}, nil
+	}
+	value, err := convert(field.Kind, valTok.text)
+	if err != nil {
+		return nil, valTok.errorf("%s %s", name.text, err)
+	}
+	return Compare{Field: field, Op: op, Value: value}, nil
+}
+
+func convert(kind Kind, raw string) (interface{}, error) {
+	switch kind {
+	case Number:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, errors.New("needs a number")
+		}
+		return n, nil
+	case Time:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if t, err := time.Parse(layout, raw); err == nil {
+				return t.UTC(), nil
+			}
+		}
+		return nil, errors.New("needs an RFC3339 time or YYYY-MM-DD date")
+	case Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.New("needs true or false")
+		}
+		return b, nil
+	case UUID:
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, errors.New("needs a UUID")
+		}
+		return id, nil
+	default:
+		return raw, nil
+	}
+}
@@ -0,0 +1,68 @@
+package filterexpr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testSchema = Schema{
+	"location":   {Column: "location", Kind: String, Nullable: true},
+	"bio":        {Column: "bio", Kind: String, Nullable: true},
+	"name":       {Column: "first_name", Kind: String},
+	"created_at": {Column: "created_at", Kind: Time},
+	"version":    {Column: "version", Kind: Number},
+	"active":     {Column: "active", Kind: Bool},
+}
+
+func TestParseCompilesParameterizedSQL(t *testing.T) {
+	expr, err := Parse(`location eq "Bangkok" and created_at gt 2025-01-01 and bio ne null`, testSchema)
+	require.NoError(t, err)
+	where, args := SQL(expr, true)
+	require.Equal(t, "((location = ? AND created_at > ?) AND bio IS NOT NULL)", where)
+	require.Equal(t, []interface{}{"Bangkok", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}, args)
+
+	expr, err = Parse(`not (name contains '50%_off' or version ge 2) and location NE "Paris"`, testSchema)
+	require.NoError(t, err)
+	where, args = SQL(expr, false)
+	require.Equal(t, "(NOT ((LOWER(first_name) LIKE LOWER(?) OR version >= ?)) AND (location <> ? OR location IS NULL))", where)
+	require.Equal(t, []interface{}{`%50\%\_off%`, 2.0, "Paris"}, args)
+
+	expr, err = Parse("  ", testSchema)
+	require.NoError(t, err)
+	require.Nil(t, expr)
+}
+
+func TestParseErrorsPointAtToken(t *testing.T) {
+	cases := []struct {
+		input    string
+		position int
+		token    string
+	}{
+		{`password eq "x"`, 1, "password"},
+		{`name eq "Ada" and version gt many`, 30, "many"},
+		{`name eq null`, 9, "null"},
+		{`active gt true`, 8, "gt"},
+		{`version contains 3`, 9, "contains"},
+		{`name like "A"`, 6, "like"},
+		{`(name eq "Ada"`, 1, "("},
+		{`name eq "Ada" version eq 1`, 15, "version"},
+		{`name eq "Ada`, 9, `"Ada`},
+		{`name eq x; drop table users`, 10, ";"},
+		{`name eq`, 8, ""},
+	}
+	for _, tc := range cases {
+		_, err := Parse(tc.input, testSchema)
+		var ferr *Error
+		require.True(t, errors.As(err, &ferr), tc.input)
+		require.ErrorIs(t, err, ErrInvalid)
+		require.Equal(t, tc.position, ferr.Position, tc.input)
+		require.Equal(t, tc.token, ferr.Token, tc.input)
+	}
+
+	_, err := Parse(strings.Repeat(`version eq 1 or `, MaxComparisons)+"version eq 1", testSchema)
+	require.ErrorIs(t, err, ErrInvalid)
+}
@@ -0,0 +1,52 @@
+package filterexpr
+
+import "strings"
+
+var comparators = map[Op]string{Eq: "=", Ne: "<>", Gt: ">", Ge: ">=", Lt: "<", Le: "<="}
+
+// likeEscaper escapes LIKE wildcards; both dialects default to backslash
+// as the escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// SQL compiles expr to a condition with ? placeholders for Rebind. ne
+// keeps rows where a nullable field is null, matching how clients read
+// "not equal". A nil expr compiles to an empty condition.
+func SQL(expr Expr, pg bool) (string, []interface{}) {
+	var args []interface{}
+	var walk func(Expr) string
+	walk = func(e Expr) string {
+		switch e := e.(type) {
+		case And:
+			return "(" + walk(e.Left) + " AND " + walk(e.Right) + ")"
+		case Or:
+			return "(" + walk(e.Left) + " OR " + walk(e.Right) + ")"
+		case Not:
+			return "NOT (" + walk(e.Expr) + ")"
+		case Compare:
+			column := e.Field.Column
+			switch {
+			case e.Value == nil && e.Op == Eq:
+				return column + " IS NULL"
+			case e.Value == nil:
+				return column + " IS NOT NULL"
+			case e.Op == Contains:
+				args = append(args, "%"+likeEscaper.Replace(e.Value.(string))+"%")
+				if pg {
+					return column + " ILIKE ?"
+				}
+				return "LOWER(" + column + ") LIKE LOWER(?)"
+			case e.Op == Ne && e.Field.Nullable:
+				args = append(args, e.Value)
+				return "(" + column + " <> ? OR " + column + " IS NULL)"
+			default:
+				args = append(args, e.Value)
+				return column + " " + comparators[e.Op] + " ?"
+			}
+		}
+		return ""
+	}
+	if expr == nil {
+		return "", nil
+	}
+	return walk(expr), args
+}
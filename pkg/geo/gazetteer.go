@@ -0,0 +1,133 @@
+package geo
+
+import (
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrNotFound is returned when a place cannot be resolved.
+var ErrNotFound = errors.New("place not found")
+
+//go:embed gazetteer.csv
+var gazetteerCSV string
+
+// place is one gazetteer row.
+type place struct {
+	country string
+	point   Point
+}
+
+// Gazetteer geocodes place names against a fixed list, without network
+// access. Lookups ignore case, accents and punctuation.
+type Gazetteer struct {
+	places map[string][]place
+}
+
+// DefaultGazetteer returns the gazetteer embedded in the binary, which
+// covers major cities worldwide.
+func DefaultGazetteer() *Gazetteer {
+	g, err := NewGazetteer(strings.NewReader(gazetteerCSV))
+	if err != nil {
+		panic("geo: reading embedded gazetteer: " + err.Error())
+	}
+	return g
+}
+
+// LoadGazetteer reads a gazetteer file in the embedded format: name,
+// country, latitude, longitude per row, # comments allowed, most populous
+// places first.
+func LoadGazetteer(path string) (*Gazetteer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewGazetteer(f)
+}
+
+// NewGazetteer parses gazetteer rows from r.
+func NewGazetteer(r io.Reader) (*Gazetteer, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+	g := &Gazetteer{places: map[string][]place{}}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return g, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		lat, errLat := strconv.ParseFloat(row[2], 64)
+		lng, errLng := strconv.ParseFloat(row[3], 64)
+		p := place{country: strings.ToUpper(row[1]), point: Point{Lat: lat, Lng: lng}}
+		if errLat != nil || errLng != nil || !p.point.Valid() {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("gazetteer line %d: %w", line, ErrInvalidPoint)
+		}
+		key := fold(row[0])
+		g.places[key] = append(g.places[key], p)
+	}
+}
+
+// Geocode resolves query, such as "Paris" or "Paris, FR", to coordinates.
+// Trailing comma-separated parts that are not a country code are dropped
+// until a place matches. country, when set, picks among places sharing a
+// name; a code inside query takes precedence over it.
+func (g *Gazetteer) Geocode(ctx context.Context, query, country string) (Point, error) {
+	parts := strings.Split(query, ",")
+	if n := len(parts); n > 1 {
+		if last := strings.TrimSpace(parts[n-1]); len(last) == 2 {
+			country, parts = last, parts[:n-1]
+		}
+	}
+	country = strings.ToUpper(country)
+	for n := len(parts); n > 0; n-- {
+		candidates := g.places[fold(strings.Join(parts[:n], ","))]
+		if len(candidates) == 0 {
+			continue
+		}
+		if country == "" {
+			return candidates[0].point, nil
+		}
+		for _, c := range candidates {
+			if c.country == country {
+				return c.point, nil
+			}
+		}
+	}
+	return Point{}, ErrNotFound
+}
+
+// fold lowercases s, strips accents and collapses punctuation and spaces
+// so "São Paulo" and "sao-paulo" share a key.
+func fold(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+// Package geo handles WGS 84 coordinates: parsing "lat,lng" pairs,
+// great-circle distances and the bounding boxes radius searches prefilter
+// with. Distances use the haversine formula on a spherical earth, which is
+// within 0.5% of the ellipsoid everywhere.
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPoint is wrapped by every parse failure.
+var ErrInvalidPoint = errors.New("invalid coordinates")
+
+// EarthRadiusKm is the mean earth radius.
+const EarthRadiusKm = 6371.0088
+
+// Point is a latitude and longitude in degrees.
+type Point struct {
+	Lat float64 `json:"latitude"`
+	Lng float64 `json:"longitude"`
+}
+
+// Valid reports whether p lies within [-90, 90] x [-180, 180].
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180
+}
+
+// ParsePoint reads "lat,lng" in decimal degrees.
+func ParsePoint(raw string) (Point, error) {
+	latRaw, lngRaw, ok := strings.Cut(raw, ",")
+	if !ok {
+		return Point{}, fmt.Errorf("%w: expected lat,lng", ErrInvalidPoint)
+	}
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(latRaw), 64)
+	lng, errLng := strconv.ParseFloat(strings.TrimSpace(lngRaw), 64)
+	if errLat != nil || errLng != nil {
+		return Point{}, fmt.Errorf("%w: expected lat,lng in decimal degrees", ErrInvalidPoint)
+	}
+	p := Point{Lat: lat, Lng: lng}
+	if !p.Valid() {
+		return Point{}, fmt.Errorf("%w: latitude must be within ±90 and longitude within ±180", ErrInvalidPoint)
+	}
+	return p, nil
+}
+
+// Distance returns the great-circle distance between a and b in km.
+func Distance(a, b Point) float64 {
+	dLat := radians(b.Lat - a.Lat)
+	dLng := radians(b.Lng - a.Lng)
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(radians(a.Lat))*math.Cos(radians(b.Lat))*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * EarthRadiusKm * math.Asin(math.Sqrt(math.Min(1, h)))
+}
+
+// Box is a latitude/longitude rectangle. When MinLng > MaxLng the box
+// crosses the antimeridian and covers longitudes >= MinLng or <= MaxLng.
+type Box struct {
+	MinLat, MaxLat float64
+	MinLng, MaxLng float64
+}
+
+// CrossesAntimeridian reports whether the box wraps from 180 to -180.
+func (b Box) CrossesAntimeridian() bool {
+	return b.MinLng > b.MaxLng
+}
+
+// BoundingBox returns the smallest box holding every point within radiusKm
+// of center. Boxes that reach a pole span every longitude.
+func BoundingBox(center Point, radiusKm float64) Box {
+	angular := radiusKm / EarthRadiusKm
+	lat := radians(center.Lat)
+	box := Box{MinLat: degrees(lat - angular), MaxLat: degrees(lat + angular), MinLng: -180, MaxLng: 180}
+	if box.MinLat <= -90 || box.MaxLat >= 90 {
+		box.MinLat, box.MaxLat = math.Max(box.MinLat, -90), math.Min(box.MaxLat, 90)
+		return box
+	}
+	dLng := math.Asin(math.Sin(angular) / math.Cos(lat))
+	box.MinLng, box.MaxLng = degrees(radians(center.Lng)-dLng), degrees(radians(center.Lng)+dLng)
+	if box.MinLng < -180 {
+		box.MinLng += 360
+	}
+	if box.MaxLng > 180 {
+		box.MaxLng -= 360
+	}
+	return box
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
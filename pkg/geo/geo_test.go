@@ -0,0 +1,77 @@
+package geo
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePoint(t *testing.T) {
+	p, err := ParsePoint(" 48.8534, 2.3488 ")
+	require.NoError(t, err)
+	require.Equal(t, Point{Lat: 48.8534, Lng: 2.3488}, p)
+
+	for _, raw := range []string{"", "48.85", "north,east", "91,0", "0,-180.5"} {
+		_, err := ParsePoint(raw)
+		require.ErrorIs(t, err, ErrInvalidPoint, raw)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	paris, london := Point{Lat: 48.8534, Lng: 2.3488}, Point{Lat: 51.5085, Lng: -0.1257}
+	require.InDelta(t, 343.5, Distance(paris, london), 1)
+	require.InDelta(t, Distance(london, paris), Distance(paris, london), 1e-9)
+	require.Zero(t, Distance(paris, paris))
+	require.InDelta(t, math.Pi*EarthRadiusKm, Distance(Point{Lat: 0, Lng: 0}, Point{Lat: 0, Lng: 180}), 1e-6)
+}
+
+func TestBoundingBox(t *testing.T) {
+	paris := Point{Lat: 48.8534, Lng: 2.3488}
+	box := BoundingBox(paris, 100)
+	require.False(t, box.CrossesAntimeridian())
+	for _, edge := range []Point{{box.MinLat, paris.Lng}, {box.MaxLat, paris.Lng}, {paris.Lat, box.MinLng}, {paris.Lat, box.MaxLng}} {
+		require.GreaterOrEqual(t, Distance(paris, edge), 100-1e-6)
+	}
+
+	suva := BoundingBox(Point{Lat: -18.1416, Lng: 178.4419}, 500)
+	require.True(t, suva.CrossesAntimeridian())
+	require.Greater(t, suva.MinLng, 170.0)
+	require.Less(t, suva.MaxLng, -170.0)
+
+	polar := BoundingBox(Point{Lat: 89, Lng: 10}, 300)
+	require.Equal(t, Box{MinLat: polar.MinLat, MaxLat: 90, MinLng: -180, MaxLng: 180}, polar)
+}
+
+func TestGazetteer(t *testing.T) {
+	g := DefaultGazetteer()
+	ctx := context.Background()
+
+	p, err := g.Geocode(ctx, "  São-Paulo ", "")
+	require.NoError(t, err)
+	require.InDelta(t, -23.5, p.Lat, 0.1)
+
+	// Ambiguous names go to the largest place unless a country decides.
+	p, err = g.Geocode(ctx, "Paris", "")
+	require.NoError(t, err)
+	require.InDelta(t, 2.35, p.Lng, 0.01)
+	p, err = g.Geocode(ctx, "Paris", "us")
+	require.NoError(t, err)
+	require.InDelta(t, -95.56, p.Lng, 0.01)
+	p, err = g.Geocode(ctx, "Paris, FR", "US")
+	require.NoError(t, err)
+	require.InDelta(t, 2.35, p.Lng, 0.01)
+	p, err = g.Geocode(ctx, "London, Greater London, England", "")
+	require.NoError(t, err)
+	require.InDelta(t, 51.51, p.Lat, 0.01)
+
+	_, err = g.Geocode(ctx, "Atlantis", "")
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = g.Geocode(ctx, "Paris", "DE")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = NewGazetteer(strings.NewReader("nowhere,XX,95,0\n"))
+	require.ErrorIs(t, err, ErrInvalidPoint)
+}
@@ -0,0 +1,308 @@
+// Package jsonpatch applies JSON Merge Patch (RFC 7396) and JSON Patch
+// (RFC 6902) documents to JSON values.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Media types for the two patch formats.
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// Patch errors. ErrTestFailed is returned when a "test" operation does not
+// match, every other problem with the patch is ErrInvalid.
+var (
+	ErrInvalid    = errors.New("invalid patch")
+	ErrTestFailed = errors.New("patch test failed")
+)
+
+// Operation is a single RFC 6902 operation. Value stays nil when the member
+// is absent, which is distinct from an explicit null.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// MergePatch applies an RFC 7396 merge patch to doc.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	if err := decode(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := decode(patch, &p); err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch interface{}) interface{} {
+	obj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	out, ok := target.(map[string]interface{})
+	if !ok {
+		out = make(map[string]interface{}, len(obj))
+	}
+	for key, val := range obj {
+		if val == nil {
+			delete(out, key)
+			continue
+		}
+		out[key] = merge(out[key], val)
+	}
+	return out
+}
+
+// Apply runs an RFC 6902 operation list against doc. Operations apply in
+// order and the patch is all-or-nothing.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var ops []Operation
+	if err := decode(patch, &ops); err != nil {
+		return nil, err
+	}
+	var root interface{}
+	if err := decode(doc, &root); err != nil {
+		return nil, err
+	}
+	for i, op := range ops {
+		var err error
+		if root, err = op.apply(root); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(root)
+}
+
+func (op Operation) apply(root interface{}) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalid)
+		}
+		var value interface{}
+		if err := decode(op.Value, &value); err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return add(root, path, value)
+		case "replace":
+			if len(path) == 0 {
+				return value, nil
+			}
+			if root, _, err = remove(root, path); err != nil {
+				return nil, err
+			}
+			return add(root, path, value)
+		default:
+			current, err := get(root, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, ErrTestFailed
+			}
+			return root, nil
+		}
+	case "remove":
+		root, _, err = remove(root, path)
+		return root, err
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(root, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			return add(root, path, clone(value))
+		}
+		if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+			return nil, fmt.Errorf("%w: cannot move a value into itself", ErrInvalid)
+		}
+		if root, _, err = remove(root, from); err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalid, op.Op)
+	}
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: pointer %q must start with /", ErrInvalid, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, tok := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func get(node interface{}, path []string) (interface{}, error) {
+	for _, tok := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			val, ok := n[tok]
+			if !ok {
+				return nil, fmt.Errorf("%w: path %q not found", ErrInvalid, tok)
+			}
+			node = val
+		case []interface{}:
+			idx, err := index(tok, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[idx]
+		default:
+			return nil, fmt.Errorf("%w: cannot traverse into %q", ErrInvalid, tok)
+		}
+	}
+	return node, nil
+}
+
+// add inserts value at path and returns the (possibly reallocated) node.
+func add(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	tok, rest := path[0], path[1:]
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if len(rest) == 0 {
+			n[tok] = value
+			return n, nil
+		}
+		child, ok := n[tok]
+		if !ok {
+			return nil, fmt.Errorf("%w: path %q not found", ErrInvalid, tok)
+		}
+		child, err := add(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		n[tok] = child
+		return n, nil
+	case []interface{}:
+		if len(rest) == 0 {
+			idx := len(n)
+			if tok != "-" {
+				var err error
+				if idx, err = index(tok, len(n)); err != nil {
+					return nil, err
+				}
+			}
+			n = append(n, nil)
+			copy(n[idx+1:], n[idx:])
+			n[idx] = value
+			return n, nil
+		}
+		idx, err := index(tok, len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		if n[idx], err = add(n[idx], rest, value); err != nil {
+			return nil, err
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf("%w: cannot add into %q", ErrInvalid, tok)
+	}
+}
+
+// remove deletes the value at path and returns the updated node and the
+// removed value.
+func remove(node interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the document root", ErrInvalid)
+	}
+	tok, rest := path[0], path[1:]
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[tok]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: path %q not found", ErrInvalid, tok)
+		}
+		if len(rest) == 0 {
+			delete(n, tok)
+			return n, child, nil
+		}
+		child, removed, err := remove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		n[tok] = child
+		return n, removed, nil
+	case []interface{}:
+		idx, err := index(tok, len(n)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := n[idx]
+			return append(n[:idx], n[idx+1:]...), removed, nil
+		}
+		child, removed, err := remove(n[idx], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		n[idx] = child
+		return n, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: cannot remove from %q", ErrInvalid, tok)
+	}
+}
+
+// index parses an array index token, rejecting leading zeros and values
+// above last.
+func index(tok string, last int) (int, error) {
+	if tok == "" || (len(tok) > 1 && tok[0] == '0') {
+		return 0, fmt.Errorf("%w: bad array index %q", ErrInvalid, tok)
+	}
+	idx, err := strconv.Atoi(tok)
+	if err != nil || idx < 0 || idx > last {
+		return 0, fmt.Errorf("%w: array index %q out of range", ErrInvalid, tok)
+	}
+	return idx, nil
+}
+
+func clone(v interface{}) interface{} {
+	raw, _ := json.Marshal(v)
+	var out interface{}
+	_ = json.Unmarshal(raw, &out)
+	return out
+}
+
+func decode(raw []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if dec.More() {
+		return fmt.Errorf("%w: trailing data", ErrInvalid)
+	}
+	return nil
+}
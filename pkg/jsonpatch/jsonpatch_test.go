@@ -0,0 +1,54 @@
+package jsonpatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatchRFC7396Example(t *testing.T) {
+	doc := `{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`
+	patch := `{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`
+
+	out, err := MergePatch([]byte(doc), []byte(patch))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`, string(out))
+}
+
+func TestApplyOperations(t *testing.T) {
+	cases := []struct {
+		name, doc, patch, want string
+	}{
+		{"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"foo":"bar","baz":"qux"}`},
+		{"insert into array", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"append to array", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":"qux"}]`, `{"foo":["bar","qux"]}`},
+		{"remove element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"move", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"copy", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"}]`, `{"a":{"b":1},"c":{"b":1}}`},
+		{"escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"remove","path":"/a~1b"},{"op":"test","path":"/m~0n","value":2}]`, `{"m~n":2}`},
+		{"add null", `{"foo":"bar"}`, `[{"op":"add","path":"/foo","value":null}]`, `{"foo":null}`},
+	}
+	for _, tc := range cases {
+		out, err := Apply([]byte(tc.doc), []byte(tc.patch))
+		require.NoError(t, err, tc.name)
+		require.JSONEq(t, tc.want, string(out), tc.name)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	_, err := Apply([]byte(`{"baz":"qux"}`), []byte(`[{"op":"test","path":"/baz","value":"bar"}]`))
+	require.ErrorIs(t, err, ErrTestFailed)
+
+	for _, patch := range []string{
+		`[{"op":"remove","path":"/missing"}]`,
+		`[{"op":"add","path":"/a/b","value":1}]`,
+		`[{"op":"replace","path":"/baz"}]`,
+		`[{"op":"jump","path":"/baz"}]`,
+		`[{"op":"move","from":"/baz","path":"/baz/x"}]`,
+		`{"op":"add"}`,
+	} {
+		_, err := Apply([]byte(`{"baz":"qux"}`), []byte(patch))
+		require.ErrorIs(t, err, ErrInvalid, patch)
+	}
+}
@@ -0,0 +1,154 @@
+// Package phone normalizes phone numbers to E.164 (+<calling code><national
+// number>) using the region metadata embedded in regions.csv. The checks are
+// structural: a number is valid when its calling code is known and its
+// national number length fits a region that uses the code; number ranges
+// and line types are not modelled.
+package phone
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalid is wrapped by every normalization failure.
+var ErrInvalid = errors.New("invalid phone number")
+
+// maxDigits is the E.164 limit on calling code plus national number.
+const maxDigits = 15
+
+//go:embed regions.csv
+var regionsCSV string
+
+// region is one row of the metadata.
+type region struct {
+	code           string
+	callingCode    string
+	trunkPrefix    string
+	minLen, maxLen int
+}
+
+var (
+	regions = map[string]region{}
+	// byCallingCode groups the regions sharing a calling code, e.g. the
+	// North American Numbering Plan under 1.
+	byCallingCode = map[string][]region{}
+)
+
+func init() {
+	r := csv.NewReader(strings.NewReader(regionsCSV))
+	r.Comment = '#'
+	rows, err := r.ReadAll()
+	if err != nil {
+		panic("phone: reading region metadata: " + err.Error())
+	}
+	for _, row := range rows {
+		minLen, errMin := strconv.Atoi(row[3])
+		maxLen, errMax := strconv.Atoi(row[4])
+		if errMin != nil || errMax != nil {
+			panic("phone: bad lengths for region " + row[0])
+		}
+		reg := region{code: row[0], callingCode: row[1], trunkPrefix: row[2], minLen: minLen, maxLen: maxLen}
+		regions[reg.code] = reg
+		byCallingCode[reg.callingCode] = append(byCallingCode[reg.callingCode], reg)
+	}
+}
+
+// Normalize returns raw in E.164 form. Numbers starting with + or the 00
+// international prefix (011 in North America) carry their own calling code;
+// anything else is read as a national number of regionCode, with the
+// region's trunk prefix dropped. Spaces, dots, dashes, slashes and
+// parentheses are ignored.
+func Normalize(raw, regionCode string) (string, error) {
+	digits, international, err := clean(raw)
+	if err != nil {
+		return "", err
+	}
+	home, hasHome := regions[strings.ToUpper(regionCode)]
+	switch {
+	case international:
+	case strings.HasPrefix(digits, "00"):
+		digits, international = digits[2:], true
+	case hasHome && home.callingCode == "1" && strings.HasPrefix(digits, "011"):
+		digits, international = digits[3:], true
+	}
+	if international {
+		return fromInternational(digits)
+	}
+	if regionCode == "" {
+		return "", fmt.Errorf("%w: national numbers need a country or a leading +", ErrInvalid)
+	}
+	if !hasHome {
+		return "", fmt.Errorf("%w: no numbering metadata for country %q", ErrInvalid, regionCode)
+	}
+	if nsn, ok := national(home, digits); ok {
+		return format(home.callingCode, nsn)
+	}
+	return "", fmt.Errorf("%w: wrong length for a %s number", ErrInvalid, home.code)
+}
+
+// clean strips formatting and reports whether raw had a leading +.
+func clean(raw string) (string, bool, error) {
+	raw = strings.TrimSpace(raw)
+	international := strings.HasPrefix(raw, "+")
+	raw = strings.TrimPrefix(raw, "+")
+	var b strings.Builder
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case strings.ContainsRune(" .-/()\u00a0", r):
+		default:
+			return "", false, fmt.Errorf("%w: unexpected character %q", ErrInvalid, r)
+		}
+	}
+	if b.Len() == 0 {
+		return "", false, fmt.Errorf("%w: no digits", ErrInvalid)
+	}
+	return b.String(), international, nil
+}
+
+// fromInternational splits digits into a calling code and national number.
+// Calling codes are prefix-free, so the first match wins.
+func fromInternational(digits string) (string, error) {
+	for n := 1; n <= 3 && n < len(digits); n++ {
+		shared, ok := byCallingCode[digits[:n]]
+		if !ok {
+			continue
+		}
+		for _, reg := range shared {
+			// Numbers are often written with the trunk prefix kept after
+			// the calling code, as in +66 (0)81 234 5678.
+			if nsn, ok := national(reg, digits[n:]); ok {
+				return format(reg.callingCode, nsn)
+			}
+		}
+		return "", fmt.Errorf("%w: wrong length for calling code +%s", ErrInvalid, digits[:n])
+	}
+	return "", fmt.Errorf("%w: unknown calling code", ErrInvalid)
+}
+
+// national returns the national significant number of digits in reg,
+// dropping a trunk prefix when the remainder still has a valid length.
+func national(reg region, digits string) (string, bool) {
+	if reg.trunkPrefix != "" && strings.HasPrefix(digits, reg.trunkPrefix) {
+		if nsn := digits[len(reg.trunkPrefix):]; reg.fits(nsn) {
+			return nsn, true
+		}
+	}
+	return digits, reg.fits(digits)
+}
+
+func (r region) fits(nsn string) bool {
+	return len(nsn) >= r.minLen && len(nsn) <= r.maxLen
+}
+
+func format(callingCode, nsn string) (string, error) {
+	if len(callingCode)+len(nsn) > maxDigits {
+		return "", fmt.Errorf("%w: longer than %d digits", ErrInvalid, maxDigits)
+	}
+	return "+" + callingCode + nsn, nil
+}
@@ -0,0 +1,46 @@
+package phone
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		raw, region, want string
+	}{
+		{"+66 81 234 5678", "", "+66812345678"},
+		{"081-234-5678", "TH", "+66812345678"},
+		{"+66 (0)81 234 5678", "", "+66812345678"},
+		{"0066812345678", "GB", "+66812345678"},
+		{"(212) 555-1234", "us", "+12125551234"},
+		{"1 212 555 1234", "US", "+12125551234"},
+		{"011 44 20 7946 0018", "US", "+442079460018"},
+		{"020 7946 0018", "GB", "+442079460018"},
+		{"+39 06 1234 5678", "", "+390612345678"},
+		{"06 30 123 4567", "HU", "+36301234567"},
+	}
+	for _, tc := range cases {
+		got, err := Normalize(tc.raw, tc.region)
+		require.NoError(t, err, tc.raw)
+		require.Equal(t, tc.want, got, tc.raw)
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	cases := []struct{ raw, region string }{
+		{"081 234 5678", ""},
+		{"081 234 5678", "ZZ"},
+		{"+66 81 234", ""},
+		{"+999 1234 5678", ""},
+		{"call me", "TH"},
+		{"+66 81 234 5678 ext 9", ""},
+		{"---", "TH"},
+		{"+49 1234 5678 9012 345", ""},
+	}
+	for _, tc := range cases {
+		_, err := Normalize(tc.raw, tc.region)
+		require.ErrorIs(t, err, ErrInvalid, tc.raw)
+	}
+}
@@ -0,0 +1,61 @@
+package response
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag formats a resource version as a strong entity tag.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// SetETag tags the response with the resource version.
+func SetETag(c *gin.Context, version int) {
+	c.Header("ETag", ETag(version))
+}
+
+// IfMatch reads the If-Match header. present is false when the header is
+// missing; a nil version means "*". A tag that is not one of ours yields -1,
+// which never matches a stored version.
+func IfMatch(c *gin.Context) (version *int, present bool) {
+	raw := strings.TrimSpace(c.GetHeader("If-Match"))
+	if raw == "" {
+		return nil, false
+	}
+	if raw == "*" {
+		return nil, true
+	}
+	v, err := strconv.Atoi(strings.Trim(raw, `"`))
+	if err != nil || !strings.HasPrefix(raw, `"`) {
+		v = -1
+	}
+	return &v, true
+}
+
+// NotModified answers 304 when If-None-Match already names the current
+// version. Weak comparison applies, as for any GET.
+func NotModified(c *gin.Context, version int) bool {
+	raw := c.GetHeader("If-None-Match")
+	if raw == "" {
+		return false
+	}
+	current := ETag(version)
+	for _, tag := range strings.Split(raw, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			SetETag(c, version)
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// PreconditionFailed helper for If-Match mismatches.
+func PreconditionFailed(c *gin.Context, message string) {
+	c.JSON(http.StatusPreconditionFailed, ErrorResponse{Error: "precondition_failed", Message: message})
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+
+	"github.com/kidpech/api_free_demo/pkg/filterexpr"
 )
 
 // ErrorResponse standardizes API errors.
@@ -33,6 +35,22 @@ func ValidationError(c *gin.Context, err error) {
 	c.JSON(http.StatusBadRequest, resp)
 }
 
+// BadRequest helper for malformed query or payload semantics.
+func BadRequest(c *gin.Context, code, message string) {
+	c.JSON(http.StatusBadRequest, ErrorResponse{Error: code, Message: message})
+}
+
+// InvalidFilter writes 400 invalid_filter. Errors from ?filter= expressions
+// carry the offending token and its position as details.
+func InvalidFilter(c *gin.Context, err error) {
+	var expr *filterexpr.Error
+	if errors.As(err, &expr) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid_filter", Message: err.Error(), Details: expr})
+		return
+	}
+	BadRequest(c, "invalid_filter", err.Error())
+}
+
 // Unauthorized helper.
 func Unauthorized(c *gin.Context, message string) {
 	c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized", Message: message})
@@ -57,6 +75,16 @@ func Conflict(c *gin.Context, code, message string) {
 	c.JSON(http.StatusConflict, ErrorResponse{Error: code, Message: message})
 }
 
+// PayloadTooLarge helper.
+func PayloadTooLarge(c *gin.Context, message string) {
+	c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: "payload_too_large", Message: message})
+}
+
+// UnsupportedMediaType helper.
+func UnsupportedMediaType(c *gin.Context, message string) {
+	c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{Error: "unsupported_media_type", Message: message})
+}
+
 // TooManyRequests helper.
 func TooManyRequests(c *gin.Context, reset time.Time) {
 	resetSeconds := strconv.FormatInt(reset.Unix(), 10)
@@ -116,15 +144,13 @@ func GetOffset(c *gin.Context) int {
 	return offset
 }
 
-// SerializeErrors returns list of error strings for bulk APIs.
-func SerializeErrors(errs []error) []string {
-	if len(errs) == 0 {
-		return nil
-	}
-	out := make([]string, len(errs))
-	for i, err := range errs {
-		if err != nil {
-			out[i] = err.Error()
+// GetList splits a comma-separated query parameter, trimming entries and
+// dropping empty ones.
+func GetList(c *gin.Context, name string) []string {
+	var out []string
+	for _, item := range strings.Split(c.Query(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
 		}
 	}
 	return out
@@ -147,6 +173,14 @@ func MustUserID(c *gin.Context) uuid.UUID {
 	return id
 }
 
+// OptionalUserID returns the signed-in caller on routes that also serve
+// anonymous requests, or uuid.Nil.
+func OptionalUserID(c *gin.Context) uuid.UUID {
+	id, _ := c.Get("user_id")
+	userID, _ := id.(uuid.UUID)
+	return userID
+}
+
 // UserIDFromContext extracts string for rate limiting.
 func UserIDFromContext(c *gin.Context) string {
 	val, exists := c.Get("user_id")
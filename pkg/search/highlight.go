@@ -0,0 +1,79 @@
+// Package search holds helpers shared by the full-text search endpoints.
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Mark tags wrap matched terms in snippets, mirroring the Postgres
+// ts_headline options used by the repositories.
+const (
+	MarkStart = "<mark>"
+	MarkStop  = "</mark>"
+)
+
+// Terms extracts the words of a web-style query, dropping quotes, operators
+// and negated words.
+func Terms(query string) []string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		if strings.HasPrefix(word, "-") || strings.EqualFold(word, "or") {
+			continue
+		}
+		word = strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		if word != "" {
+			terms = append(terms, strings.ToLower(word))
+		}
+	}
+	return terms
+}
+
+// Highlight returns a window of up to maxWords words of text centred on the
+// first matching term, with every match wrapped in mark tags. It is the
+// fallback for databases without a native headline function.
+func Highlight(text, query string, maxWords int) string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return ""
+	}
+	terms := Terms(query)
+	matches := func(word string) bool {
+		w := strings.ToLower(strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }))
+		for _, t := range terms {
+			if strings.HasPrefix(w, t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	first := -1
+	for i, w := range words {
+		if matches(w) {
+			first = i
+			break
+		}
+	}
+	start := 0
+	if first > maxWords/2 {
+		start = first - maxWords/2
+	}
+	end := min(start+maxWords, len(words))
+
+	out := make([]string, 0, end-start)
+	for _, w := range words[start:end] {
+		if matches(w) {
+			w = MarkStart + w + MarkStop
+		}
+		out = append(out, w)
+	}
+	snippet := strings.Join(out, " ")
+	if start > 0 {
+		snippet = "… " + snippet
+	}
+	if end < len(words) {
+		snippet += " …"
+	}
+	return snippet
+}
@@ -0,0 +1,25 @@
+// Package tenancy carries the active tenant through request contexts so
+// repositories can scope queries without threading ids through every call.
+package tenancy
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type ctxKey struct{}
+
+// WithTenant returns a context scoped to tenantID.
+func WithTenant(ctx context.Context, tenantID uuid.UUID) context.Context {
+	return context.WithValue(ctx, ctxKey{}, tenantID)
+}
+
+// FromContext returns the active tenant, if any.
+func FromContext(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(ctxKey{}).(uuid.UUID)
+	if !ok || id == uuid.Nil {
+		return uuid.Nil, false
+	}
+	return id, true
+}
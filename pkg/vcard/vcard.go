@@ -0,0 +1,311 @@
+// Package vcard reads and writes the subset of vCard 3.0 (RFC 2426) used for
+// exchanging contact-like records: names, note, birthday, one phone, URL,
+// locality, photo link and categories.
+package vcard
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MediaType is the vCard content type.
+const MediaType = "text/vcard"
+
+// ErrInvalid is returned for cards that cannot be parsed.
+var ErrInvalid = errors.New("invalid vcard")
+
+// Card is one contact. Empty fields are omitted when writing.
+type Card struct {
+	UID           string
+	FormattedName string
+	GivenName     string
+	FamilyName    string
+	Nickname      string
+	Note          string
+	// Birthday is a YYYY-MM-DD date.
+	Birthday string
+	Tel      string
+	URL      string
+	// Locality is the city or free-form place of the first address.
+	Locality   string
+	Photo      string
+	Categories []string
+}
+
+// maxLineOctets is where content lines are folded.
+const maxLineOctets = 75
+
+// Write encodes card with CRLF line endings.
+func Write(w io.Writer, card Card) error {
+	fn := card.FormattedName
+	if fn == "" {
+		fn = strings.TrimSpace(card.GivenName + " " + card.FamilyName)
+	}
+	lines := []string{"BEGIN:VCARD", "VERSION:3.0"}
+	add := func(name, value string) {
+		if value != "" {
+			lines = append(lines, fold(name+":"+value))
+		}
+	}
+	add("UID", escape(card.UID))
+	lines = append(lines, fold("FN:"+escape(fn)))
+	lines = append(lines, fold("N:"+escape(card.FamilyName)+";"+escape(card.GivenName)+";;;"))
+	add("NICKNAME", escape(card.Nickname))
+	add("NOTE", escape(card.Note))
+	add("BDAY", card.Birthday)
+	add("TEL", escape(card.Tel))
+	add("URL", card.URL)
+	if card.Locality != "" {
+		lines = append(lines, fold("ADR:;;;"+escape(card.Locality)+";;;"))
+	}
+	if card.Photo != "" {
+		lines = append(lines, fold("PHOTO;VALUE=uri:"+card.Photo))
+	}
+	if len(card.Categories) > 0 {
+		escaped := make([]string, len(card.Categories))
+		for i, cat := range card.Categories {
+			escaped[i] = escape(cat)
+		}
+		add("CATEGORIES", strings.Join(escaped, ","))
+	}
+	lines = append(lines, "END:VCARD")
+	_, err := io.WriteString(w, strings.Join(lines, "\r\n")+"\r\n")
+	return err
+}
+
+// Reader decodes a stream of cards.
+type Reader struct {
+	scanner *bufio.Scanner
+	line    int
+	// pending is a physical line read ahead while unfolding.
+	pending *string
+	// skipping is set after a stray line outside a card was reported, so
+	// the rest of that run is passed over quietly.
+	skipping bool
+}
+
+// NewReader returns a Reader over r.
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &Reader{scanner: scanner}
+}
+
+// Next returns the next card and the line it starts on, or io.EOF once the
+// input is exhausted. A malformed card is reported as ErrInvalid; reading
+// may continue with the next card.
+func (r *Reader) Next() (Card, int, error) {
+	var card Card
+	start := 0
+	for {
+		line, n, err := r.logicalLine()
+		if err != nil {
+			if errors.Is(err, io.EOF) && start > 0 {
+				return card, start, fmt.Errorf("%w: missing END:VCARD", ErrInvalid)
+			}
+			return card, start, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, params, value, ok := splitLine(line)
+		if start == 0 {
+			if !ok || name != "BEGIN" || !strings.EqualFold(value, "VCARD") {
+				if r.skipping {
+					continue
+				}
+				r.skipping = true
+				return card, n, fmt.Errorf("%w: expected BEGIN:VCARD on line %d", ErrInvalid, n)
+			}
+			r.skipping = false
+			start = n
+			continue
+		}
+		if !ok {
+			continue
+		}
+		switch name {
+		case "END":
+			return card, start, nil
+		case "UID":
+			card.UID = unescape(value)
+		case "FN":
+			card.FormattedName = unescape(value)
+		case "N":
+			parts := splitUnescaped(value, ';')
+			if len(parts) > 0 {
+				card.FamilyName = parts[0]
+			}
+			if len(parts) > 1 {
+				card.GivenName = parts[1]
+			}
+		case "NICKNAME":
+			card.Nickname = firstOf(splitUnescaped(value, ','))
+		case "NOTE":
+			card.Note = unescape(value)
+		case "BDAY":
+			card.Birthday = normalizeDate(value)
+		case "TEL":
+			if card.Tel == "" {
+				card.Tel = unescape(value)
+			}
+		case "URL":
+			if card.URL == "" {
+				card.URL = unescape(value)
+			}
+		case "ADR":
+			if card.Locality == "" {
+				var parts []string
+				for _, part := range splitUnescaped(value, ';') {
+					if part = strings.TrimSpace(part); part != "" {
+						parts = append(parts, part)
+					}
+				}
+				card.Locality = strings.Join(parts, ", ")
+			}
+		case "PHOTO":
+			// Inline photos are binary payloads; only links are kept.
+			if !strings.Contains(strings.ToUpper(params), "ENCODING=") && !strings.HasPrefix(value, "data:") {
+				card.Photo = value
+			}
+		case "CATEGORIES":
+			for _, cat := range splitUnescaped(value, ',') {
+				if cat = strings.TrimSpace(cat); cat != "" {
+					card.Categories = append(card.Categories, cat)
+				}
+			}
+		}
+	}
+}
+
+// logicalLine returns the next unfolded content line and its first
+// physical line number.
+func (r *Reader) logicalLine() (string, int, error) {
+	var first string
+	if r.pending != nil {
+		first, r.pending = *r.pending, nil
+	} else {
+		if !r.scanner.Scan() {
+			if err := r.scanner.Err(); err != nil {
+				return "", r.line, err
+			}
+			return "", r.line, io.EOF
+		}
+		r.line++
+		first = r.scanner.Text()
+	}
+	start := r.line
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(first, "\r"))
+	for r.scanner.Scan() {
+		r.line++
+		next := strings.TrimRight(r.scanner.Text(), "\r")
+		if strings.HasPrefix(next, " ") || strings.HasPrefix(next, "\t") {
+			b.WriteString(next[1:])
+			continue
+		}
+		r.pending = &next
+		break
+	}
+	if r.pending == nil {
+		if err := r.scanner.Err(); err != nil {
+			return "", r.line, err
+		}
+	}
+	return b.String(), start, nil
+}
+
+// splitLine splits "group.NAME;params:value" into its upper-cased name,
+// raw parameters and value.
+func splitLine(line string) (name, params, value string, ok bool) {
+	head, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return "", "", "", false
+	}
+	name, params, _ = strings.Cut(head, ";")
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToUpper(strings.TrimSpace(name)), params, value, true
+}
+
+// fold breaks a content line into CRLF-space continuations of at most
+// maxLineOctets octets without splitting UTF-8 sequences.
+func fold(line string) string {
+	if len(line) <= maxLineOctets {
+		return line
+	}
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines lose one octet to the leading space.
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	return b.String()
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func unescape(s string) string {
+	return strings.Join(splitUnescaped(s, 0), "")
+}
+
+// splitUnescaped splits s on unescaped sep (none when sep is 0) and
+// unescapes each part.
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '\\' && i+1 < len(s):
+			i++
+			if s[i] == 'n' || s[i] == 'N' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(s[i])
+			}
+		case sep != 0 && ch == sep:
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return append(parts, b.String())
+}
+
+func firstOf(parts []string) string {
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(parts[0])
+}
+
+// normalizeDate accepts YYYY-MM-DD, YYYYMMDD and timestamps starting with
+// either, returning YYYY-MM-DD; anything else is returned unchanged so
+// validation can reject it.
+func normalizeDate(value string) string {
+	value = strings.TrimSpace(value)
+	if date, _, _ := strings.Cut(value, "T"); len(date) == 8 && !strings.Contains(date, "-") {
+		return date[:4] + "-" + date[4:6] + "-" + date[6:]
+	} else if len(date) == 10 {
+		return date
+	}
+	return value
+}
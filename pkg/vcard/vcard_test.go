@@ -0,0 +1,61 @@
+package vcard
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	card := Card{
+		UID:        "42",
+		GivenName:  "Jane",
+		FamilyName: "Doe; Jr.",
+		Note:       "Line one\nLine two, with comma and a long tail that needs folding past seventy-five octets ✓✓✓",
+		Birthday:   "1990-01-02",
+		Tel:        "+66 81 234 5678",
+		URL:        "https://example.com/jane",
+		Locality:   "Bangkok",
+		Categories: []string{"vip", "new york"},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, card))
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		require.LessOrEqual(t, len(line), maxLineOctets)
+	}
+
+	got, line, err := NewReader(&buf).Next()
+	require.NoError(t, err)
+	require.Equal(t, 1, line)
+	card.FormattedName = "Jane Doe; Jr."
+	require.Equal(t, card, got)
+}
+
+func TestReaderSkipsBadInputAndReadsForeignCards(t *testing.T) {
+	input := "garbage\nmore garbage\n" +
+		"BEGIN:VCARD\nVERSION:3.0\nFN:Ann Lee\nN:Lee;Ann;;;\nitem1.TEL;TYPE=cell:0812\nADR;TYPE=home:;;1 Main St;Springfield;;;USA\nBDAY:19851231\nPHOTO;ENCODING=b;TYPE=JPEG:AAAA\nEND:VCARD\n" +
+		"BEGIN:VCARD\nFN:Cut Off\n"
+	r := NewReader(strings.NewReader(input))
+
+	_, line, err := r.Next()
+	require.ErrorIs(t, err, ErrInvalid)
+	require.Equal(t, 1, line)
+
+	card, line, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, 3, line)
+	require.Equal(t, "Ann", card.GivenName)
+	require.Equal(t, "0812", card.Tel)
+	require.Equal(t, "1 Main St, Springfield, USA", card.Locality)
+	require.Equal(t, "1985-12-31", card.Birthday)
+	require.Empty(t, card.Photo)
+
+	_, _, err = r.Next()
+	require.ErrorIs(t, err, ErrInvalid)
+	_, _, err = r.Next()
+	require.True(t, errors.Is(err, io.EOF))
+}
@@ -10,8 +10,6 @@ NETWORK=${NETWORK:-demo-net}
 POSTGRES_NAME=${POSTGRES_NAME:-postgres}
 API_NAME=${API_NAME:-api}
 DB_DSN=${DB_DSN:-postgres://postgres:postgres@${POSTGRES_NAME}:5432/demo_db?sslmode=disable}
-REPO_ROOT=$(cd "$(dirname "$0")/.." && pwd)
-MIGRATIONS_PATH=${MIGRATIONS_PATH:-${REPO_ROOT}/migrations}
 
 echo "[1/6] Cleaning previous containers"
 docker rm -f "${POSTGRES_NAME}" "${API_NAME}" >/dev/null 2>&1 || true
@@ -36,8 +34,8 @@ until docker exec "${POSTGRES_NAME}" pg_isready -U postgres >/dev/null 2>&1; do
 done
 
 echo "[6/8] Applying database migrations"
-docker run --rm --network "${NETWORK}" -v "${MIGRATIONS_PATH}:/migrations" \
-  migrate/migrate -path=/migrations/postgres -database "${DB_DSN}" up
+docker run --rm --network "${NETWORK}" --env-file .env -e DB_DSN="${DB_DSN}" \
+  "${IMAGE}:${TAG}" migrate up
 
 docker run -d --name "${API_NAME}" --network "${NETWORK}" -p 8080:8080 \
   --env-file .env "${IMAGE}:${TAG}"